@@ -0,0 +1,159 @@
+package api
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultWorkers                   = 4
+	defaultBatchSize                 = 20
+	authenticatedRequestsPerMinute   = 60
+	unauthenticatedRequestsPerMinute = 25
+	defaultPriceCurrency             = "USD"
+)
+
+// Currencies the Discogs marketplace reports prices in
+var priceCurrencies = map[string]bool{
+	"USD": true, "GBP": true, "EUR": true, "CAD": true, "AUD": true, "JPY": true,
+	"CHF": true, "MXN": true, "BRL": true, "NZD": true, "SEK": true, "ZAR": true,
+}
+
+// SyncConfig controls how releases are fetched from the Discogs API. Fetched releases are stored
+// BatchSize at a time, each batch in one transaction. By default only releases
+// missing from the database are fetched; MaxAge also re-fetches stored copies older than that,
+// and FullRefresh fetches everything again. A nil Schedule syncs only once at start. SublabelDepth
+// is how many levels of sublabels are synced along with each label; zero syncs none. FetchMasters
+// and EnrichArtists also fetch the masters of the synced releases and the profiles of their
+// artists, following the same refresh rules. CollectCommunity fetches every stored release of the
+// label again on every sync to record its community statistics, and CollectPrices records the
+// marketplace prices of all of the label's releases in PriceCurrency.
+type SyncConfig struct {
+	Workers           int
+	BatchSize         int
+	RequestsPerMinute int
+	FullRefresh       bool
+	MaxAge            time.Duration
+	Schedule          cron.Schedule
+	SublabelDepth     int
+	FetchMasters      bool
+	EnrichArtists     bool
+	CollectCommunity  bool
+	CollectPrices     bool
+	PriceCurrency     string
+}
+
+// LoadSyncConfig reads the sync configuration from the environment. The default request rate
+// follows the Discogs limits, which are higher for authenticated clients.
+func LoadSyncConfig() (SyncConfig, error) {
+	config := SyncConfig{
+		Workers:           defaultWorkers,
+		BatchSize:         defaultBatchSize,
+		RequestsPerMinute: unauthenticatedRequestsPerMinute,
+		PriceCurrency:     defaultPriceCurrency,
+	}
+
+	if os.Getenv("DISCOGS_KEY") != "" && os.Getenv("DISCOGS_SECRET") != "" {
+		config.RequestsPerMinute = authenticatedRequestsPerMinute
+	}
+
+	var err error
+	if config.Workers, err = getPositiveIntEnv("DISCOGS_WORKERS", config.Workers); err != nil {
+		return SyncConfig{}, err
+	}
+	if config.BatchSize, err = getPositiveIntEnv("SYNC_BATCH_SIZE", config.BatchSize); err != nil {
+		return SyncConfig{}, err
+	}
+	if config.RequestsPerMinute, err = getPositiveIntEnv("DISCOGS_REQUESTS_PER_MINUTE", config.RequestsPerMinute); err != nil {
+		return SyncConfig{}, err
+	}
+	if config.FullRefresh, err = getBoolEnv("SYNC_FULL_REFRESH"); err != nil {
+		return SyncConfig{}, err
+	}
+	if config.MaxAge, err = getDurationEnv("SYNC_MAX_AGE"); err != nil {
+		return SyncConfig{}, err
+	}
+	if config.SublabelDepth, err = getNonNegativeIntEnv("SYNC_SUBLABEL_DEPTH"); err != nil {
+		return SyncConfig{}, err
+	}
+	if config.FetchMasters, err = getBoolEnv("SYNC_MASTERS"); err != nil {
+		return SyncConfig{}, err
+	}
+	if config.EnrichArtists, err = getBoolEnv("SYNC_ARTIST_PROFILES"); err != nil {
+		return SyncConfig{}, err
+	}
+	if config.CollectCommunity, err = getBoolEnv("SYNC_COMMUNITY"); err != nil {
+		return SyncConfig{}, err
+	}
+	if config.CollectPrices, err = getBoolEnv("SYNC_PRICES"); err != nil {
+		return SyncConfig{}, err
+	}
+	if currency := os.Getenv("SYNC_PRICE_CURRENCY"); currency != "" {
+		if !priceCurrencies[currency] {
+			return SyncConfig{}, fmt.Errorf("SYNC_PRICE_CURRENCY must be a Discogs marketplace currency such as USD, got: %v", currency)
+		}
+		config.PriceCurrency = currency
+	}
+	if scheduleSpec := os.Getenv("SYNC_SCHEDULE"); scheduleSpec != "" {
+		if config.Schedule, err = parseSchedule(scheduleSpec); err != nil {
+			return SyncConfig{}, fmt.Errorf("SYNC_SCHEDULE must be an interval such as 6h or a cron expression, got: %v", scheduleSpec)
+		}
+	}
+
+	return config, nil
+}
+
+func getPositiveIntEnv(name string, defaultValue int) (int, error) {
+	valueStr := os.Getenv(name)
+	if valueStr == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got: %v", name, valueStr)
+	}
+	return value, nil
+}
+
+func getNonNegativeIntEnv(name string) (int, error) {
+	valueStr := os.Getenv(name)
+	if valueStr == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got: %v", name, valueStr)
+	}
+	return value, nil
+}
+
+func getBoolEnv(name string) (bool, error) {
+	valueStr := os.Getenv(name)
+	if valueStr == "" {
+		return false, nil
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean, got: %v", name, valueStr)
+	}
+	return value, nil
+}
+
+func getDurationEnv(name string) (time.Duration, error) {
+	valueStr := os.Getenv(name)
+	if valueStr == "" {
+		return 0, nil
+	}
+
+	value, err := time.ParseDuration(valueStr)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative duration such as 720h, got: %v", name, valueStr)
+	}
+	return value, nil
+}
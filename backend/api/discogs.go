@@ -1,38 +1,27 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"github.com/LissaGreense/discogs_record_label/backend/models"
-	"github.com/LissaGreense/discogs_record_label/backend/storage"
 	"github.com/go-resty/resty/v2"
 	"log"
 	"os"
-	"time"
+	"strconv"
+	"strings"
 )
 
 const (
-	discogsLabelAPIURL = "https://api.discogs.com/labels/%d/releases?page=1&per_page=%d"
-	perPage            = 100
+	discogsLabelAPIURL   = "https://api.discogs.com/labels/%d/releases?page=1&per_page=%d"
+	discogsReleaseAPIURL = "https://api.discogs.com/releases/%d"
+	discogsLabelInfoURL  = "https://api.discogs.com/labels/%d"
+	discogsArtistURL     = "https://api.discogs.com/artists/%d"
+	discogsMasterURL     = "https://api.discogs.com/masters/%d"
+	discogsPriceURL      = "https://api.discogs.com/marketplace/stats/%d?curr_abbr=%s"
+	perPage              = 100
+	maxRequestAttempts   = 5
 )
 
-func FetchAndStoreReleases(db *sql.DB, labelID int) error {
-	client := createDiscogsClient()
-
-	releaseUrls, err := getReleasesURLs(labelID, client)
-	if err != nil {
-		return err
-	}
-
-	err = fetchReleasesDetailAndSave(db, releaseUrls, client)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func createDiscogsClient() *resty.Client {
 	client := resty.New()
 
@@ -51,48 +40,193 @@ func createDiscogsClient() *resty.Client {
 	return client
 }
 
-func fetchReleasesDetailAndSave(db *sql.DB, releaseUrls []string, client *resty.Client) error {
-	for releaseIndex := 0; releaseIndex < len(releaseUrls); {
+// getWithRateLimit requests url once the limiter allows it. Transport errors and 429 responses
+// throttle every worker and are retried up to maxRequestAttempts in total, after which the last
+// error is returned.
+func getWithRateLimit(client *resty.Client, limiter *rateLimiter, url string) (*resty.Response, error) {
+	var lastErr error
+	for attempt := 0; attempt < maxRequestAttempts; attempt++ {
+		limiter.Wait()
+
 		resp, err := client.R().
 			SetHeader("Accept", "application/json").
-			Get(releaseUrls[releaseIndex])
+			Get(url)
 
 		if err != nil || resp.StatusCode() == 429 {
 			handleRequestError(resp, err)
-			time.Sleep(60 * time.Second)
+			limiter.Throttle(resp)
+			if err == nil {
+				err = fmt.Errorf("request to %s failed with status %s", url, resp.Status())
+			}
+			lastErr = err
 			continue
 		}
-		releaseIndex++
 
-		release, err := parseReleaseResponse(err, resp.Body())
-		if err != nil {
-			return err
-		}
-
-		err = storage.StoreRelease(db, release)
-		if err != nil {
-			log.Printf("Error storing release %d: %v", release.Id, err)
+		limiter.Observe(resp)
+		if resp.IsError() {
+			return nil, fmt.Errorf("request to %s failed with status %s", url, resp.Status())
 		}
+		return resp, nil
 	}
-	return nil
+	return nil, fmt.Errorf("giving up after %d attempts: %v", maxRequestAttempts, lastErr)
 }
 
-func parseReleaseResponse(err error, body []byte) (*models.Release, error) {
-	var releaseFromBody map[string]interface{}
-	err = json.Unmarshal(body, &releaseFromBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v, body: %v", err, body)
+// parseReleaseResponse decodes the release resource requested as releaseID. Errors name the
+// release, and a body without an id, such as a Discogs error message, or with the id of another
+// release is rejected, so that it is never stored under the wrong id.
+func parseReleaseResponse(releaseID int32, body []byte) (*models.Release, error) {
+	var releaseFromBody releaseResponse
+	if err := json.Unmarshal(body, &releaseFromBody); err != nil {
+		return nil, fmt.Errorf("release %d: failed to unmarshal response: %v", releaseID, err)
+	}
+
+	if releaseFromBody.Id == 0 {
+		return nil, fmt.Errorf("release %d: %v", releaseID, missingDataError("release", releaseFromBody.errorResponse))
+	}
+	if releaseFromBody.Id != releaseID {
+		return nil, fmt.Errorf("release %d: Discogs returned release %d", releaseID, releaseFromBody.Id)
 	}
 
 	release := &models.Release{
-		Id:      int32(releaseFromBody["id"].(float64)),
-		Artists: extractArtists(releaseFromBody),
-		Styles:  extractStyles(releaseFromBody),
-		Genres:  extractGenres(releaseFromBody),
+		Id:          releaseFromBody.Id,
+		Title:       releaseFromBody.Title,
+		Year:        releaseFromBody.Year,
+		Released:    releaseFromBody.Released,
+		Country:     releaseFromBody.Country,
+		DataQuality: releaseFromBody.DataQuality,
+		Notes:       releaseFromBody.Notes,
+		URI:         releaseFromBody.URI,
+		MasterId:    releaseFromBody.MasterId,
+		Artists:     extractArtists(releaseFromBody.Artists),
+		Styles:      extractNames(releaseFromBody.Styles),
+		Genres:      extractNames(releaseFromBody.Genres),
+		Formats:     extractFormats(releaseFromBody.Formats),
+		Tracks:      extractTracks(releaseFromBody.Tracklist),
+		Credits:     extractCredits(releaseFromBody.Credits, releaseFromBody.Tracklist),
+		Labels:      extractLabels(releaseFromBody.Labels),
+		Companies:   extractCompanies(releaseFromBody.Companies),
+		Identifiers: extractIdentifiers(releaseFromBody.Identifiers),
+		Community:   extractCommunity(releaseFromBody.Community),
 	}
 	return release, nil
 }
 
+// parseLabelResponse returns the label, with its parent if it has one, and its direct sublabels.
+func parseLabelResponse(body []byte) (*models.Label, []*models.Label, error) {
+	var labelFromBody labelResponse
+	if err := json.Unmarshal(body, &labelFromBody); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal response: %v", err)
+	}
+
+	if labelFromBody.Id == 0 {
+		return nil, nil, missingDataError("label", labelFromBody.errorResponse)
+	}
+
+	label := &models.Label{Id: labelFromBody.Id, Name: labelFromBody.Name}
+	if labelFromBody.ParentLabel != nil && labelFromBody.ParentLabel.Id != 0 {
+		label.ParentId = &labelFromBody.ParentLabel.Id
+	}
+
+	var sublabels []*models.Label
+	for _, sublabel := range labelFromBody.Sublabels {
+		if sublabel.Id == 0 {
+			continue
+		}
+		sublabels = append(sublabels, &models.Label{Id: sublabel.Id, Name: sublabel.Name, ParentId: &label.Id})
+	}
+
+	return label, sublabels, nil
+}
+
+// parseMasterResponse decodes the master resource requested as masterID.
+func parseMasterResponse(masterID int, body []byte) (*models.Master, error) {
+	var masterFromBody masterResponse
+	if err := json.Unmarshal(body, &masterFromBody); err != nil {
+		return nil, fmt.Errorf("master %d: failed to unmarshal response: %v", masterID, err)
+	}
+
+	if masterFromBody.Id == 0 {
+		return nil, fmt.Errorf("master %d: %v", masterID, missingDataError("master", masterFromBody.errorResponse))
+	}
+
+	return &models.Master{
+		Id:          masterFromBody.Id,
+		Title:       masterFromBody.Title,
+		Year:        masterFromBody.Year,
+		MainRelease: masterFromBody.MainRelease,
+		URI:         masterFromBody.URI,
+	}, nil
+}
+
+// parsePriceResponse decodes the marketplace statistics of the release requested as releaseID in
+// currency. Discogs leaves the lowest price out, or null, while no copy is for sale.
+func parsePriceResponse(releaseID int32, currency string, body []byte) (*models.PriceSnapshot, error) {
+	var priceFromBody priceResponse
+	if err := json.Unmarshal(body, &priceFromBody); err != nil {
+		return nil, fmt.Errorf("release %d: failed to unmarshal marketplace statistics: %v", releaseID, err)
+	}
+
+	if priceFromBody.Message != "" {
+		return nil, fmt.Errorf("release %d: %v", releaseID, missingDataError("marketplace", priceFromBody.errorResponse))
+	}
+
+	price := &models.PriceSnapshot{
+		Currency:        currency,
+		BlockedFromSale: priceFromBody.BlockedFromSale,
+	}
+	if priceFromBody.NumForSale != nil {
+		price.NumForSale = *priceFromBody.NumForSale
+	}
+	if priceFromBody.LowestPrice != nil {
+		price.LowestPrice = &priceFromBody.LowestPrice.Value
+		if priceFromBody.LowestPrice.Currency != "" {
+			price.Currency = priceFromBody.LowestPrice.Currency
+		}
+	}
+	return price, nil
+}
+
+// parseArtistResponse decodes the artist resource requested as artistID.
+func parseArtistResponse(artistID int, body []byte) (*models.Artist, error) {
+	var artistFromBody artistResponse
+	if err := json.Unmarshal(body, &artistFromBody); err != nil {
+		return nil, fmt.Errorf("artist %d: failed to unmarshal response: %v", artistID, err)
+	}
+
+	if artistFromBody.Id == 0 || artistFromBody.Name == "" {
+		return nil, fmt.Errorf("artist %d: %v", artistID, missingDataError("artist", artistFromBody.errorResponse))
+	}
+
+	return &models.Artist{
+		Id:             artistFromBody.Id,
+		Name:           artistFromBody.Name,
+		ResourceURL:    artistFromBody.ResourceURL,
+		RealName:       artistFromBody.RealName,
+		Profile:        artistFromBody.Profile,
+		NameVariations: extractNames(artistFromBody.NameVariations),
+		Aliases:        extractArtistRefs(artistFromBody.Aliases),
+		Groups:         extractArtistRefs(artistFromBody.Groups),
+		Members:        extractArtistRefs(artistFromBody.Members),
+	}, nil
+}
+
+func extractArtistRefs(refs []artistRef) []models.ArtistRef {
+	var artists []models.ArtistRef
+	for _, ref := range refs {
+		if ref.Id != 0 && ref.Name != "" {
+			artists = append(artists, models.ArtistRef{Id: ref.Id, Name: ref.Name, Active: ref.Active})
+		}
+	}
+	return artists
+}
+
+func missingDataError(resource string, errorBody errorResponse) error {
+	if errorBody.Message != "" {
+		return fmt.Errorf("no %s data, Discogs responded: %s", resource, errorBody.Message)
+	}
+	return fmt.Errorf("unexpected format of %s data", resource)
+}
+
 func handleRequestError(resp *resty.Response, err error) {
 	if err != nil {
 		log.Printf("Failed to fetch release details: %v", err)
@@ -105,120 +239,214 @@ func handleRequestError(resp *resty.Response, err error) {
 	}
 }
 
-func getReleasesURLs(labelID int, client *resty.Client) ([]string, error) {
-	var releaseUrls []string
-	url := fmt.Sprintf(discogsLabelAPIURL, labelID, perPage)
+func parseReleases(body []byte) ([]int32, error) {
+	var result labelReleasesResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+	}
 
-	for {
-		resp, err := client.R().
-			SetHeader("Accept", "application/json").
-			Get(url)
+	if result.Releases == nil {
+		return nil, missingDataError("releases", result.errorResponse)
+	}
 
-		if err != nil || resp.StatusCode() == 429 {
-			handleRequestError(resp, err)
-			time.Sleep(60 * time.Second)
+	var releaseIDs []int32
+	for _, release := range *result.Releases {
+		if release.Id == 0 {
+			log.Printf("Skipping listed release without id: %q", release.Title)
 			continue
 		}
+		releaseIDs = append(releaseIDs, release.Id)
+	}
 
-		releases, err := parseReleases(resp.Body())
-		if err != nil {
-			return nil, err
+	return releaseIDs, nil
+}
+
+func getNextPageURL(body []byte) (string, bool) {
+	var result labelReleasesResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", false
+	}
+
+	nextPageURL := result.Pagination.Urls.Next
+	return nextPageURL, nextPageURL != ""
+}
+
+// extractArtists keeps the release's artists in credited order. Artists without a Discogs id
+// cannot be linked to an artist entity and are skipped.
+func extractArtists(artistCredits []artistCredit) []models.ReleaseArtist {
+	var artists []models.ReleaseArtist
+	for _, artist := range artistCredits {
+		if artist.Id == 0 || artist.Name == "" {
+			continue
 		}
-		releaseUrls = append(releaseUrls, releases...)
+		artists = append(artists, models.ReleaseArtist{
+			Id:          artist.Id,
+			Name:        artist.Name,
+			Anv:         artist.Anv,
+			Join:        artist.Join,
+			ResourceURL: artist.ResourceURL,
+		})
+	}
+	return artists
+}
 
-		nextPageURL, hasNext := getNextPageURL(resp.Body())
-		if !hasNext {
-			break
+// extractFormats skips formats without a name. Discogs sends the quantity as a string; one that
+// is not a number is stored as unknown.
+func extractFormats(formats []format) []models.Format {
+	var extracted []models.Format
+	for _, f := range formats {
+		if f.Name == "" {
+			continue
 		}
-		url = nextPageURL
+		qty, _ := strconv.Atoi(f.Qty)
+		extracted = append(extracted, models.Format{
+			Name:         f.Name,
+			Qty:          qty,
+			Text:         f.Text,
+			Descriptions: extractNames(f.Descriptions),
+		})
 	}
-	return releaseUrls, nil
+	return extracted
 }
 
-func parseReleases(body []byte) ([]string, error) {
-	var result map[string]interface{}
-	err := json.Unmarshal(body, &result)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+// extractTracks flattens the tracklist, listing the sub tracks of an index track right after it.
+func extractTracks(tracklist []track) []models.Track {
+	var tracks []models.Track
+	for _, t := range tracklist {
+		if t.Title == "" && len(t.SubTracks) == 0 {
+			continue
+		}
+
+		var artists []models.TrackArtist
+		for _, artist := range t.Artists {
+			if artist.Name != "" {
+				artists = append(artists, models.TrackArtist{Name: artist.Name})
+			}
+		}
+		for _, artist := range t.ExtraArtists {
+			if artist.Name != "" {
+				artists = append(artists, models.TrackArtist{Name: artist.Name, Role: artist.Role})
+			}
+		}
+
+		tracks = append(tracks, models.Track{
+			Position:        t.Position,
+			Type:            t.Type,
+			Title:           t.Title,
+			Duration:        t.Duration,
+			DurationSeconds: parseDuration(t.Duration),
+			Artists:         artists,
+		})
+		tracks = append(tracks, extractTracks(t.SubTracks)...)
 	}
+	return tracks
+}
 
-	items, ok := result["releases"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("unexpected format of releases data")
+// extractCredits returns the release credits followed by the credits of single tracks, which are
+// scoped to the track's position.
+func extractCredits(releaseCredits []artistCredit, tracklist []track) []models.Credit {
+	var credits []models.Credit
+	for _, credit := range releaseCredits {
+		if credit.Name != "" && credit.Role != "" {
+			credits = append(credits, models.Credit{ArtistId: credit.Id, Name: credit.Name, Role: credit.Role, Tracks: credit.Tracks})
+		}
 	}
 
-	var releaseUrls []string
-	for _, item := range items {
-		releaseMap := item.(map[string]interface{})
-		releaseUrl := releaseMap["resource_url"].(string)
-		releaseUrls = append(releaseUrls, releaseUrl)
+	for _, t := range tracklist {
+		for _, credit := range t.ExtraArtists {
+			if credit.Name != "" && credit.Role != "" {
+				credits = append(credits, models.Credit{ArtistId: credit.Id, Name: credit.Name, Role: credit.Role, Tracks: t.Position})
+			}
+		}
+		credits = append(credits, extractCredits(nil, t.SubTracks)...)
 	}
+	return credits
+}
 
-	return releaseUrls, nil
+// extractLabels keeps the labels of a release with their catalog numbers.
+func extractLabels(labels []company) []models.ReleaseLabel {
+	var releaseLabels []models.ReleaseLabel
+	for _, label := range labels {
+		if label.Name != "" {
+			releaseLabels = append(releaseLabels, models.ReleaseLabel{Id: label.Id, Name: label.Name, Catno: extractCatno(label.Catno)})
+		}
+	}
+	return releaseLabels
 }
 
-func getNextPageURL(body []byte) (string, bool) {
-	var result map[string]interface{}
-	err := json.Unmarshal(body, &result)
-	if err != nil {
-		return "", false
+// extractCompanies keeps the companies of a release with their entity type as role.
+func extractCompanies(companies []company) []models.Company {
+	var releaseCompanies []models.Company
+	for _, c := range companies {
+		if c.Name != "" {
+			releaseCompanies = append(releaseCompanies, models.Company{
+				Id:    c.Id,
+				Name:  c.Name,
+				Role:  c.EntityTypeName,
+				Catno: extractCatno(c.Catno),
+			})
+		}
 	}
+	return releaseCompanies
+}
 
-	pagination, ok := result["pagination"].(map[string]interface{})
-	if !ok {
-		return "", false
+// extractIdentifiers keeps the identifiers of a release that have a value.
+func extractIdentifiers(identifiers []identifier) []models.Identifier {
+	var releaseIdentifiers []models.Identifier
+	for _, id := range identifiers {
+		value := strings.TrimSpace(id.Value)
+		if id.Type != "" && value != "" {
+			releaseIdentifiers = append(releaseIdentifiers, models.Identifier{Type: id.Type, Value: value, Description: id.Description})
+		}
 	}
-	paginationUrls, ok := pagination["urls"].(map[string]interface{})
-	if !ok || paginationUrls["next"] == nil {
-		return "", false
+	return releaseIdentifiers
+}
+
+func extractCommunity(stats *community) *models.CommunityStats {
+	if stats == nil {
+		return nil
+	}
+	return &models.CommunityStats{
+		Have:          stats.Have,
+		Want:          stats.Want,
+		RatingAverage: stats.Rating.Average,
+		RatingCount:   stats.Rating.Count,
 	}
-	return paginationUrls["next"].(string), true
 }
 
-func extractArtists(releaseMap map[string]interface{}) []string {
-	artistsRaw, ok := releaseMap["artists"].([]interface{})
-	if ok && len(artistsRaw) > 0 {
-		var artists []string
-		for _, artistInterface := range artistsRaw {
-			artistMap, ok := artistInterface.(map[string]interface{})
-			if ok {
-				name, ok := artistMap["name"].(string)
-				if ok {
-					artists = append(artists, name)
-				}
-			}
-		}
-		return artists
+// extractCatno drops the "none" Discogs enters for releases without a catalog number.
+func extractCatno(catno string) string {
+	catno = strings.TrimSpace(catno)
+	if strings.EqualFold(catno, "none") {
+		return ""
 	}
-	return nil
+	return catno
 }
 
-func extractStyles(releaseMap map[string]interface{}) []string {
-	stylesRaw, ok := releaseMap["styles"].([]interface{})
-	if ok && len(stylesRaw) > 0 {
-		var styles []string
-		for _, styleInterface := range stylesRaw {
-			style, ok := styleInterface.(string)
-			if ok {
-				styles = append(styles, style)
-			}
+// parseDuration converts durations such as "4:32" or "1:02:10" to seconds, returning 0 for
+// missing or malformed ones.
+func parseDuration(duration string) int {
+	if duration == "" {
+		return 0
+	}
+
+	seconds := 0
+	for _, part := range strings.Split(duration, ":") {
+		value, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || value < 0 {
+			return 0
 		}
-		return styles
+		seconds = seconds*60 + value
 	}
-	return nil
+	return seconds
 }
 
-func extractGenres(releaseMap map[string]interface{}) []string {
-	genresRaw, ok := releaseMap["genres"].([]interface{})
-	if ok && len(genresRaw) > 0 {
-		var genres []string
-		for _, genreInterface := range genresRaw {
-			genre, ok := genreInterface.(string)
-			if ok {
-				genres = append(genres, genre)
-			}
+func extractNames(names []string) []string {
+	var nonEmpty []string
+	for _, name := range names {
+		if name != "" {
+			nonEmpty = append(nonEmpty, name)
 		}
-		return genres
 	}
-	return nil
+	return nonEmpty
 }
@@ -3,6 +3,7 @@ package api
 import (
 	"testing"
 
+	"github.com/LissaGreense/discogs_record_label/backend/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -10,13 +11,23 @@ import (
 const (
 	mockedReleaseJSON = `{
 		"id": 123456,
-		"artists": [{"name": "Some Artist"}],
+		"title": "Some Title",
+		"year": 1999,
+		"released": "1999-03-00",
+		"country": "UK",
+		"data_quality": "Correct",
+		"notes": "Recorded live.",
+		"uri": "https://www.discogs.com/release/123456",
+		"master_id": 4321,
+		"artists": [{"id": 11, "name": "Some Artist (2)", "anv": "Some Artist", "join": "", "resource_url": "https://api.discogs.com/artists/11"}],
 		"styles": ["Rock"],
-		"genres": ["Pop"]
+		"genres": ["Pop"],
+		"formats": [{"name": "Vinyl", "qty": "2", "descriptions": ["12\"", "33 ⅓ RPM"], "text": "Red"}]
 	}`
 
 	mockedReleasesJSON = `{
 		"releases": [{
+			"id": 123456,
 			"resource_url": "https://api.discogs.com/releases/123456"
 		}],
 		"pagination": {
@@ -29,22 +40,146 @@ const (
 
 func TestParseReleaseResponse(t *testing.T) {
 	body := []byte(mockedReleaseJSON)
-	release, err := parseReleaseResponse(nil, body)
+	release, err := parseReleaseResponse(123456, body)
 
 	require.NoError(t, err)
 	assert.Equal(t, int32(123456), release.Id)
-	assert.Equal(t, []string{"Some Artist"}, release.Artists)
+	assert.Equal(t, "Some Title", release.Title)
+	assert.Equal(t, 1999, release.Year)
+	assert.Equal(t, "1999-03-00", release.Released)
+	assert.Equal(t, "UK", release.Country)
+	assert.Equal(t, "Correct", release.DataQuality)
+	assert.Equal(t, "Recorded live.", release.Notes)
+	assert.Equal(t, "https://www.discogs.com/release/123456", release.URI)
+	assert.Equal(t, 4321, release.MasterId)
+	assert.Equal(t, []models.ReleaseArtist{
+		{Id: 11, Name: "Some Artist (2)", Anv: "Some Artist", ResourceURL: "https://api.discogs.com/artists/11"},
+	}, release.Artists)
 	assert.Equal(t, []string{"Rock"}, release.Styles)
 	assert.Equal(t, []string{"Pop"}, release.Genres)
+	assert.Equal(t, []models.Format{{Name: "Vinyl", Qty: 2, Text: "Red", Descriptions: []string{`12"`, "33 ⅓ RPM"}}}, release.Formats)
+}
+
+func TestParseReleaseResponseErrors(t *testing.T) {
+	_, err := parseReleaseResponse(42, []byte(`{"message": "Release not found."}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "release 42")
+	assert.Contains(t, err.Error(), "Release not found.")
+
+	_, err = parseReleaseResponse(42, []byte(`{"id": "42"}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "release 42")
+
+	_, err = parseReleaseResponse(42, []byte(`{"id": 43, "title": "Another Title"}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "release 42")
+
+	_, err = parseReleaseResponse(42, []byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestParseReleaseResponseIgnoresUnknownFields(t *testing.T) {
+	release, err := parseReleaseResponse(7, []byte(`{"id": 7, "title": "Untitled", "artists": [{"id": 1, "name": ""}, {"id": 2, "name": "A"}]}`))
+
+	require.NoError(t, err)
+	require.Len(t, release.Artists, 1)
+	assert.Equal(t, "A", release.Artists[0].Name)
+	assert.Empty(t, release.Styles)
+	assert.Empty(t, release.Genres)
 }
 
 func TestParseReleases(t *testing.T) {
 	body := []byte(mockedReleasesJSON)
-	releaseUrls, err := parseReleases(body)
+	releaseIDs, err := parseReleases(body)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int32{123456}, releaseIDs)
+
+	_, err = parseReleases([]byte(`{"message": "The requested resource was not found."}`))
+	assert.Error(t, err)
+}
+
+func TestParseLabelResponse(t *testing.T) {
+	label, sublabels, err := parseLabelResponse([]byte(`{
+		"id": 5,
+		"name": "Tone Addiction",
+		"parent_label": {"id": 1, "name": "Umbrella Music"},
+		"sublabels": [{"id": 6, "name": "Tone Addiction Jazz"}, {"name": "Broken"}]
+	}`))
 
 	require.NoError(t, err)
-	assert.Equal(t, 1, len(releaseUrls))
-	assert.Equal(t, "https://api.discogs.com/releases/123456", releaseUrls[0])
+	assert.Equal(t, 5, label.Id)
+	assert.Equal(t, "Tone Addiction", label.Name)
+	require.NotNil(t, label.ParentId)
+	assert.Equal(t, 1, *label.ParentId)
+	require.Len(t, sublabels, 1)
+	assert.Equal(t, 6, sublabels[0].Id)
+	assert.Equal(t, 5, *sublabels[0].ParentId)
+
+	_, _, err = parseLabelResponse([]byte(`{"message": "Label not found."}`))
+	assert.Error(t, err)
+}
+
+func TestParseMasterResponse(t *testing.T) {
+	master, err := parseMasterResponse(4321, []byte(`{
+		"id": 4321,
+		"title": "Blue Train",
+		"year": 1957,
+		"main_release": 7,
+		"uri": "https://www.discogs.com/master/4321",
+		"versions_url": "https://api.discogs.com/masters/4321/versions"
+	}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, &models.Master{Id: 4321, Title: "Blue Train", Year: 1957, MainRelease: 7, URI: "https://www.discogs.com/master/4321"}, master)
+
+	_, err = parseMasterResponse(404, []byte(`{"message": "Master not found."}`))
+	assert.ErrorContains(t, err, "Master not found.")
+}
+
+func TestParsePriceResponse(t *testing.T) {
+	price, err := parsePriceResponse(7, "EUR", []byte(`{
+		"lowest_price": {"currency": "EUR", "value": 24.5},
+		"num_for_sale": 12,
+		"blocked_from_sale": false
+	}`))
+
+	require.NoError(t, err)
+	require.NotNil(t, price.LowestPrice)
+	assert.Equal(t, 24.5, *price.LowestPrice)
+	assert.Equal(t, "EUR", price.Currency)
+	assert.Equal(t, 12, price.NumForSale)
+
+	price, err = parsePriceResponse(8, "EUR", []byte(`{"lowest_price": null, "num_for_sale": 0, "blocked_from_sale": true}`))
+	require.NoError(t, err)
+	assert.Equal(t, &models.PriceSnapshot{Currency: "EUR", BlockedFromSale: true}, price)
+
+	_, err = parsePriceResponse(404, "EUR", []byte(`{"message": "Release not found."}`))
+	assert.ErrorContains(t, err, "Release not found.")
+}
+
+func TestParseArtistResponse(t *testing.T) {
+	artist, err := parseArtistResponse(11, []byte(`{
+		"id": 11,
+		"name": "John Smith (2)",
+		"realname": "John Smith",
+		"profile": "Drummer.",
+		"namevariations": ["J. Smith", ""],
+		"aliases": [{"id": 12, "name": "Smithy"}],
+		"groups": [{"id": 13, "name": "The Smiths (3)", "active": false}, {"name": "Unlinked"}]
+	}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, "John Smith", artist.RealName)
+	assert.Equal(t, []string{"J. Smith"}, artist.NameVariations)
+	assert.Equal(t, []models.ArtistRef{{Id: 12, Name: "Smithy"}}, artist.Aliases)
+	require.Len(t, artist.Groups, 1)
+	require.NotNil(t, artist.Groups[0].Active)
+	assert.False(t, *artist.Groups[0].Active)
+	assert.Empty(t, artist.Members)
+
+	_, err = parseArtistResponse(404, []byte(`{"message": "Artist not found."}`))
+	assert.ErrorContains(t, err, "Artist not found.")
 }
 
 func TestGetNextPageURL(t *testing.T) {
@@ -56,37 +191,84 @@ func TestGetNextPageURL(t *testing.T) {
 }
 
 func TestExtractArtists(t *testing.T) {
-	releaseMap := map[string]interface{}{
-		"artists": []interface{}{
-			map[string]interface{}{"name": "Artist 1"},
-			map[string]interface{}{"name": "Artist 2"},
-		},
-	}
-
-	artists := extractArtists(releaseMap)
-	assert.Equal(t, []string{"Artist 1", "Artist 2"}, artists)
-}
-
-func TestExtractStyles(t *testing.T) {
-	releaseMap := map[string]interface{}{
-		"styles": []interface{}{
-			"Style 1",
-			"Style 2",
-		},
-	}
-
-	styles := extractStyles(releaseMap)
-	assert.Equal(t, []string{"Style 1", "Style 2"}, styles)
-}
-
-func TestExtractGenres(t *testing.T) {
-	releaseMap := map[string]interface{}{
-		"genres": []interface{}{
-			"Genre 1",
-			"Genre 2",
-		},
-	}
-
-	genres := extractGenres(releaseMap)
-	assert.Equal(t, []string{"Genre 1", "Genre 2"}, genres)
+	artists := extractArtists([]artistCredit{{Id: 1, Name: "Artist 1", Join: "&"}, {Id: 2, Name: "Artist 2"}, {Name: "No Id"}})
+	assert.Equal(t, []models.ReleaseArtist{{Id: 1, Name: "Artist 1", Join: "&"}, {Id: 2, Name: "Artist 2"}}, artists)
+}
+
+func TestExtractNames(t *testing.T) {
+	names := extractNames([]string{"Style 1", "", "Style 2"})
+	assert.Equal(t, []string{"Style 1", "Style 2"}, names)
+}
+
+func TestExtractTracks(t *testing.T) {
+	tracks := extractTracks([]track{
+		{Position: "A1", Type: "track", Title: "Intro", Duration: "1:05"},
+		{Type: "index", Title: "Suite", SubTracks: []track{
+			{Position: "A2.a", Type: "track", Title: "Part One", Duration: "1:02:10",
+				Artists:      []artistCredit{{Name: "Guest"}},
+				ExtraArtists: []artistCredit{{Name: "Remixer", Role: "Remix"}}},
+		}},
+		{Type: "heading"},
+	})
+
+	require.Len(t, tracks, 3)
+	assert.Equal(t, 65, tracks[0].DurationSeconds)
+	assert.Equal(t, "index", tracks[1].Type)
+	assert.Equal(t, "Part One", tracks[2].Title)
+	assert.Equal(t, 3730, tracks[2].DurationSeconds)
+	assert.Equal(t, []models.TrackArtist{{Name: "Guest"}, {Name: "Remixer", Role: "Remix"}}, tracks[2].Artists)
+}
+
+func TestParseDuration(t *testing.T) {
+	assert.Equal(t, 272, parseDuration("4:32"))
+	assert.Equal(t, 0, parseDuration(""))
+	assert.Equal(t, 0, parseDuration("4m32s"))
+}
+
+func TestExtractCredits(t *testing.T) {
+	credits := extractCredits(
+		[]artistCredit{{Id: 1, Name: "Producer", Role: "Producer", Tracks: "A1 to A3"}, {Name: "No Role"}},
+		[]track{{Position: "B1", ExtraArtists: []artistCredit{{Id: 2, Name: "Remixer", Role: "Remix"}}}},
+	)
+
+	assert.Equal(t, []models.Credit{
+		{ArtistId: 1, Name: "Producer", Role: "Producer", Tracks: "A1 to A3"},
+		{ArtistId: 2, Name: "Remixer", Role: "Remix", Tracks: "B1"},
+	}, credits)
+}
+
+func TestExtractCompanies(t *testing.T) {
+	labels := extractLabels([]company{
+		{Id: 281, Name: "Blue Note", Catno: "BLP 1577", EntityTypeName: "Label"},
+		{Id: 750, Name: "Not On Label", Catno: "none"},
+	})
+	assert.Equal(t, []models.ReleaseLabel{{Id: 281, Name: "Blue Note", Catno: "BLP 1577"}, {Id: 750, Name: "Not On Label"}}, labels)
+
+	companies := extractCompanies([]company{
+		{Id: 266218, Name: "Optimal Media", EntityTypeName: "Pressed By"},
+		{Catno: "orphan"},
+	})
+	assert.Equal(t, []models.Company{{Id: 266218, Name: "Optimal Media", Role: "Pressed By"}}, companies)
+}
+
+func TestExtractIdentifiers(t *testing.T) {
+	identifiers := extractIdentifiers([]identifier{
+		{Type: "Barcode", Value: " 0 7464-40566 1 8 "},
+		{Type: "Matrix / Runout", Value: "BLP-1577-A", Description: "Side A"},
+		{Type: "Rights Society", Value: ""},
+	})
+
+	assert.Equal(t, []models.Identifier{
+		{Type: "Barcode", Value: "0 7464-40566 1 8"},
+		{Type: "Matrix / Runout", Value: "BLP-1577-A", Description: "Side A"},
+	}, identifiers)
+}
+
+func TestExtractCommunity(t *testing.T) {
+	assert.Nil(t, extractCommunity(nil))
+
+	stats := &community{Have: 5200, Want: 1800}
+	stats.Rating.Count = 950
+	stats.Rating.Average = 4.8
+	assert.Equal(t, &models.CommunityStats{Have: 5200, Want: 1800, RatingAverage: 4.8, RatingCount: 950}, extractCommunity(stats))
 }
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"github.com/LissaGreense/discogs_record_label/backend/storage"
+	"github.com/go-resty/resty/v2"
+	"log"
+	"time"
+)
+
+// enrichArtists fetches the profiles of the artists credited on the label's releases that were
+// never fetched or are due again. An artist whose profile cannot be fetched is logged and skipped;
+// only storage errors stop the pass.
+func enrichArtists(store storage.Store, labelFilter models.LabelFilter, client *resty.Client, limiter *rateLimiter, config SyncConfig, tracker *SyncTracker) error {
+	artistIDs, err := store.FetchArtistsToEnrich(labelFilter, profileRefetchCutoff(config, time.Now()))
+	if err != nil {
+		return err
+	}
+	log.Printf("%d artist profiles of label %d will be fetched", len(artistIDs), labelFilter.LabelId)
+	tracker.enriching(len(artistIDs))
+
+	failed := 0
+	for _, artistID := range artistIDs {
+		artist, err := fetchArtistProfile(artistID, client, limiter)
+		if err != nil {
+			log.Printf("Error enriching artist %d: %v", artistID, err)
+			failed++
+			tracker.artistDone(true)
+			continue
+		}
+
+		if err := store.StoreArtistProfile(artist, time.Now()); err != nil {
+			return err
+		}
+		tracker.artistDone(false)
+	}
+
+	log.Printf("Enriched %d artists of label %d, %d failed", len(artistIDs)-failed, labelFilter.LabelId, failed)
+	return nil
+}
+
+func fetchArtistProfile(artistID int, client *resty.Client, limiter *rateLimiter) (*models.Artist, error) {
+	resp, err := getWithRateLimit(client, limiter, fmt.Sprintf(discogsArtistURL, artistID))
+	if err != nil {
+		return nil, err
+	}
+	artist, err := parseArtistResponse(artistID, resp.Body())
+	if err != nil {
+		return nil, err
+	}
+
+	// Discogs may answer with the artist the requested one was merged into. The profile is kept
+	// under the requested id, which is the one the releases link to.
+	artist.Id = artistID
+	return artist, nil
+}
+
+// fetchMasters fetches the details of the masters of the label's releases that were never fetched
+// or are due again. Like artist profiles, masters that cannot be fetched are logged and skipped.
+func fetchMasters(store storage.Store, labelFilter models.LabelFilter, client *resty.Client, limiter *rateLimiter, config SyncConfig, tracker *SyncTracker) error {
+	masterIDs, err := store.FetchMastersToFetch(labelFilter, profileRefetchCutoff(config, time.Now()))
+	if err != nil {
+		return err
+	}
+	log.Printf("%d masters of label %d will be fetched", len(masterIDs), labelFilter.LabelId)
+	tracker.fetchingMasters(len(masterIDs))
+
+	failed := 0
+	for _, masterID := range masterIDs {
+		master, err := fetchMaster(masterID, client, limiter)
+		if err != nil {
+			log.Printf("Error fetching master %d: %v", masterID, err)
+			failed++
+			tracker.masterDone(true)
+			continue
+		}
+
+		if err := store.StoreMaster(master, time.Now()); err != nil {
+			return err
+		}
+		tracker.masterDone(false)
+	}
+
+	log.Printf("Fetched %d masters of label %d, %d failed", len(masterIDs)-failed, labelFilter.LabelId, failed)
+	return nil
+}
+
+func fetchMaster(masterID int, client *resty.Client, limiter *rateLimiter) (*models.Master, error) {
+	resp, err := getWithRateLimit(client, limiter, fmt.Sprintf(discogsMasterURL, masterID))
+	if err != nil {
+		return nil, err
+	}
+	master, err := parseMasterResponse(masterID, resp.Body())
+	if err != nil {
+		return nil, err
+	}
+
+	// Kept under the requested id, which is the one the releases link to, like merged artists.
+	master.Id = masterID
+	return master, nil
+}
+
+// collectCommunity fetches the label's stored releases again that were not fetched since the sync
+// started and stores them batchSize at a time, which records their current community statistics
+// and refreshes the releases. Releases that cannot be fetched or stored are logged and skipped.
+func collectCommunity(store storage.Store, labelFilter models.LabelFilter, client *resty.Client, limiter *rateLimiter, config SyncConfig, syncStartedAt time.Time, tracker *SyncTracker) error {
+	releaseIDs, err := store.FetchReleasesToSnapshot(labelFilter, syncStartedAt)
+	if err != nil {
+		return err
+	}
+	log.Printf("Community statistics of %d releases of label %d will be fetched", len(releaseIDs), labelFilter.LabelId)
+	tracker.fetchingCommunity(len(releaseIDs))
+
+	failed := 0
+	var batch []*models.Release
+	storeBatch := func() {
+		failedReleases := store.StoreReleases(batch)
+		for _, release := range batch {
+			if err, ok := failedReleases[release.Id]; ok {
+				log.Printf("Error storing community statistics of release %d: %v", release.Id, err)
+				failed++
+				tracker.communityDone(true)
+				continue
+			}
+			tracker.communityDone(false)
+		}
+		batch = nil
+	}
+
+	err = fetchReleaseDetails(releaseIDs, client, limiter, config.Workers, func(result fetchedRelease) error {
+		if result.err != nil {
+			log.Printf("Error fetching community statistics of release %d: %v", result.id, result.err)
+			failed++
+			tracker.communityDone(true)
+			return nil
+		}
+		batch = append(batch, result.release)
+		if len(batch) >= config.BatchSize {
+			storeBatch()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	storeBatch()
+
+	log.Printf("Fetched community statistics of %d releases of label %d, %d failed", len(releaseIDs)-failed, labelFilter.LabelId, failed)
+	return nil
+}
+
+// collectPrices records the current marketplace prices of all the label's releases, building a
+// price history with one snapshot per release and sync. Releases whose prices cannot be fetched
+// are logged and skipped.
+func collectPrices(store storage.Store, labelFilter models.LabelFilter, client *resty.Client, limiter *rateLimiter, config SyncConfig, tracker *SyncTracker) error {
+	releaseIDs, err := store.FetchReleasesToPrice(labelFilter)
+	if err != nil {
+		return err
+	}
+	log.Printf("Prices of %d releases of label %d will be fetched", len(releaseIDs), labelFilter.LabelId)
+	tracker.fetchingPrices(len(releaseIDs))
+
+	failed := 0
+	for _, releaseID := range releaseIDs {
+		price, err := fetchPrice(releaseID, config.PriceCurrency, client, limiter)
+		if err != nil {
+			log.Printf("Error fetching prices of release %d: %v", releaseID, err)
+			failed++
+			tracker.priceDone(true)
+			continue
+		}
+
+		price.CapturedAt = time.Now().UTC()
+		if err := store.StorePriceSnapshot(releaseID, price); err != nil {
+			return err
+		}
+		tracker.priceDone(false)
+	}
+
+	log.Printf("Fetched prices of %d releases of label %d, %d failed", len(releaseIDs)-failed, labelFilter.LabelId, failed)
+	return nil
+}
+
+func fetchPrice(releaseID int32, currency string, client *resty.Client, limiter *rateLimiter) (*models.PriceSnapshot, error) {
+	resp, err := getWithRateLimit(client, limiter, fmt.Sprintf(discogsPriceURL, releaseID, currency))
+	if err != nil {
+		return nil, err
+	}
+	return parsePriceResponse(releaseID, currency, resp.Body())
+}
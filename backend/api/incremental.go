@@ -0,0 +1,44 @@
+package api
+
+import "time"
+
+// selectReleasesToFetch returns the ids of releases that are missing from the database or, when
+// a maximum age is configured, were fetched longer ago than that age. A full refresh selects
+// every release.
+func selectReleasesToFetch(releaseIDs []int32, fetchedAt map[int32]time.Time, config SyncConfig, now time.Time) []int32 {
+	var selected []int32
+	seen := make(map[int32]bool, len(releaseIDs))
+
+	for _, releaseID := range releaseIDs {
+		if seen[releaseID] {
+			continue
+		}
+		seen[releaseID] = true
+
+		if config.FullRefresh || isStale(releaseID, fetchedAt, config.MaxAge, now) {
+			selected = append(selected, releaseID)
+		}
+	}
+	return selected
+}
+
+func isStale(releaseID int32, fetchedAt map[int32]time.Time, maxAge time.Duration, now time.Time) bool {
+	lastFetch, stored := fetchedAt[releaseID]
+	if !stored {
+		return true
+	}
+	return maxAge > 0 && now.Sub(lastFetch) > maxAge
+}
+
+// profileRefetchCutoff returns the time before which fetched artist profiles and masters are
+// fetched again, or nil when only those that were never fetched are due.
+func profileRefetchCutoff(config SyncConfig, now time.Time) *time.Time {
+	if config.FullRefresh {
+		return &now
+	}
+	if config.MaxAge > 0 {
+		cutoff := now.Add(-config.MaxAge)
+		return &cutoff
+	}
+	return nil
+}
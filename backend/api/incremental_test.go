@@ -0,0 +1,53 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectReleasesToFetch(t *testing.T) {
+	now := time.Date(2024, 10, 24, 12, 0, 0, 0, time.UTC)
+	releaseIDs := []int32{1, 2, 3, 3}
+	fetchedAt := map[int32]time.Time{
+		1: now.Add(-time.Hour),
+		2: now.Add(-48 * time.Hour),
+	}
+
+	tests := []struct {
+		name     string
+		config   SyncConfig
+		expected []int32
+	}{
+		{
+			name:     "missing only",
+			config:   SyncConfig{},
+			expected: []int32{3},
+		},
+		{
+			name:     "missing and stale",
+			config:   SyncConfig{MaxAge: 24 * time.Hour},
+			expected: []int32{2, 3},
+		},
+		{
+			name:     "full refresh",
+			config:   SyncConfig{FullRefresh: true},
+			expected: []int32{1, 2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, selectReleasesToFetch(releaseIDs, fetchedAt, tt.config, now))
+		})
+	}
+}
+
+func TestProfileRefetchCutoff(t *testing.T) {
+	now := time.Date(2024, 10, 24, 12, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, profileRefetchCutoff(SyncConfig{}, now))
+	assert.Equal(t, now.Add(-24*time.Hour), *profileRefetchCutoff(SyncConfig{MaxAge: 24 * time.Hour}, now))
+	assert.Equal(t, now, *profileRefetchCutoff(SyncConfig{FullRefresh: true, MaxAge: 24 * time.Hour}, now))
+}
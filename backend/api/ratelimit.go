@@ -0,0 +1,121 @@
+package api
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const maxBackoff = 60 * time.Second
+
+// rateLimiter is a token bucket shared by every worker talking to Discogs. It refills at the
+// configured rate and is corrected by the X-Discogs-Ratelimit-Remaining header of each response.
+type rateLimiter struct {
+	mu           sync.Mutex
+	tokens       float64
+	capacity     float64
+	refillRate   float64
+	lastRefill   time.Time
+	blockedUntil time.Time
+	backoff      time.Duration
+}
+
+func newRateLimiter(requestsPerMinute int) *rateLimiter {
+	capacity := float64(requestsPerMinute)
+	return &rateLimiter{
+		tokens:     capacity,
+		capacity:   capacity,
+		refillRate: capacity / 60,
+		lastRefill: time.Now(),
+	}
+}
+
+// Wait blocks until a request may be sent and takes a token for it.
+func (l *rateLimiter) Wait() {
+	for {
+		delay := l.reserve(time.Now())
+		if delay == 0 {
+			return
+		}
+		time.Sleep(delay)
+	}
+}
+
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(now)
+
+	if now.Before(l.blockedUntil) {
+		return l.blockedUntil.Sub(now)
+	}
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return l.tokenInterval(1 - l.tokens)
+}
+
+func (l *rateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.lastRefill).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	l.tokens = math.Min(l.capacity, l.tokens+elapsed*l.refillRate)
+	l.lastRefill = now
+}
+
+func (l *rateLimiter) tokenInterval(tokens float64) time.Duration {
+	return time.Duration(tokens / l.refillRate * float64(time.Second))
+}
+
+// Observe aligns the bucket with the number of requests Discogs says are left in the window.
+func (l *rateLimiter) Observe(resp *resty.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.backoff = 0
+
+	remaining, err := strconv.Atoi(resp.Header().Get("X-Discogs-Ratelimit-Remaining"))
+	if err != nil {
+		return
+	}
+	l.refill(time.Now())
+	l.tokens = math.Min(l.tokens, float64(remaining))
+}
+
+// Throttle empties the bucket and pauses all workers after a failed or rate limited request.
+// The pause starts at one refill interval and doubles on consecutive failures, unless Discogs
+// sends a Retry-After header.
+func (l *rateLimiter) Throttle(resp *resty.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.backoff == 0 {
+		l.backoff = l.tokenInterval(1)
+	} else {
+		l.backoff *= 2
+	}
+	if l.backoff > maxBackoff {
+		l.backoff = maxBackoff
+	}
+
+	delay := l.backoff
+	if resp != nil {
+		if retryAfter, err := strconv.Atoi(resp.Header().Get("Retry-After")); err == nil && retryAfter > 0 {
+			delay = time.Duration(retryAfter) * time.Second
+		}
+	}
+
+	now := time.Now()
+	l.refill(now)
+	l.tokens = 0
+	if blockedUntil := now.Add(delay); blockedUntil.After(l.blockedUntil) {
+		l.blockedUntil = blockedUntil
+		l.lastRefill = blockedUntil
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter := newRateLimiter(60)
+	now := limiter.lastRefill
+
+	for i := 0; i < 60; i++ {
+		assert.Equal(t, time.Duration(0), limiter.reserve(now))
+	}
+	assert.Equal(t, time.Second, limiter.reserve(now))
+	assert.Equal(t, time.Duration(0), limiter.reserve(now.Add(time.Second)))
+}
+
+func TestRateLimiterObserveRemaining(t *testing.T) {
+	limiter := newRateLimiter(60)
+	resp := &resty.Response{RawResponse: &http.Response{Header: http.Header{}}}
+	resp.RawResponse.Header.Set("X-Discogs-Ratelimit-Remaining", "2")
+
+	limiter.Observe(resp)
+
+	assert.InDelta(t, 2, limiter.tokens, 0.1)
+}
+
+func TestRateLimiterThrottle(t *testing.T) {
+	limiter := newRateLimiter(60)
+
+	limiter.Throttle(nil)
+	assert.Equal(t, time.Second, limiter.backoff)
+	assert.Zero(t, limiter.tokens)
+
+	limiter.Throttle(nil)
+	assert.Equal(t, 2*time.Second, limiter.backoff)
+
+	resp := &resty.Response{RawResponse: &http.Response{Header: http.Header{}}}
+	limiter.Observe(resp)
+	assert.Zero(t, limiter.backoff)
+}
+
+func TestGetWithRateLimitRetriesTooManyRequests(t *testing.T) {
+	client := resty.New()
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	url := "https://api.discogs.com/releases/123456"
+	httpmock.RegisterResponder("GET", url, httpmock.ResponderFromMultipleResponses([]*http.Response{
+		httpmock.NewStringResponse(http.StatusTooManyRequests, ""),
+		httpmock.NewStringResponse(http.StatusOK, mockedReleaseJSON),
+	}))
+
+	resp, err := getWithRateLimit(client, newRateLimiter(6000), url)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, 2, httpmock.GetTotalCallCount())
+}
+
+func TestGetWithRateLimitGivesUpAfterMaxAttempts(t *testing.T) {
+	client := resty.New()
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	url := "https://api.discogs.com/releases/123456"
+	httpmock.RegisterResponder("GET", url, httpmock.NewErrorResponder(errors.New("connection refused")))
+
+	resp, err := getWithRateLimit(client, newRateLimiter(6000), url)
+
+	assert.Nil(t, resp)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Equal(t, maxRequestAttempts, httpmock.GetTotalCallCount())
+}
@@ -0,0 +1,158 @@
+package api
+
+// Discogs API response bodies. Only the fields the sync uses are declared; unknown fields are
+// ignored and missing ones keep their zero value.
+
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+type pagination struct {
+	Page    int `json:"page"`
+	Pages   int `json:"pages"`
+	PerPage int `json:"per_page"`
+	Items   int `json:"items"`
+	Urls    struct {
+		Next string `json:"next"`
+		Last string `json:"last"`
+	} `json:"urls"`
+}
+
+type labelReleasesResponse struct {
+	errorResponse
+	Pagination pagination      `json:"pagination"`
+	Releases   *[]labelRelease `json:"releases"`
+}
+
+type labelRelease struct {
+	Id          int32  `json:"id"`
+	Title       string `json:"title"`
+	Catno       string `json:"catno"`
+	ResourceURL string `json:"resource_url"`
+}
+
+type labelRef struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	ResourceURL string `json:"resource_url"`
+}
+
+type labelResponse struct {
+	errorResponse
+	labelRef
+	ParentLabel *labelRef  `json:"parent_label"`
+	Sublabels   []labelRef `json:"sublabels"`
+}
+
+type artistCredit struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Anv         string `json:"anv"`
+	Join        string `json:"join"`
+	Role        string `json:"role"`
+	Tracks      string `json:"tracks"`
+	ResourceURL string `json:"resource_url"`
+}
+
+type releaseResponse struct {
+	errorResponse
+	Id          int32          `json:"id"`
+	Title       string         `json:"title"`
+	Year        int            `json:"year"`
+	Released    string         `json:"released"`
+	Country     string         `json:"country"`
+	DataQuality string         `json:"data_quality"`
+	Notes       string         `json:"notes"`
+	URI         string         `json:"uri"`
+	MasterId    int            `json:"master_id"`
+	Artists     []artistCredit `json:"artists"`
+	Credits     []artistCredit `json:"extraartists"`
+	Styles      []string       `json:"styles"`
+	Genres      []string       `json:"genres"`
+	Formats     []format       `json:"formats"`
+	Tracklist   []track        `json:"tracklist"`
+	Labels      []company      `json:"labels"`
+	Companies   []company      `json:"companies"`
+	Identifiers []identifier   `json:"identifiers"`
+	Community   *community     `json:"community"`
+}
+
+type community struct {
+	Have   int `json:"have"`
+	Want   int `json:"want"`
+	Rating struct {
+		Count   int     `json:"count"`
+		Average float64 `json:"average"`
+	} `json:"rating"`
+}
+
+type identifier struct {
+	Type        string `json:"type"`
+	Value       string `json:"value"`
+	Description string `json:"description"`
+}
+
+type company struct {
+	Id             int    `json:"id"`
+	Name           string `json:"name"`
+	Catno          string `json:"catno"`
+	EntityTypeName string `json:"entity_type_name"`
+}
+
+type track struct {
+	Position     string         `json:"position"`
+	Type         string         `json:"type_"`
+	Title        string         `json:"title"`
+	Duration     string         `json:"duration"`
+	Artists      []artistCredit `json:"artists"`
+	ExtraArtists []artistCredit `json:"extraartists"`
+	SubTracks    []track        `json:"sub_tracks"`
+}
+
+type format struct {
+	Name         string   `json:"name"`
+	Qty          string   `json:"qty"`
+	Text         string   `json:"text"`
+	Descriptions []string `json:"descriptions"`
+}
+
+type masterResponse struct {
+	errorResponse
+	Id          int    `json:"id"`
+	Title       string `json:"title"`
+	Year        int    `json:"year"`
+	MainRelease int    `json:"main_release"`
+	URI         string `json:"uri"`
+}
+
+type priceResponse struct {
+	errorResponse
+	LowestPrice     *price `json:"lowest_price"`
+	NumForSale      *int   `json:"num_for_sale"`
+	BlockedFromSale bool   `json:"blocked_from_sale"`
+}
+
+type price struct {
+	Currency string  `json:"currency"`
+	Value    float64 `json:"value"`
+}
+
+type artistResponse struct {
+	errorResponse
+	Id             int         `json:"id"`
+	Name           string      `json:"name"`
+	RealName       string      `json:"realname"`
+	Profile        string      `json:"profile"`
+	ResourceURL    string      `json:"resource_url"`
+	NameVariations []string    `json:"namevariations"`
+	Aliases        []artistRef `json:"aliases"`
+	Groups         []artistRef `json:"groups"`
+	Members        []artistRef `json:"members"`
+}
+
+type artistRef struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	ResourceURL string `json:"resource_url"`
+	Active      *bool  `json:"active"`
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"github.com/LissaGreense/discogs_record_label/backend/storage"
+	"github.com/robfig/cron/v3"
+	"log"
+	"sync"
+	"time"
+)
+
+// Scheduler syncs the labels one after another once at start and then on the configured schedule.
+// A run that is due while the previous one is still going is skipped instead of starting a second
+// sync.
+type Scheduler struct {
+	store    storage.Store
+	labelIDs []int
+	config   SyncConfig
+	tracker  *SyncTracker
+	running  sync.Mutex
+	syncFunc func(store storage.Store, labelID int, config SyncConfig, tracker *SyncTracker) error
+}
+
+func NewScheduler(store storage.Store, labelIDs []int, config SyncConfig, tracker *SyncTracker) *Scheduler {
+	return &Scheduler{
+		store:    store,
+		labelIDs: labelIDs,
+		config:   config,
+		tracker:  tracker,
+		syncFunc: FetchAndStoreReleases,
+	}
+}
+
+// Run blocks until the context is cancelled. Without a schedule it returns after the first sync.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.RunNow()
+
+	if s.config.Schedule == nil {
+		return
+	}
+
+	for {
+		next := s.config.Schedule.Next(time.Now())
+		s.tracker.scheduled(next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			go s.RunNow()
+		}
+	}
+}
+
+// RunNow syncs the labels unless a sync is already running and reports whether it ran. A failed
+// label does not stop the remaining ones from syncing.
+func (s *Scheduler) RunNow() bool {
+	if !s.running.TryLock() {
+		log.Println("Skipping sync, the previous sync is still running")
+		return false
+	}
+	defer s.running.Unlock()
+
+	for _, labelID := range s.labelIDs {
+		if err := s.syncFunc(s.store, labelID, s.config, s.tracker); err != nil {
+			log.Printf("Error fetching and storing releases of label %d: %v", labelID, err)
+			continue
+		}
+		log.Printf("Finished fetching and storing releases of label %d.", labelID)
+	}
+	return true
+}
+
+func parseSchedule(spec string) (cron.Schedule, error) {
+	if interval, err := time.ParseDuration(spec); err == nil {
+		spec = "@every " + interval.String()
+	}
+	return cron.ParseStandard(spec)
+}
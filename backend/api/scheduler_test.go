@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/LissaGreense/discogs_record_label/backend/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2024, 10, 24, 12, 0, 0, 0, time.UTC)
+
+	interval, err := parseSchedule("6h")
+	require.NoError(t, err)
+	assert.Equal(t, from.Add(6*time.Hour), interval.Next(from))
+
+	daily, err := parseSchedule("30 3 * * *")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 10, 25, 3, 30, 0, 0, time.UTC), daily.Next(from))
+
+	_, err = parseSchedule("every now and then")
+	assert.Error(t, err)
+}
+
+func TestSchedulerSkipsOverlappingRuns(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	runs := 0
+
+	scheduler := NewScheduler(nil, []int{5}, SyncConfig{}, NewSyncTracker())
+	scheduler.syncFunc = func(store storage.Store, labelID int, config SyncConfig, tracker *SyncTracker) error {
+		runs++
+		close(started)
+		<-release
+		return nil
+	}
+
+	finished := make(chan bool)
+	go func() { finished <- scheduler.RunNow() }()
+	<-started
+
+	assert.False(t, scheduler.RunNow())
+
+	close(release)
+	assert.True(t, <-finished)
+	assert.Equal(t, 1, runs)
+}
+
+func TestSchedulerSyncsEveryLabel(t *testing.T) {
+	var synced []int
+
+	scheduler := NewScheduler(nil, []int{5, 6, 7}, SyncConfig{}, NewSyncTracker())
+	scheduler.syncFunc = func(store storage.Store, labelID int, config SyncConfig, tracker *SyncTracker) error {
+		synced = append(synced, labelID)
+		if labelID == 6 {
+			return fmt.Errorf("label not found")
+		}
+		return nil
+	}
+
+	assert.True(t, scheduler.RunNow())
+	assert.Equal(t, []int{5, 6, 7}, synced)
+}
@@ -0,0 +1,354 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"github.com/LissaGreense/discogs_record_label/backend/storage"
+	"github.com/go-resty/resty/v2"
+	"log"
+	"sync"
+	"time"
+)
+
+// FetchAndStoreReleases syncs the releases of a label and reports its progress to the tracker.
+// Progress is also checkpointed in the database, so a sync that was cut short resumes from the
+// last listed page and the releases still pending. With a sublabel depth configured, the label's
+// sublabels are discovered and synced after it, level by level, each refreshing its stored releases
+// first when community statistics are collected. Masters, artist profiles and marketplace prices
+// are fetched last when enabled.
+func FetchAndStoreReleases(store storage.Store, labelID int, config SyncConfig, tracker *SyncTracker) error {
+	return syncLabelTree(store, labelID, createDiscogsClient(), newRateLimiter(config.RequestsPerMinute), config, tracker)
+}
+
+// syncLabelTree runs every pass of a sync and finishes the tracker once with the errors of all of
+// them, so that a pass that succeeds does not hide an earlier failure.
+func syncLabelTree(store storage.Store, labelID int, client *resty.Client, limiter *rateLimiter, config SyncConfig, tracker *SyncTracker) error {
+	err := runSyncPasses(store, labelID, client, limiter, config, tracker)
+	tracker.finish(err, time.Now())
+	return err
+}
+
+func runSyncPasses(store storage.Store, labelID int, client *resty.Client, limiter *rateLimiter, config SyncConfig, tracker *SyncTracker) error {
+	startedAt := time.Now()
+	sublabels, err := syncLabelReleases(store, labelID, 0, client, limiter, config, startedAt, tracker)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{syncSublabels(store, labelID, sublabels, client, limiter, config, startedAt, tracker)}
+
+	labelFilter := models.LabelFilter{LabelId: labelID, IncludeSublabels: config.SublabelDepth > 0}
+	if config.FetchMasters {
+		errs = append(errs, fetchMasters(store, labelFilter, client, limiter, config, tracker))
+	}
+	if config.EnrichArtists {
+		errs = append(errs, enrichArtists(store, labelFilter, client, limiter, config, tracker))
+	}
+	if config.CollectPrices {
+		errs = append(errs, collectPrices(store, labelFilter, client, limiter, config, tracker))
+	}
+	return errors.Join(errs...)
+}
+
+// syncSublabels walks the sublabel tree breadth first up to the configured depth. A failing
+// sublabel does not stop its siblings; the errors are returned together at the end.
+func syncSublabels(store storage.Store, labelID int, sublabels []*models.Label, client *resty.Client, limiter *rateLimiter, config SyncConfig, syncStartedAt time.Time, tracker *SyncTracker) error {
+	type queuedLabel struct {
+		id    int
+		depth int
+	}
+
+	visited := map[int]bool{labelID: true}
+	var queue []queuedLabel
+	enqueue := func(labels []*models.Label, depth int) {
+		for _, label := range labels {
+			if !visited[label.Id] {
+				visited[label.Id] = true
+				queue = append(queue, queuedLabel{id: label.Id, depth: depth})
+			}
+		}
+	}
+	enqueue(sublabels, 1)
+
+	var errs []error
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		children, err := syncLabelReleases(store, next.id, next.depth, client, limiter, config, syncStartedAt, tracker)
+		if err != nil {
+			log.Printf("Error fetching and storing releases of sublabel %d: %v", next.id, err)
+			errs = append(errs, fmt.Errorf("sublabel %d: %v", next.id, err))
+			continue
+		}
+		enqueue(children, next.depth+1)
+	}
+
+	return errors.Join(errs...)
+}
+
+// syncLabelReleases runs one checkpointed sync of a single label and returns the sublabels that
+// should be synced after it.
+func syncLabelReleases(store storage.Store, labelID int, depth int, client *resty.Client, limiter *rateLimiter, config SyncConfig, syncStartedAt time.Time, tracker *SyncTracker) ([]*models.Label, error) {
+	firstPageURL := fmt.Sprintf(discogsLabelAPIURL, labelID, perPage)
+	startedAt := time.Now()
+	tracker.start(labelID, startedAt)
+
+	state, err := store.StartSyncRun(labelID, firstPageURL, startedAt)
+	if err != nil {
+		return nil, err
+	}
+	tracker.attach(state)
+	if state.PagesListed > 0 {
+		log.Printf("Resuming sync of label %d after %d listed pages", labelID, state.PagesListed)
+	}
+
+	sublabels, runErr := syncLabel(store, state, depth, client, limiter, config, syncStartedAt, tracker)
+	if err := store.FinishSyncRun(state, runErr, time.Now()); err != nil {
+		log.Printf("Error finishing sync run %d: %v", state.RunId, err)
+	}
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	run, err := store.FetchSyncRun(state.RunId)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Sync run %d of label %d listed %d releases, fetched %d and failed %d",
+		run.Id, labelID, run.ReleasesListed, run.ReleasesFetched, run.ReleasesFailed)
+
+	return sublabels, nil
+}
+
+// syncLabel lists the label's releases and fetches the pending ones. With CollectCommunity the
+// stored releases are refreshed first; pending releases that were already fetched since the sync
+// started, by that pass or as a release of another label in the tree, are not fetched again.
+func syncLabel(store storage.Store, state *models.SyncState, depth int, client *resty.Client, limiter *rateLimiter, config SyncConfig, syncStartedAt time.Time, tracker *SyncTracker) ([]*models.Label, error) {
+	sublabels, err := fetchLabelAndSave(store, state.LabelId, depth < config.SublabelDepth, client, limiter)
+	if err != nil {
+		return nil, err
+	}
+
+	if !state.ListingComplete {
+		if err := listPendingReleases(store, state, client, limiter, config, tracker); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.CollectCommunity {
+		labelFilter := models.LabelFilter{LabelId: state.LabelId}
+		if err := collectCommunity(store, labelFilter, client, limiter, config, syncStartedAt, tracker); err != nil {
+			return nil, err
+		}
+	}
+
+	pendingIDs, err := skipReleasesFetchedSince(store, state, syncStartedAt)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("%d releases of label %d will be fetched", len(pendingIDs), state.LabelId)
+	tracker.fetching(len(pendingIDs))
+
+	if err := fetchReleasesDetailAndSave(store, state, pendingIDs, client, limiter, config.Workers, config.BatchSize, tracker); err != nil {
+		return nil, err
+	}
+	return sublabels, nil
+}
+
+// listPendingReleases pages through the label's releases from the checkpoint onwards and marks the
+// ones selected for fetching as pending, saving the checkpoint after every page.
+func listPendingReleases(store storage.Store, state *models.SyncState, client *resty.Client, limiter *rateLimiter, config SyncConfig, tracker *SyncTracker) error {
+	fetchedAt, err := store.FetchReleaseFetchTimes()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	for !state.ListingComplete {
+		resp, err := getWithRateLimit(client, limiter, state.NextPageURL)
+		if err != nil {
+			return err
+		}
+
+		releaseIDs, err := parseReleases(resp.Body())
+		if err != nil {
+			return err
+		}
+
+		nextPageURL, _ := getNextPageURL(resp.Body())
+		pendingIDs := selectReleasesToFetch(releaseIDs, fetchedAt, config, now)
+
+		if err := store.SaveListedPage(state, releaseIDs, pendingIDs, nextPageURL); err != nil {
+			return err
+		}
+		tracker.pageListed(len(releaseIDs))
+	}
+	return nil
+}
+
+// skipReleasesFetchedSince returns the label's pending releases, completing those already fetched
+// since the sync started instead.
+func skipReleasesFetchedSince(store storage.Store, state *models.SyncState, syncStartedAt time.Time) ([]int32, error) {
+	pendingIDs, err := store.FetchPendingReleaseIDs(state.LabelId)
+	if err != nil {
+		return nil, err
+	}
+	fetchedAt, err := store.FetchReleaseFetchTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	var releaseIDs []int32
+	for _, releaseID := range pendingIDs {
+		if fetched, ok := fetchedAt[releaseID]; ok && !fetched.Before(syncStartedAt) {
+			if err := store.CompletePendingRelease(state, releaseID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		releaseIDs = append(releaseIDs, releaseID)
+	}
+	return releaseIDs, nil
+}
+
+// fetchLabelAndSave stores the label and, when its sublabels are to be synced as well, the
+// sublabels with their parent, which it then returns.
+func fetchLabelAndSave(store storage.Store, labelID int, withSublabels bool, client *resty.Client, limiter *rateLimiter) ([]*models.Label, error) {
+	resp, err := getWithRateLimit(client, limiter, fmt.Sprintf(discogsLabelInfoURL, labelID))
+	if err != nil {
+		return nil, err
+	}
+
+	label, sublabels, err := parseLabelResponse(resp.Body())
+	if err != nil {
+		return nil, fmt.Errorf("label %d: %v", labelID, err)
+	}
+
+	if err := store.StoreLabel(label); err != nil {
+		return nil, err
+	}
+
+	if !withSublabels {
+		return nil, nil
+	}
+	for _, sublabel := range sublabels {
+		if err := store.StoreLabel(sublabel); err != nil {
+			return nil, err
+		}
+	}
+	return sublabels, nil
+}
+
+// fetchedRelease is a release fetched by a worker, or the error fetching it.
+type fetchedRelease struct {
+	id      int32
+	release *models.Release
+	err     error
+}
+
+// fetchReleasesDetailAndSave fetches the pending releases with a pool of workers and stores them
+// batchSize at a time. Releases that cannot be fetched or stored are recorded as failed; only
+// errors updating the checkpoint stop the pool.
+func fetchReleasesDetailAndSave(store storage.Store, state *models.SyncState, releaseIDs []int32, client *resty.Client, limiter *rateLimiter, workers int, batchSize int, tracker *SyncTracker) error {
+	var batch []*models.Release
+	err := fetchReleaseDetails(releaseIDs, client, limiter, workers, func(result fetchedRelease) error {
+		if result.err != nil {
+			return failRelease(store, state, result.id, result.err, tracker)
+		}
+		batch = append(batch, result.release)
+		if len(batch) < batchSize {
+			return nil
+		}
+		err := saveReleaseBatch(store, state, batch, tracker)
+		batch = nil
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return saveReleaseBatch(store, state, batch, tracker)
+}
+
+// fetchReleaseDetails fetches the releases with a pool of workers sharing one rate limiter and
+// passes each of them, or the error fetching it, to handle. Once handle returns an error no more
+// releases are requested and that error is returned.
+func fetchReleaseDetails(releaseIDs []int32, client *resty.Client, limiter *rateLimiter, workers int, handle func(result fetchedRelease) error) error {
+	ids := make(chan int32)
+	fetched := make(chan fetchedRelease)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for releaseID := range ids {
+				release, err := fetchReleaseDetail(releaseID, client, limiter)
+				fetched <- fetchedRelease{id: releaseID, release: release, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(ids)
+		for _, releaseID := range releaseIDs {
+			select {
+			case ids <- releaseID:
+			case <-done:
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(fetched)
+	}()
+
+	// The fetched releases are read until the workers stop, even after an error, so that none
+	// of them blocks.
+	var firstErr error
+	for result := range fetched {
+		if firstErr != nil {
+			continue
+		}
+		if firstErr = handle(result); firstErr != nil {
+			close(done)
+		}
+	}
+	return firstErr
+}
+
+// saveReleaseBatch stores a batch of fetched releases and checks each of them off the checkpoint,
+// as completed or as failed when it could not be stored.
+func saveReleaseBatch(store storage.Store, state *models.SyncState, batch []*models.Release, tracker *SyncTracker) error {
+	failed := store.StoreReleases(batch)
+	for _, release := range batch {
+		if err, ok := failed[release.Id]; ok {
+			if err := failRelease(store, state, release.Id, err, tracker); err != nil {
+				return err
+			}
+			continue
+		}
+		tracker.releaseDone(false)
+		if err := store.CompletePendingRelease(state, release.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func failRelease(store storage.Store, state *models.SyncState, releaseID int32, err error, tracker *SyncTracker) error {
+	log.Printf("Error syncing release %d: %v", releaseID, err)
+	tracker.releaseDone(true)
+	return store.FailPendingRelease(state, releaseID, err, time.Now())
+}
+
+func fetchReleaseDetail(releaseID int32, client *resty.Client, limiter *rateLimiter) (*models.Release, error) {
+	resp, err := getWithRateLimit(client, limiter, fmt.Sprintf(discogsReleaseAPIURL, releaseID))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseReleaseResponse(releaseID, resp.Body())
+}
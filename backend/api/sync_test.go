@@ -0,0 +1,178 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"github.com/LissaGreense/discogs_record_label/backend/storage"
+	"github.com/go-resty/resty/v2"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchReleasesDetailAndSaveRecordsFailures(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	client := resty.New()
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/releases/123456",
+		httpmock.NewStringResponder(http.StatusOK, mockedReleaseJSON))
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/releases/404",
+		httpmock.NewStringResponder(http.StatusNotFound, `{"message": "Release not found."}`))
+
+	state := &models.SyncState{LabelId: 5, RunId: 7}
+
+	// The release that cannot be fetched fails right away, the fetched one when its batch is stored.
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM sync_pending_releases").WithArgs(5, int32(404)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO sync_failed_releases").WithArgs(7, int32(404), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE sync_runs SET releases_failed").WithArgs(1, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO releases").WithArgs(int32(123456), "Some Title", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+		sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	for _, tableName := range []string{"artists", "release_artists", "genres", "styles", "formats", "tracks", "credits",
+		"release_companies", "identifiers"} {
+		mock.ExpectExec("DELETE FROM " + tableName).WithArgs(int32(123456)).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	for _, tableName := range []string{"discogs_artists", "release_artists", "genres", "styles"} {
+		mock.ExpectExec("INSERT INTO " + tableName).WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectQuery("INSERT INTO formats").WillReturnRows(sqlmock.NewRows([]string{"id", "release_id", "position"}).AddRow(1, 123456, 0))
+	mock.ExpectExec("INSERT INTO format_descriptions").WithArgs(1, `12"`, 1, "33 ⅓ RPM").WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM sync_pending_releases").WithArgs(5, int32(123456)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE sync_runs SET releases_fetched").WithArgs(1, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tracker := NewSyncTracker()
+	tracker.fetching(2)
+
+	err = fetchReleasesDetailAndSave(storage.NewPostgresStore(db), state, []int32{123456, 404}, client, newRateLimiter(6000), 1, 2, tracker)
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	status := tracker.Status()
+	assert.Equal(t, models.SyncPhaseFetching, status.Phase)
+	assert.Equal(t, 0, status.ReleasesPending)
+	assert.Equal(t, 1, status.ReleasesFetched)
+	assert.Equal(t, 1, status.ReleasesFailed)
+}
+
+func TestEnrichArtistsSkipsFailedProfiles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	client := resty.New()
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/artists/11",
+		httpmock.NewStringResponder(http.StatusOK, `{"id": 11, "name": "John Smith (2)", "profile": "Drummer."}`))
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/artists/404",
+		httpmock.NewStringResponder(http.StatusNotFound, `{"message": "Artist not found."}`))
+
+	mock.ExpectQuery("SELECT da.id FROM discogs_artists da").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11).AddRow(404))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO discogs_artists").
+		WithArgs(11, "John Smith (2)", "", "", "Drummer.", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM artist_name_variations").WithArgs(11).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM artist_relations").WithArgs(11).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	tracker := NewSyncTracker()
+	err = enrichArtists(storage.NewPostgresStore(db), models.LabelFilter{LabelId: 5}, client, newRateLimiter(6000), SyncConfig{}, tracker)
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	status := tracker.Status()
+	assert.Equal(t, models.SyncPhaseEnriching, status.Phase)
+	assert.Nil(t, status.FinishedAt)
+	assert.Equal(t, 0, status.ArtistsPending)
+	assert.Equal(t, 1, status.ArtistsEnriched)
+	assert.Equal(t, 1, status.ArtistsFailed)
+}
+
+func TestSyncCollectsCommunityOfStoredReleases(t *testing.T) {
+	client := resty.New()
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/labels/5",
+		httpmock.NewStringResponder(http.StatusOK, `{"id": 5, "name": "Warp Records"}`))
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/labels/5/releases?page=1&per_page=100",
+		httpmock.NewStringResponder(http.StatusOK, mockedReleasesJSON))
+	calls := 0
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/releases/123456", func(*http.Request) (*http.Response, error) {
+		calls++
+		return httpmock.NewStringResponse(http.StatusOK, fmt.Sprintf(`{"id": 123456, "title": "Title %d",
+			"community": {"have": 100, "want": %d, "rating": {"count": 0, "average": 0}}}`, calls, 40+5*calls)), nil
+	})
+	store := storage.NewMemoryStore()
+	runSync := func(config SyncConfig) {
+		require.NoError(t, syncLabelTree(store, 5, client, newRateLimiter(6000), config, NewSyncTracker()))
+	}
+
+	// Without CollectCommunity a sync with no new release requests none.
+	runSync(SyncConfig{Workers: 1, BatchSize: 1})
+	runSync(SyncConfig{Workers: 1, BatchSize: 1})
+	assert.Equal(t, 1, calls)
+
+	// The stored release is due again by age. The community pass refreshes it and the detail fetch
+	// does not request it a second time.
+	runSync(SyncConfig{Workers: 1, BatchSize: 1, MaxAge: time.Nanosecond, CollectCommunity: true})
+	assert.Equal(t, 2, calls)
+
+	history, err := store.FetchCommunityHistory(123456, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 45, history[0].Want)
+	assert.Equal(t, 50, history[1].Want)
+	release, err := store.FetchRelease(123456)
+	require.NoError(t, err)
+	assert.Equal(t, "Title 2", release.Title)
+}
+
+func TestSyncReportsFailedSublabelAfterLaterPasses(t *testing.T) {
+	client := resty.New()
+	httpmock.ActivateNonDefault(client.GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/labels/5",
+		httpmock.NewStringResponder(http.StatusOK, `{"id": 5, "name": "Warp Records", "sublabels": [{"id": 6, "name": "Warp Sublabel"}]}`))
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/labels/5/releases?page=1&per_page=100",
+		httpmock.NewStringResponder(http.StatusOK, mockedReleasesJSON))
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/releases/123456",
+		httpmock.NewStringResponder(http.StatusOK, mockedReleaseJSON))
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/labels/6",
+		httpmock.NewStringResponder(http.StatusNotFound, `{"message": "Label not found."}`))
+	httpmock.RegisterResponder("GET", "https://api.discogs.com/artists/11",
+		httpmock.NewStringResponder(http.StatusOK, `{"id": 11, "name": "Some Artist (2)"}`))
+
+	// The artist pass runs after the failed sublabel and must not report the sync as succeeded.
+	tracker := NewSyncTracker()
+	config := SyncConfig{Workers: 1, BatchSize: 1, SublabelDepth: 1, EnrichArtists: true}
+	err := syncLabelTree(storage.NewMemoryStore(), 5, client, newRateLimiter(6000), config, tracker)
+
+	require.Error(t, err)
+	status := tracker.Status()
+	assert.Equal(t, models.SyncPhaseFailed, status.Phase)
+	assert.Contains(t, status.LastError, "sublabel 6")
+	assert.NotNil(t, status.FinishedAt)
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["GET https://api.discogs.com/artists/11"])
+}
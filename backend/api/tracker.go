@@ -0,0 +1,163 @@
+package api
+
+import (
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"sync"
+	"time"
+)
+
+// SyncTracker keeps the progress of the running sync so it can be reported while the server is
+// already serving requests. It is safe for concurrent use.
+type SyncTracker struct {
+	mu     sync.Mutex
+	status models.SyncStatus
+}
+
+func NewSyncTracker() *SyncTracker {
+	return &SyncTracker{status: models.SyncStatus{Phase: models.SyncPhaseIdle}}
+}
+
+func (t *SyncTracker) Status() models.SyncStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
+
+func (t *SyncTracker) update(change func(status *models.SyncStatus)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	change(&t.status)
+}
+
+func (t *SyncTracker) start(labelID int, startedAt time.Time) {
+	t.update(func(status *models.SyncStatus) {
+		*status = models.SyncStatus{
+			Phase:     models.SyncPhaseListing,
+			LabelId:   labelID,
+			StartedAt: &startedAt,
+			NextRunAt: status.NextRunAt,
+		}
+	})
+}
+
+func (t *SyncTracker) scheduled(nextRunAt time.Time) {
+	t.update(func(status *models.SyncStatus) {
+		status.NextRunAt = &nextRunAt
+	})
+}
+
+func (t *SyncTracker) attach(state *models.SyncState) {
+	t.update(func(status *models.SyncStatus) {
+		status.RunId = state.RunId
+		status.PagesListed = state.PagesListed
+	})
+}
+
+func (t *SyncTracker) pageListed(listedCount int) {
+	t.update(func(status *models.SyncStatus) {
+		status.PagesListed++
+		status.ReleasesListed += listedCount
+	})
+}
+
+func (t *SyncTracker) fetching(pendingCount int) {
+	t.update(func(status *models.SyncStatus) {
+		status.Phase = models.SyncPhaseFetching
+		status.ReleasesPending = pendingCount
+	})
+}
+
+func (t *SyncTracker) releaseDone(failed bool) {
+	t.update(func(status *models.SyncStatus) {
+		status.ReleasesPending--
+		if failed {
+			status.ReleasesFailed++
+		} else {
+			status.ReleasesFetched++
+		}
+	})
+}
+
+func (t *SyncTracker) fetchingCommunity(pendingCount int) {
+	t.update(func(status *models.SyncStatus) {
+		status.Phase = models.SyncPhaseCommunity
+		status.CommunityPending = pendingCount
+	})
+}
+
+func (t *SyncTracker) communityDone(failed bool) {
+	t.update(func(status *models.SyncStatus) {
+		status.CommunityPending--
+		if failed {
+			status.CommunityFailed++
+		} else {
+			status.CommunityFetched++
+		}
+	})
+}
+
+func (t *SyncTracker) fetchingMasters(pendingCount int) {
+	t.update(func(status *models.SyncStatus) {
+		status.Phase = models.SyncPhaseMasters
+		status.MastersPending = pendingCount
+	})
+}
+
+func (t *SyncTracker) masterDone(failed bool) {
+	t.update(func(status *models.SyncStatus) {
+		status.MastersPending--
+		if failed {
+			status.MastersFailed++
+		} else {
+			status.MastersFetched++
+		}
+	})
+}
+
+func (t *SyncTracker) enriching(pendingCount int) {
+	t.update(func(status *models.SyncStatus) {
+		status.Phase = models.SyncPhaseEnriching
+		status.ArtistsPending = pendingCount
+	})
+}
+
+func (t *SyncTracker) artistDone(failed bool) {
+	t.update(func(status *models.SyncStatus) {
+		status.ArtistsPending--
+		if failed {
+			status.ArtistsFailed++
+		} else {
+			status.ArtistsEnriched++
+		}
+	})
+}
+
+func (t *SyncTracker) fetchingPrices(pendingCount int) {
+	t.update(func(status *models.SyncStatus) {
+		status.Phase = models.SyncPhasePrices
+		status.PricesPending = pendingCount
+	})
+}
+
+func (t *SyncTracker) priceDone(failed bool) {
+	t.update(func(status *models.SyncStatus) {
+		status.PricesPending--
+		if failed {
+			status.PricesFailed++
+		} else {
+			status.PricesFetched++
+		}
+	})
+}
+
+func (t *SyncTracker) finish(runErr error, finishedAt time.Time) {
+	t.update(func(status *models.SyncStatus) {
+		status.FinishedAt = &finishedAt
+		status.Phase = models.SyncPhaseSucceeded
+		status.LastError = ""
+		if runErr != nil {
+			status.Phase = models.SyncPhaseFailed
+			status.LastError = runErr.Error()
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"github.com/LissaGreense/discogs_record_label/backend/storage"
+	"time"
+)
+
+// demoLabelID is the label the demo data is listed under, Warp Records.
+const demoLabelID = 23528
+
+// seedDemoStore fills store with a small label catalog, so the frontend can be tried without a
+// database or Discogs credentials. The data goes through a sync run like synced releases do.
+func seedDemoStore(store storage.Store) error {
+	if err := store.StoreLabel(&models.Label{Id: demoLabelID, Name: "Warp Records"}); err != nil {
+		return err
+	}
+
+	syncedAt := time.Now()
+	state, err := store.StartSyncRun(demoLabelID, fmt.Sprintf("https://api.discogs.com/labels/%d/releases", demoLabelID), syncedAt)
+	if err != nil {
+		return err
+	}
+	releases := demoReleases()
+	var releaseIDs []int32
+	for _, release := range releases {
+		releaseIDs = append(releaseIDs, release.Id)
+	}
+	if err := store.SaveListedPage(state, releaseIDs, releaseIDs, ""); err != nil {
+		return err
+	}
+	for _, release := range releases {
+		if err := store.StoreRelease(release); err != nil {
+			return err
+		}
+		if err := store.CompletePendingRelease(state, release.Id); err != nil {
+			return err
+		}
+	}
+	return store.FinishSyncRun(state, nil, syncedAt)
+}
+
+func demoReleases() []*models.Release {
+	warp := func(catno string) []models.ReleaseLabel {
+		return []models.ReleaseLabel{{Id: demoLabelID, Name: "Warp Records", Catno: catno}}
+	}
+	return []*models.Release{
+		{
+			Id: 1001, Title: "Artificial Intelligence", Year: 1992, Country: "UK", Released: "1992-07-10",
+			Labels:    warp("WARP LP 6"),
+			Community: &models.CommunityStats{Have: 5200, Want: 2100, RatingAverage: 4.5, RatingCount: 410},
+			Artists:   []models.ReleaseArtist{{Id: 194, Name: "Various"}},
+			Genres:    []string{"Electronic"},
+			Styles:    []string{"Ambient", "IDM", "Techno"},
+			Formats:   []models.Format{{Name: "Vinyl", Qty: 1, Descriptions: []string{"Compilation", "LP"}}},
+			Tracks: []models.Track{
+				{Position: "A1", Title: "Polygon Window", Duration: "5:28", DurationSeconds: 328,
+					Artists: []models.TrackArtist{{Name: "The Dice Man", Role: "Written-By"}}},
+				{Position: "A2", Title: "Telefone 529", Duration: "5:03", DurationSeconds: 303,
+					Artists: []models.TrackArtist{{Name: "Musicology", Role: "Written-By"}}},
+			},
+			Credits:   []models.Credit{{ArtistId: 3501, Name: "The Designers Republic", Role: "Design"}},
+			Companies: []models.Company{{Id: 264990, Name: "Porky's", Role: "Mastered At"}},
+		},
+		{
+			Id: 1002, Title: "Selected Ambient Works Volume II", Year: 1994, Country: "UK", MasterId: 565,
+			Released:    "1994-03-07",
+			Labels:      warp("WARP LP 21"),
+			Identifiers: []models.Identifier{{Type: "Barcode", Value: "5 021603 02126 3"}},
+			Community:   &models.CommunityStats{Have: 9800, Want: 6400, RatingAverage: 4.6, RatingCount: 1320},
+			Artists:     []models.ReleaseArtist{{Id: 45, Name: "Aphex Twin"}},
+			Genres:      []string{"Electronic"},
+			Styles:      []string{"Ambient"},
+			Formats:     []models.Format{{Name: "Vinyl", Qty: 3, Descriptions: []string{"Album", "LP"}}},
+			Tracks: []models.Track{
+				{Position: "A1", Title: "#1", Duration: "7:25", DurationSeconds: 445},
+				{Position: "A2", Title: "#2", Duration: "8:47", DurationSeconds: 527},
+			},
+			Credits:   []models.Credit{{ArtistId: 45, Name: "Aphex Twin", Role: "Written-By"}},
+			Companies: []models.Company{{Id: 30513, Name: "MPO", Role: "Pressed By"}},
+		},
+		{
+			Id: 1003, Title: "Selected Ambient Works Volume II", Year: 1994, Country: "Europe", MasterId: 565,
+			Labels:    warp("WARP CD 21"),
+			Community: &models.CommunityStats{Have: 7300, Want: 2900, RatingAverage: 4.6, RatingCount: 740},
+			Artists:   []models.ReleaseArtist{{Id: 45, Name: "Aphex Twin"}},
+			Genres:    []string{"Electronic"},
+			Styles:    []string{"Ambient"},
+			Formats:   []models.Format{{Name: "CD", Qty: 2, Descriptions: []string{"Album"}}},
+			Tracks:    []models.Track{{Position: "1-1", Title: "#1", Duration: "7:25", DurationSeconds: 445}},
+			Credits:   []models.Credit{{ArtistId: 45, Name: "Aphex Twin", Role: "Written-By"}},
+			Companies: []models.Company{{Id: 30513, Name: "MPO", Role: "Pressed By"}},
+		},
+		{
+			Id: 1004, Title: "Music Has The Right To Children", Year: 1998, Country: "UK", Released: "1998-04-20",
+			Labels:    warp("WARP LP 55"),
+			Community: &models.CommunityStats{Have: 11000, Want: 8700, RatingAverage: 4.7, RatingCount: 1900},
+			Artists:   []models.ReleaseArtist{{Id: 2081, Name: "Boards Of Canada"}},
+			Genres:    []string{"Electronic"},
+			Styles:    []string{"Downtempo", "IDM"},
+			Formats:   []models.Format{{Name: "Vinyl", Qty: 2, Descriptions: []string{"Album", "LP"}}},
+			Tracks: []models.Track{
+				{Position: "A1", Title: "Wildlife Analysis", Duration: "1:16", DurationSeconds: 76},
+				{Position: "A2", Title: "An Eagle In Your Mind", Duration: "6:23", DurationSeconds: 383},
+			},
+			Credits: []models.Credit{{ArtistId: 2082, Name: "Marcus Eoin", Role: "Written-By"},
+				{ArtistId: 2083, Name: "Michael Sandison", Role: "Written-By"}},
+			Companies: []models.Company{{Id: 264990, Name: "Porky's", Role: "Mastered At"}},
+		},
+		{
+			Id: 1005, Title: "Tri Repetae", Year: 1995, Country: "UK", Released: "1995-11-06",
+			Labels:    warp("WARP LP 38"),
+			Community: &models.CommunityStats{Have: 6100, Want: 3300, RatingAverage: 4.5, RatingCount: 620},
+			Artists:   []models.ReleaseArtist{{Id: 12, Name: "Autechre"}},
+			Genres:    []string{"Electronic"},
+			Styles:    []string{"Experimental", "IDM"},
+			Formats:   []models.Format{{Name: "Vinyl", Qty: 2, Descriptions: []string{"Album", "LP"}}},
+			Tracks:    []models.Track{{Position: "A1", Title: "Dael", Duration: "6:37", DurationSeconds: 397}},
+			Credits:   []models.Credit{{ArtistId: 3501, Name: "The Designers Republic", Role: "Design"}},
+			Companies: []models.Company{{Id: 30513, Name: "MPO", Role: "Pressed By"}},
+		},
+	}
+}
@@ -1,41 +1,246 @@
 package graphQL
 
 import (
-	"database/sql"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"github.com/LissaGreense/discogs_record_label/backend/storage"
 	"github.com/graphql-go/graphql"
 )
 
-func NewQueryType(db *sql.DB) *graphql.Object {
+func NewQueryType(store storage.Store, syncStatus SyncStatusProvider) *graphql.Object {
 	return graphql.NewObject(graphql.ObjectConfig{
 		Name: "Query",
 		Fields: graphql.Fields{
 			"releaseCounts": &graphql.Field{
-				Type: CountResultType,
+				Type:    CountResultType,
+				Args:    countArgs(releaseFilterArgs()),
+				Resolve: ReleaseCountsResolver(store),
+			},
+			"release": &graphql.Field{
+				Type: ReleaseType,
 				Args: graphql.FieldConfigArgument{
-					"artist": &graphql.ArgumentConfig{
-						Type: graphql.String,
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+				},
+				Resolve: ReleaseResolver(store),
+			},
+			"releaseByIdentifier": &graphql.Field{
+				Type: graphql.NewList(ReleaseType),
+				Args: graphql.FieldConfigArgument{
+					"value": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
 					},
-					"style": &graphql.ArgumentConfig{
+					"type": &graphql.ArgumentConfig{
 						Type: graphql.String,
 					},
-					"genre": &graphql.ArgumentConfig{
+				},
+				Resolve: ReleaseByIdentifierResolver(store),
+			},
+			"releases": &graphql.Field{
+				Type:    graphql.NewList(ReleaseType),
+				Args:    orderArgs(pageArgs(releaseFilterArgs())),
+				Resolve: ReleasesResolver(store),
+			},
+			"trackSearch": &graphql.Field{
+				Type: graphql.NewList(ReleaseType),
+				Args: pageArgs(graphql.FieldConfigArgument{
+					"title": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				}),
+				Resolve: TrackSearchResolver(store),
+			},
+			"communityHistory": &graphql.Field{
+				Type: graphql.NewList(CommunityStatsType),
+				Args: graphql.FieldConfigArgument{
+					"release": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+					"from": &graphql.ArgumentConfig{
+						Type: graphql.DateTime,
+					},
+					"until": &graphql.ArgumentConfig{
+						Type: graphql.DateTime,
+					},
+				},
+				Resolve: CommunityHistoryResolver(store),
+			},
+			"mostWanted": &graphql.Field{
+				Type:    graphql.NewList(ReleaseType),
+				Args:    limitArgs(labelArgs()),
+				Resolve: MostWantedResolver(store),
+			},
+			"trending": &graphql.Field{
+				Type:    graphql.NewList(WantGrowthType),
+				Args:    limitArgs(periodArgs(labelArgs())),
+				Resolve: TrendingResolver(store),
+			},
+			"priceHistory": &graphql.Field{
+				Type: graphql.NewList(PriceSnapshotType),
+				Args: graphql.FieldConfigArgument{
+					"release": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+					"currency": &graphql.ArgumentConfig{
 						Type: graphql.String,
 					},
+					"from": &graphql.ArgumentConfig{
+						Type: graphql.DateTime,
+					},
+					"until": &graphql.ArgumentConfig{
+						Type: graphql.DateTime,
+					},
+				},
+				Resolve: PriceHistoryResolver(store),
+			},
+			"priceStats": &graphql.Field{
+				Type:    graphql.NewList(PriceStatsType),
+				Args:    priceStatsArgs(labelArgs()),
+				Resolve: PriceStatsResolver(store),
+			},
+			"master": &graphql.Field{
+				Type: MasterType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+				},
+				Resolve: MasterResolver(store),
+			},
+			"artist": &graphql.Field{
+				Type: ArtistType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
 				},
-				Resolve: ReleaseCountsResolver(db),
+				Resolve: ArtistResolver(store),
 			},
 			"uniqueArtists": &graphql.Field{
-				Type:    graphql.NewList(UniqueNameType),
-				Resolve: UniqueArtistsResolver(db),
+				Type:    graphql.NewList(ArtistType),
+				Args:    labelArgs(),
+				Resolve: UniqueArtistsResolver(store),
 			},
 			"uniqueGenres": &graphql.Field{
 				Type:    graphql.NewList(UniqueNameType),
-				Resolve: UniqueGenresResolver(db),
+				Args:    labelArgs(),
+				Resolve: UniqueGenresResolver(store),
 			},
 			"uniqueStyles": &graphql.Field{
 				Type:    graphql.NewList(UniqueNameType),
-				Resolve: UniqueStylesResolver(db),
+				Args:    labelArgs(),
+				Resolve: UniqueStylesResolver(store),
+			},
+			"labels": &graphql.Field{
+				Type: graphql.NewList(LabelType),
+				Args: graphql.FieldConfigArgument{
+					"parent": &graphql.ArgumentConfig{
+						Type: graphql.Int,
+					},
+				},
+				Resolve: LabelsResolver(store),
+			},
+			"syncStatus": &graphql.Field{
+				Type:    SyncStatusType,
+				Resolve: SyncStatusResolver(syncStatus),
+			},
+			"lastSyncedAt": &graphql.Field{
+				Type: graphql.DateTime,
+				Args: graphql.FieldConfigArgument{
+					"label": &graphql.ArgumentConfig{
+						Type: graphql.Int,
+					},
+				},
+				Resolve: LastSyncedAtResolver(store),
 			},
 		},
 	})
 }
+
+func labelArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"label": &graphql.ArgumentConfig{
+			Type: graphql.Int,
+		},
+		"includeSublabels": &graphql.ArgumentConfig{
+			Type: graphql.Boolean,
+		},
+	}
+}
+
+func releaseFilterArgs() graphql.FieldConfigArgument {
+	args := labelArgs()
+	for _, name := range []string{"artist", "style", "genre", "format", "track", "country", "catno"} {
+		args[name] = &graphql.ArgumentConfig{
+			Type: graphql.String,
+		}
+	}
+	for _, name := range []string{"year", "masterId"} {
+		args[name] = &graphql.ArgumentConfig{
+			Type: graphql.Int,
+		}
+	}
+	args["credit"] = &graphql.ArgumentConfig{
+		Type: CreditFilterType,
+	}
+	args["company"] = &graphql.ArgumentConfig{
+		Type: CompanyFilterType,
+	}
+	return args
+}
+
+// countArgs adds groupByMaster, which counts the versions of a master once.
+func countArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args["groupByMaster"] = &graphql.ArgumentConfig{
+		Type:         graphql.Boolean,
+		DefaultValue: false,
+	}
+	return args
+}
+
+func orderArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args["orderBy"] = &graphql.ArgumentConfig{
+		Type:         ReleaseOrderType,
+		DefaultValue: models.ReleaseOrderId,
+	}
+	return args
+}
+
+// priceStatsArgs adds what prices are grouped by and the currency of the prices aggregated.
+func priceStatsArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args["by"] = &graphql.ArgumentConfig{
+		Type: graphql.NewNonNull(PriceGroupType),
+	}
+	args["currency"] = &graphql.ArgumentConfig{
+		Type:         graphql.String,
+		DefaultValue: "USD",
+	}
+	return args
+}
+
+// periodArgs adds the required from and until of a period.
+func periodArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	for _, name := range []string{"from", "until"} {
+		args[name] = &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.DateTime),
+		}
+	}
+	return args
+}
+
+func limitArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args["limit"] = &graphql.ArgumentConfig{
+		Type:         graphql.Int,
+		DefaultValue: defaultPageSize,
+	}
+	return args
+}
+
+func pageArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args = limitArgs(args)
+	args["offset"] = &graphql.ArgumentConfig{
+		Type:         graphql.Int,
+		DefaultValue: 0,
+	}
+	return args
+}
@@ -1,43 +1,239 @@
 package graphQL
 
 import (
-	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
 	"github.com/LissaGreense/discogs_record_label/backend/storage"
 	"github.com/graphql-go/graphql"
+	"strings"
+	"time"
 )
 
-func ReleaseCountsResolver(db *sql.DB) graphql.FieldResolveFn {
+// SyncStatusProvider reports the progress of the background sync.
+type SyncStatusProvider interface {
+	Status() models.SyncStatus
+}
+
+// Page sizes of list queries
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+func ReleaseCountsResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		groupByMaster, _ := params.Args["groupByMaster"].(bool)
+		return store.FetchReleaseCounts(releaseFilterArg(params), groupByMaster)
+	}
+}
+
+func ReleaseResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		releaseID, _ := params.Args["id"].(int)
+		return store.FetchRelease(int32(releaseID))
+	}
+}
+
+// ReleaseByIdentifierResolver finds the releases with an identifier, such as a scanned barcode,
+// matching the given value regardless of whitespace and dashes.
+func ReleaseByIdentifierResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		value, _ := params.Args["value"].(string)
+		identifierType, _ := params.Args["type"].(string)
+		if strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("value must not be empty")
+		}
+		return store.FetchReleasesByIdentifier(value, identifierType)
+	}
+}
+
+func ReleasesResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		limit, offset, err := pageArg(params)
+		if err != nil {
+			return nil, err
+		}
+		order, _ := params.Args["orderBy"].(models.ReleaseOrder)
+		return store.FetchReleases(releaseFilterArg(params), order, limit, offset)
+	}
+}
+
+// TrackSearchResolver finds the releases with a track whose title contains the given one.
+func TrackSearchResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		limit, offset, err := pageArg(params)
+		if err != nil {
+			return nil, err
+		}
+		title, _ := params.Args["title"].(string)
+		return store.FetchReleases(models.ReleaseFilter{Track: title}, models.ReleaseOrderId, limit, offset)
+	}
+}
+
+// CommunityHistoryResolver returns the community statistics snapshots of a release, optionally
+// limited to a period.
+func CommunityHistoryResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		releaseID, _ := params.Args["release"].(int)
+		return store.FetchCommunityHistory(int32(releaseID), optionalTimeArg(params, "from"), optionalTimeArg(params, "until"))
+	}
+}
+
+// PriceHistoryResolver returns the marketplace price snapshots of a release, optionally limited to
+// a currency and a period.
+func PriceHistoryResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		releaseID, _ := params.Args["release"].(int)
+		currency, _ := params.Args["currency"].(string)
+		return store.FetchPriceHistory(int32(releaseID), strings.ToUpper(currency),
+			optionalTimeArg(params, "from"), optionalTimeArg(params, "until"))
+	}
+}
+
+func PriceStatsResolver(store storage.Store) graphql.FieldResolveFn {
 	return func(params graphql.ResolveParams) (interface{}, error) {
-		var artist, style, genre string
+		facet, _ := params.Args["by"].(storage.Facet)
+		currency, _ := params.Args["currency"].(string)
+		if strings.TrimSpace(currency) == "" {
+			return nil, fmt.Errorf("currency must not be empty")
+		}
+		return store.FetchPriceStats(facet, strings.ToUpper(currency), labelFilterArg(params))
+	}
+}
 
-		if artistArg, ok := params.Args["artist"].(string); ok {
-			artist = artistArg
+func MostWantedResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		limit, err := limitArg(params)
+		if err != nil {
+			return nil, err
 		}
-		if styleArg, ok := params.Args["style"].(string); ok {
-			style = styleArg
+		return store.FetchMostWanted(labelFilterArg(params), limit)
+	}
+}
+
+// TrendingResolver ranks releases by how much their want count grew between from and until.
+func TrendingResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		limit, err := limitArg(params)
+		if err != nil {
+			return nil, err
 		}
-		if genreArg, ok := params.Args["genre"].(string); ok {
-			genre = genreArg
+		from, _ := params.Args["from"].(time.Time)
+		until, _ := params.Args["until"].(time.Time)
+		if !from.Before(until) {
+			return nil, fmt.Errorf("from must be before until")
 		}
+		return store.FetchTrending(labelFilterArg(params), from, until, limit)
+	}
+}
+
+func MasterResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		masterID, _ := params.Args["id"].(int)
+		return store.FetchMaster(masterID)
+	}
+}
+
+func ArtistResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		artistID, _ := params.Args["id"].(int)
+		return store.FetchArtist(artistID)
+	}
+}
+
+func UniqueArtistsResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		return store.FetchUniqueArtists(labelFilterArg(params))
+	}
+}
+
+func UniqueGenresResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		return store.FetchUniqueNames(storage.FacetGenre, labelFilterArg(params))
+	}
+}
 
-		return storage.FetchReleaseCounts(db, artist, style, genre)
+func UniqueStylesResolver(store storage.Store) graphql.FieldResolveFn {
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		return store.FetchUniqueNames(storage.FacetStyle, labelFilterArg(params))
 	}
 }
 
-func UniqueArtistsResolver(db *sql.DB) graphql.FieldResolveFn {
+func SyncStatusResolver(syncStatus SyncStatusProvider) graphql.FieldResolveFn {
 	return func(params graphql.ResolveParams) (interface{}, error) {
-		return storage.FetchUniqueNames(db, storage.ArtistsTableName)
+		return syncStatus.Status(), nil
 	}
 }
 
-func UniqueGenresResolver(db *sql.DB) graphql.FieldResolveFn {
+func LastSyncedAtResolver(store storage.Store) graphql.FieldResolveFn {
 	return func(params graphql.ResolveParams) (interface{}, error) {
-		return storage.FetchUniqueNames(db, storage.GenresTableName)
+		return store.FetchLastSyncedAt(labelArg(params))
 	}
 }
 
-func UniqueStylesResolver(db *sql.DB) graphql.FieldResolveFn {
+func LabelsResolver(store storage.Store) graphql.FieldResolveFn {
 	return func(params graphql.ResolveParams) (interface{}, error) {
-		return storage.FetchUniqueNames(db, storage.StylesTableName)
+		parentID, _ := params.Args["parent"].(int)
+		return store.FetchLabels(parentID)
+	}
+}
+
+func labelArg(params graphql.ResolveParams) int {
+	labelID, _ := params.Args["label"].(int)
+	return labelID
+}
+
+func labelFilterArg(params graphql.ResolveParams) models.LabelFilter {
+	includeSublabels, _ := params.Args["includeSublabels"].(bool)
+	return models.LabelFilter{LabelId: labelArg(params), IncludeSublabels: includeSublabels}
+}
+
+func releaseFilterArg(params graphql.ResolveParams) models.ReleaseFilter {
+	filter := models.ReleaseFilter{LabelFilter: labelFilterArg(params)}
+	filter.Artist, _ = params.Args["artist"].(string)
+	filter.Style, _ = params.Args["style"].(string)
+	filter.Genre, _ = params.Args["genre"].(string)
+	filter.Format, _ = params.Args["format"].(string)
+	filter.Track, _ = params.Args["track"].(string)
+	filter.Country, _ = params.Args["country"].(string)
+	filter.Catno, _ = params.Args["catno"].(string)
+	filter.Year, _ = params.Args["year"].(int)
+	filter.MasterId, _ = params.Args["masterId"].(int)
+	if credit, ok := params.Args["credit"].(map[string]interface{}); ok {
+		filter.Credit.Role, _ = credit["role"].(string)
+		filter.Credit.Name, _ = credit["name"].(string)
+	}
+	if company, ok := params.Args["company"].(map[string]interface{}); ok {
+		filter.Company.Role, _ = company["role"].(string)
+		filter.Company.Name, _ = company["name"].(string)
+	}
+	return filter
+}
+
+// optionalTimeArg returns the DateTime argument, or nil when it was not given.
+func optionalTimeArg(params graphql.ResolveParams, name string) *time.Time {
+	if value, ok := params.Args[name].(time.Time); ok {
+		return &value
+	}
+	return nil
+}
+
+func limitArg(params graphql.ResolveParams) (int, error) {
+	limit, _ := params.Args["limit"].(int)
+	if limit < 1 || limit > maxPageSize {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+	}
+	return limit, nil
+}
+
+func pageArg(params graphql.ResolveParams) (int, int, error) {
+	limit, err := limitArg(params)
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, _ := params.Args["offset"].(int)
+	if offset < 0 {
+		return 0, 0, fmt.Errorf("offset must not be negative")
 	}
+	return limit, offset, nil
 }
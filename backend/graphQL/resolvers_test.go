@@ -2,8 +2,11 @@ package graphQL
 
 import (
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"github.com/LissaGreense/discogs_record_label/backend/storage"
 	"github.com/graphql-go/graphql"
 	"github.com/stretchr/testify/assert"
 )
@@ -18,10 +21,16 @@ func TestReleaseCountsResolver(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"releaseCount", "style", "genre", "artist"}).
-		AddRow(5, "Rock", "Pop", "ArtistA"))
+	mock.ExpectQuery("WITH filtered").WillReturnRows(sqlmock.NewRows([]string{"facet", "artist_id", "name", "release_count"}).
+		AddRow("artist", 11, "ArtistA", 5).
+		AddRow("genre", 0, "Pop", 5).
+		AddRow("style", 0, "Rock", 5).
+		AddRow("total", 0, "", 5))
+	mock.ExpectQuery("SELECT term").WillReturnRows(sqlmock.NewRows([]string{"term", "count"}))
+	mock.ExpectQuery("SELECT c.name").WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+	mock.ExpectQuery("SELECT co.name").WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
 
-	query := NewQueryType(db)
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
 	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
 	assert.NoError(t, err)
 
@@ -34,7 +43,7 @@ func TestReleaseCountsResolver(t *testing.T) {
 	result := executeQuery(queryString, schema)
 
 	assert.Nil(t, result.Errors)
-	assert.Equal(t, 1, result.Data.(map[string]interface{})["releaseCounts"].(map[string]interface{})["releaseCount"])
+	assert.Equal(t, 5, result.Data.(map[string]interface{})["releaseCounts"].(map[string]interface{})["releaseCount"])
 }
 
 func TestUniqueArtistsResolver(t *testing.T) {
@@ -42,14 +51,19 @@ func TestUniqueArtistsResolver(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("ArtistA").AddRow("ArtistB"))
+	mock.ExpectQuery("FROM discogs_artists da").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "resource_url", "real_name", "profile", "profile_fetched_at"}).
+		AddRow(11, "ArtistA", "", "", "", nil).
+		AddRow(12, "ArtistB", "", "", "", nil))
+	mock.ExpectQuery("FROM artist_name_variations").WillReturnRows(sqlmock.NewRows([]string{"artist_id", "name"}))
+	mock.ExpectQuery("FROM artist_relations").WillReturnRows(sqlmock.NewRows([]string{"artist_id", "kind", "related_id", "related_name", "active"}))
 
-	query := NewQueryType(db)
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
 	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
 	assert.NoError(t, err)
 
 	queryString := `{
 		uniqueArtists {
+			id
 			name
 		}
 	}`
@@ -59,6 +73,7 @@ func TestUniqueArtistsResolver(t *testing.T) {
 	assert.Nil(t, result.Errors)
 	artists := result.Data.(map[string]interface{})["uniqueArtists"].([]interface{})
 	assert.Len(t, artists, 2)
+	assert.Equal(t, 11, artists[0].(map[string]interface{})["id"])
 	assert.Equal(t, "ArtistA", artists[0].(map[string]interface{})["name"])
 	assert.Equal(t, "ArtistB", artists[1].(map[string]interface{})["name"])
 }
@@ -70,7 +85,7 @@ func TestUniqueGenresResolver(t *testing.T) {
 
 	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Pop").AddRow("Rock"))
 
-	query := NewQueryType(db)
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
 	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
 	assert.NoError(t, err)
 
@@ -96,7 +111,7 @@ func TestUniqueStylesResolver(t *testing.T) {
 
 	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Jazz").AddRow("Blues"))
 
-	query := NewQueryType(db)
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
 	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
 	assert.NoError(t, err)
 
@@ -114,3 +129,442 @@ func TestUniqueStylesResolver(t *testing.T) {
 	assert.Equal(t, "Jazz", styles[0].(map[string]interface{})["name"])
 	assert.Equal(t, "Blues", styles[1].(map[string]interface{})["name"])
 }
+
+type staticSyncStatus models.SyncStatus
+
+func (s staticSyncStatus) Status() models.SyncStatus {
+	return models.SyncStatus(s)
+}
+
+func TestSyncStatusResolver(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	startedAt := time.Date(2024, 10, 24, 12, 0, 0, 0, time.UTC)
+	syncStatus := staticSyncStatus{
+		Phase:           models.SyncPhaseFetching,
+		LabelId:         5,
+		StartedAt:       &startedAt,
+		ReleasesPending: 40,
+		ReleasesFetched: 60,
+	}
+
+	query := NewQueryType(storage.NewPostgresStore(db), syncStatus)
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	queryString := `{
+		syncStatus {
+			phase
+			labelId
+			startedAt
+			finishedAt
+			releasesPending
+			releasesFetched
+		}
+	}`
+
+	result := executeQuery(queryString, schema)
+	assert.Nil(t, result.Errors)
+
+	status := result.Data.(map[string]interface{})["syncStatus"].(map[string]interface{})
+	assert.Equal(t, "fetching", status["phase"])
+	assert.Equal(t, 5, status["labelId"])
+	assert.Equal(t, "2024-10-24T12:00:00Z", status["startedAt"])
+	assert.Nil(t, status["finishedAt"])
+	assert.Equal(t, 40, status["releasesPending"])
+	assert.Equal(t, 60, status["releasesFetched"])
+}
+
+func TestLastSyncedAtResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT finished_at FROM sync_runs").WillReturnRows(sqlmock.NewRows([]string{"finished_at"}).
+		AddRow(time.Date(2024, 10, 24, 13, 0, 0, 0, time.UTC)))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ lastSyncedAt }`, schema)
+	assert.Nil(t, result.Errors)
+	assert.Equal(t, "2024-10-24T13:00:00Z", result.Data.(map[string]interface{})["lastSyncedAt"])
+}
+
+func TestUniqueStylesResolverByLabel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT DISTINCT name FROM styles WHERE release_id IN").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Jazz"))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ uniqueStyles(label: 5) { name } }`, schema)
+	assert.Nil(t, result.Errors)
+
+	styles := result.Data.(map[string]interface{})["uniqueStyles"].([]interface{})
+	assert.Len(t, styles, 1)
+	assert.Equal(t, "Jazz", styles[0].(map[string]interface{})["name"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLabelsResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id, name, parent_id FROM labels").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).
+			AddRow(5, "Tone Addiction", nil).
+			AddRow(6, "Sister Label", 5))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ labels { id name parentId } }`, schema)
+	assert.Nil(t, result.Errors)
+
+	labels := result.Data.(map[string]interface{})["labels"].([]interface{})
+	assert.Len(t, labels, 2)
+	assert.Equal(t, 6, labels[1].(map[string]interface{})["id"])
+	assert.Equal(t, "Sister Label", labels[1].(map[string]interface{})["name"])
+	assert.Nil(t, labels[0].(map[string]interface{})["parentId"])
+	assert.Equal(t, 5, labels[1].(map[string]interface{})["parentId"])
+}
+
+func TestReleaseCountsResolverWithSublabels(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("WITH RECURSIVE label_tree").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"facet", "artist_id", "name", "release_count"}).
+			AddRow("artist", 11, "ArtistA", 2).
+			AddRow("total", 0, "", 2))
+	mock.ExpectQuery("SELECT term").WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"term", "count"}))
+	mock.ExpectQuery("SELECT c.name").WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+	mock.ExpectQuery("SELECT co.name").WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ releaseCounts(label: 5, includeSublabels: true) { releaseCount } }`, schema)
+	assert.Nil(t, result.Errors)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReleaseResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM releases r WHERE r.id = \\$1").
+		WithArgs(int32(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "year", "released", "country", "data_quality", "notes", "uri", "master_id"}).
+			AddRow(7, "Blue Train", 1957, "1957-09-00", "US", "Correct", "", "https://www.discogs.com/release/7", 0))
+	mock.ExpectQuery("FROM release_artists ra").
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "id", "name", "anv", "join_phrase", "resource_url"}).
+			AddRow(7, 97545, "John Coltrane", "", "", "https://api.discogs.com/artists/97545"))
+	mock.ExpectQuery("SELECT release_id, name FROM styles").
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "name"}).AddRow(7, "Hard Bop"))
+	mock.ExpectQuery("SELECT release_id, name FROM genres").
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "name"}).AddRow(7, "Jazz"))
+	mock.ExpectQuery("FROM formats f").
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "id", "name", "qty", "text", "description"}).
+			AddRow(7, 1, "Vinyl", 1, "", "LP"))
+	mock.ExpectQuery("FROM tracks t").
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "id", "position", "type", "title", "duration", "duration_seconds", "name", "role"}).
+			AddRow(7, 1, "A", "track", "Blue Train", "10:43", 643, "", ""))
+	mock.ExpectQuery("FROM credits").
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "artist_id", "name", "role", "tracks"}))
+	mock.ExpectQuery("FROM release_companies").
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "kind", "company_id", "name", "role", "catno"}).
+			AddRow(7, "label", 281, "Blue Note", "", "BLP 1577"))
+	mock.ExpectQuery("FROM identifiers").
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "type", "value", "description"}))
+	mock.ExpectQuery("FROM community_snapshots").
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "captured_at", "have", "want", "rating_average", "rating_count"}).
+			AddRow(7, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), 5200, 1800, 4.8, 950))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ release(id: 7) { id title year country artists { id name } genres formats { name descriptions } labels { name catno } community { want ratingAverage } } }`, schema)
+	assert.Nil(t, result.Errors)
+
+	release := result.Data.(map[string]interface{})["release"].(map[string]interface{})
+	assert.Equal(t, "Blue Train", release["title"])
+	assert.Equal(t, 1957, release["year"])
+	assert.Equal(t, "US", release["country"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": 97545, "name": "John Coltrane"}}, release["artists"])
+	assert.Equal(t, []interface{}{"Jazz"}, release["genres"])
+	formats := release["formats"].([]interface{})
+	assert.Len(t, formats, 1)
+	assert.Equal(t, "Vinyl", formats[0].(map[string]interface{})["name"])
+	assert.Equal(t, []interface{}{"LP"}, formats[0].(map[string]interface{})["descriptions"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "Blue Note", "catno": "BLP 1577"}}, release["labels"])
+	assert.Equal(t, map[string]interface{}{"want": 1800, "ratingAverage": 4.8}, release["community"])
+}
+
+func TestReleaseResolverNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM releases r WHERE r.id = \\$1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "year", "released", "country", "data_quality", "notes", "uri", "master_id"}))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ release(id: 8) { title } }`, schema)
+	assert.Nil(t, result.Errors)
+	assert.Nil(t, result.Data.(map[string]interface{})["release"])
+}
+
+func TestReleasesResolverRejectsLargePages(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ releases(limit: 100000) { id } }`, schema)
+	assert.NotEmpty(t, result.Errors)
+}
+
+func TestTrackSearchResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("r.id IN \\(SELECT release_id FROM tracks WHERE title ILIKE \\$1\\)").
+		WithArgs("%Blue Train%", 50, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "year", "released", "country", "data_quality", "notes", "uri", "master_id"}))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ trackSearch(title: "Blue Train") { id tracks { title } } }`, schema)
+	assert.Nil(t, result.Errors)
+	assert.Empty(t, result.Data.(map[string]interface{})["trackSearch"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReleaseCountsResolverByCredit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("AND s.name ILIKE \\$1 AND r.id IN \\(SELECT release_id FROM credits c WHERE 1=1 AND c.role ILIKE \\$2\\)").
+		WithArgs("%Techno%", "%Mastered By%", "%Techno%").
+		WillReturnRows(sqlmock.NewRows([]string{"facet", "artist_id", "name", "release_count"}).
+			AddRow("style", 0, "Techno", 1).
+			AddRow("total", 0, "", 1))
+	mock.ExpectQuery("SELECT term").
+		WithArgs("%Techno%", "%Mastered By%").
+		WillReturnRows(sqlmock.NewRows([]string{"term", "count"}))
+	mock.ExpectQuery("SELECT c.name, c.role, COUNT\\(DISTINCT r.id\\) AS release_count FROM credits c JOIN releases r ON r.id = c.release_id WHERE c.release_id IN \\(.*\\) AND c.role ILIKE \\$3 GROUP BY c.name, c.role").
+		WithArgs("%Techno%", "%Mastered By%", "%Mastered By%").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}).AddRow("Engineer", "Mastered By", 12))
+	mock.ExpectQuery("SELECT co.name").
+		WithArgs("%Techno%", "%Mastered By%").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{
+		releaseCounts(style: "Techno", credit: {role: "Mastered By"}) {
+			creditCounts { name role count }
+		}
+	}`, schema)
+	assert.Nil(t, result.Errors)
+
+	creditCounts := result.Data.(map[string]interface{})["releaseCounts"].(map[string]interface{})["creditCounts"].([]interface{})
+	assert.Len(t, creditCounts, 1)
+	assert.Equal(t, "Engineer", creditCounts[0].(map[string]interface{})["name"])
+	assert.Equal(t, 12, creditCounts[0].(map[string]interface{})["count"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestArtistResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM discogs_artists da WHERE da.id IN \\(\\$1\\)").
+		WithArgs(97545).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "resource_url", "real_name", "profile", "profile_fetched_at"}).
+			AddRow(97545, "John Coltrane", "", "John William Coltrane", "American jazz saxophonist.", time.Date(2024, 10, 24, 13, 0, 0, 0, time.UTC)))
+	mock.ExpectQuery("FROM artist_name_variations").
+		WithArgs(97545).
+		WillReturnRows(sqlmock.NewRows([]string{"artist_id", "name"}).AddRow(97545, "Coltrane"))
+	mock.ExpectQuery("FROM artist_relations").
+		WithArgs(97545).
+		WillReturnRows(sqlmock.NewRows([]string{"artist_id", "kind", "related_id", "related_name", "active"}).
+			AddRow(97545, "group", 253006, "The John Coltrane Quartet", true))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ artist(id: 97545) { realName nameVariations groups { id name active } members { name } } }`, schema)
+	assert.Nil(t, result.Errors)
+
+	artist := result.Data.(map[string]interface{})["artist"].(map[string]interface{})
+	assert.Equal(t, "John William Coltrane", artist["realName"])
+	assert.Equal(t, []interface{}{"Coltrane"}, artist["nameVariations"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": 253006, "name": "The John Coltrane Quartet", "active": true}}, artist["groups"])
+	assert.Empty(t, artist["members"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMasterResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM releases r LEFT JOIN masters m").
+		WithArgs(4321).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "year", "main_release", "uri", "fetched_at", "versions"}).
+			AddRow(4321, "Blue Train", 1957, 7, "https://www.discogs.com/master/4321", time.Date(2024, 10, 24, 13, 0, 0, 0, time.UTC), 3))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ master(id: 4321) { title year mainRelease versionCount } }`, schema)
+	assert.Nil(t, result.Errors)
+
+	expected := map[string]interface{}{"title": "Blue Train", "year": 1957, "mainRelease": 7, "versionCount": 3}
+	assert.Equal(t, expected, result.Data.(map[string]interface{})["master"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReleaseByIdentifierResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("WHERE i.normalized_value = \\$1\\) ORDER BY r.id").
+		WithArgs("074644056618").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "year", "released", "country", "data_quality", "notes", "uri", "master_id"}))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ releaseByIdentifier(value: "0 7464-40566 1 8") { id } }`, schema)
+	assert.Nil(t, result.Errors)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	result = executeQuery(`{ releaseByIdentifier(value: " ") { id } }`, schema)
+	assert.NotEmpty(t, result.Errors)
+}
+
+func TestTrendingResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM community_snapshots period_start").
+		WithArgs(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), 3).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "want_from", "want_until", "growth"}))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ trending(from: "2024-01-01T00:00:00Z", until: "2024-07-01T00:00:00Z", limit: 3) { growth release { id } } }`, schema)
+	assert.Nil(t, result.Errors)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	result = executeQuery(`{ trending(from: "2024-07-01T00:00:00Z", until: "2024-01-01T00:00:00Z") { growth } }`, schema)
+	assert.NotEmpty(t, result.Errors)
+}
+
+func TestPriceHistoryResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM price_snapshots ps WHERE ps.release_id = \\$1 AND ps.currency = \\$2 ORDER BY ps.captured_at").
+		WithArgs(int32(7), "EUR").
+		WillReturnRows(sqlmock.NewRows([]string{"captured_at", "currency", "lowest_price", "num_for_sale", "blocked_from_sale"}).
+			AddRow(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), "EUR", 24.5, 12, false).
+			AddRow(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), "EUR", nil, 0, false))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ priceHistory(release: 7, currency: "eur") { lowestPrice numForSale } }`, schema)
+	assert.Nil(t, result.Errors)
+
+	expected := []interface{}{
+		map[string]interface{}{"lowestPrice": 24.5, "numForSale": 12},
+		map[string]interface{}{"lowestPrice": nil, "numForSale": 0},
+	}
+	assert.Equal(t, expected, result.Data.(map[string]interface{})["priceHistory"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPriceStatsResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM price_snapshots ps JOIN genres a").
+		WithArgs("USD").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "lowest_price", "num_for_sale"}).
+			AddRow("Jazz", 15.0, 2))
+
+	query := NewQueryType(storage.NewPostgresStore(db), staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ priceStats(by: GENRE) { name releaseCount medianPrice } }`, schema)
+	assert.Nil(t, result.Errors)
+
+	expected := []interface{}{map[string]interface{}{"name": "Jazz", "releaseCount": 1, "medianPrice": 15.0}}
+	assert.Equal(t, expected, result.Data.(map[string]interface{})["priceStats"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTrackSearchResolverOnMemoryStore(t *testing.T) {
+	store := storage.NewMemoryStore()
+	assert.NoError(t, store.StoreRelease(&models.Release{
+		Id: 1, Title: "Blue Train",
+		Tracks: []models.Track{{Position: "A1", Title: "Blue Train"}, {Position: "A2", Title: "Moment's Notice"}},
+	}))
+	assert.NoError(t, store.StoreRelease(&models.Release{Id: 2, Title: "Giant Steps", Tracks: []models.Track{{Position: "A1", Title: "Giant Steps"}}}))
+
+	query := NewQueryType(store, staticSyncStatus{})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	assert.NoError(t, err)
+
+	result := executeQuery(`{ trackSearch(title: "blue train") { id tracks { title } } }`, schema)
+	assert.Nil(t, result.Errors)
+	releases := result.Data.(map[string]interface{})["trackSearch"].([]interface{})
+	assert.Len(t, releases, 1)
+	assert.Equal(t, 1, releases[0].(map[string]interface{})["id"])
+	assert.Len(t, releases[0].(map[string]interface{})["tracks"], 2)
+}
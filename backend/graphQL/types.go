@@ -1,6 +1,10 @@
 package graphQL
 
-import "github.com/graphql-go/graphql"
+import (
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"github.com/LissaGreense/discogs_record_label/backend/storage"
+	"github.com/graphql-go/graphql"
+)
 
 var UniqueNameType = graphql.NewObject(graphql.ObjectConfig{
 	Name: "UniqueName",
@@ -11,6 +15,434 @@ var UniqueNameType = graphql.NewObject(graphql.ObjectConfig{
 	},
 })
 
+var LabelType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Label",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"parentId": &graphql.Field{
+			Type: graphql.Int,
+		},
+	},
+})
+
+var FormatType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Format",
+	Fields: graphql.Fields{
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"qty": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"text": &graphql.Field{
+			Type: graphql.String,
+		},
+		"descriptions": &graphql.Field{
+			Type: graphql.NewList(graphql.String),
+		},
+	},
+})
+
+var TrackType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Track",
+	Fields: graphql.Fields{
+		"position": &graphql.Field{
+			Type: graphql.String,
+		},
+		"type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"title": &graphql.Field{
+			Type: graphql.String,
+		},
+		"duration": &graphql.Field{
+			Type: graphql.String,
+		},
+		"durationSeconds": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"artists": &graphql.Field{
+			Type: graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
+				Name: "TrackArtist",
+				Fields: graphql.Fields{
+					"name": &graphql.Field{
+						Type: graphql.String,
+					},
+					"role": &graphql.Field{
+						Type: graphql.String,
+					},
+				},
+			})),
+		},
+	},
+})
+
+var CreditType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Credit",
+	Fields: graphql.Fields{
+		"artistId": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"role": &graphql.Field{
+			Type: graphql.String,
+		},
+		"tracks": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+var CreditFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreditFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"role": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+		},
+		"name": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+		},
+	},
+})
+
+var ArtistRefType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ArtistRef",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"active": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+	},
+})
+
+var ArtistType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Artist",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"resourceUrl": &graphql.Field{
+			Type: graphql.String,
+		},
+		"realName": &graphql.Field{
+			Type: graphql.String,
+		},
+		"profile": &graphql.Field{
+			Type: graphql.String,
+		},
+		"nameVariations": &graphql.Field{
+			Type: graphql.NewList(graphql.String),
+		},
+		"aliases": &graphql.Field{
+			Type: graphql.NewList(ArtistRefType),
+		},
+		"groups": &graphql.Field{
+			Type: graphql.NewList(ArtistRefType),
+		},
+		"members": &graphql.Field{
+			Type: graphql.NewList(ArtistRefType),
+		},
+		"profileFetchedAt": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+	},
+})
+
+var ReleaseLabelType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ReleaseLabel",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"catno": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+var CompanyType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Company",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"role": &graphql.Field{
+			Type: graphql.String,
+		},
+		"catno": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+var CompanyFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CompanyFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"role": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+		},
+		"name": &graphql.InputObjectFieldConfig{
+			Type: graphql.String,
+		},
+	},
+})
+
+var IdentifierType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Identifier",
+	Fields: graphql.Fields{
+		"type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"value": &graphql.Field{
+			Type: graphql.String,
+		},
+		"description": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+var CommunityStatsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CommunityStats",
+	Fields: graphql.Fields{
+		"have": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"want": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"ratingAverage": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"ratingCount": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"capturedAt": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+	},
+})
+
+var PriceSnapshotType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PriceSnapshot",
+	Fields: graphql.Fields{
+		"currency": &graphql.Field{
+			Type: graphql.String,
+		},
+		"lowestPrice": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"numForSale": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"blockedFromSale": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+		"capturedAt": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+	},
+})
+
+var PriceStatsType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PriceStats",
+	Fields: graphql.Fields{
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"releaseCount": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"pricedCount": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"numForSale": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"minPrice": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"medianPrice": &graphql.Field{
+			Type: graphql.Float,
+		},
+	},
+})
+
+// PriceGroupType selects the attribute prices are aggregated by.
+var PriceGroupType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "PriceGroup",
+	Values: graphql.EnumValueConfigMap{
+		"STYLE": &graphql.EnumValueConfig{
+			Value: storage.FacetStyle,
+		},
+		"GENRE": &graphql.EnumValueConfig{
+			Value: storage.FacetGenre,
+		},
+	},
+})
+
+var ReleaseOrderType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ReleaseOrder",
+	Values: graphql.EnumValueConfigMap{
+		"ID": &graphql.EnumValueConfig{
+			Value: models.ReleaseOrderId,
+		},
+		"CATNO": &graphql.EnumValueConfig{
+			Value: models.ReleaseOrderCatno,
+		},
+	},
+})
+
+var MasterType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Master",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"title": &graphql.Field{
+			Type: graphql.String,
+		},
+		"year": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"mainRelease": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"uri": &graphql.Field{
+			Type: graphql.String,
+		},
+		"versionCount": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"fetchedAt": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+	},
+})
+
+var ReleaseArtistType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ReleaseArtist",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"anv": &graphql.Field{
+			Type: graphql.String,
+		},
+		"join": &graphql.Field{
+			Type: graphql.String,
+		},
+		"resourceUrl": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+var ReleaseType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Release",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"title": &graphql.Field{
+			Type: graphql.String,
+		},
+		"year": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"released": &graphql.Field{
+			Type: graphql.String,
+		},
+		"country": &graphql.Field{
+			Type: graphql.String,
+		},
+		"dataQuality": &graphql.Field{
+			Type: graphql.String,
+		},
+		"notes": &graphql.Field{
+			Type: graphql.String,
+		},
+		"uri": &graphql.Field{
+			Type: graphql.String,
+		},
+		"masterId": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"artists": &graphql.Field{
+			Type: graphql.NewList(ReleaseArtistType),
+		},
+		"styles": &graphql.Field{
+			Type: graphql.NewList(graphql.String),
+		},
+		"genres": &graphql.Field{
+			Type: graphql.NewList(graphql.String),
+		},
+		"formats": &graphql.Field{
+			Type: graphql.NewList(FormatType),
+		},
+		"tracks": &graphql.Field{
+			Type: graphql.NewList(TrackType),
+		},
+		"credits": &graphql.Field{
+			Type: graphql.NewList(CreditType),
+		},
+		"labels": &graphql.Field{
+			Type: graphql.NewList(ReleaseLabelType),
+		},
+		"companies": &graphql.Field{
+			Type: graphql.NewList(CompanyType),
+		},
+		"identifiers": &graphql.Field{
+			Type: graphql.NewList(IdentifierType),
+		},
+		"community": &graphql.Field{
+			Type: CommunityStatsType,
+		},
+	},
+})
+
+var WantGrowthType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "WantGrowth",
+	Fields: graphql.Fields{
+		"release": &graphql.Field{
+			Type: ReleaseType,
+		},
+		"wantFrom": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"wantUntil": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"growth": &graphql.Field{
+			Type: graphql.Int,
+		},
+	},
+})
+
 var CountResultType = graphql.NewObject(graphql.ObjectConfig{
 	Name: "CountResult",
 	Fields: graphql.Fields{
@@ -46,6 +478,54 @@ var CountResultType = graphql.NewObject(graphql.ObjectConfig{
 		"artistCounts": &graphql.Field{
 			Type: graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
 				Name: "ArtistCount",
+				Fields: graphql.Fields{
+					"id": &graphql.Field{
+						Type: graphql.Int,
+					},
+					"name": &graphql.Field{
+						Type: graphql.String,
+					},
+					"count": &graphql.Field{
+						Type: graphql.Int,
+					},
+				},
+			})),
+		},
+		"creditCounts": &graphql.Field{
+			Type: graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
+				Name: "CreditCount",
+				Fields: graphql.Fields{
+					"name": &graphql.Field{
+						Type: graphql.String,
+					},
+					"role": &graphql.Field{
+						Type: graphql.String,
+					},
+					"count": &graphql.Field{
+						Type: graphql.Int,
+					},
+				},
+			})),
+		},
+		"companyCounts": &graphql.Field{
+			Type: graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
+				Name: "CompanyCount",
+				Fields: graphql.Fields{
+					"name": &graphql.Field{
+						Type: graphql.String,
+					},
+					"role": &graphql.Field{
+						Type: graphql.String,
+					},
+					"count": &graphql.Field{
+						Type: graphql.Int,
+					},
+				},
+			})),
+		},
+		"formatCounts": &graphql.Field{
+			Type: graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
+				Name: "FormatCount",
 				Fields: graphql.Fields{
 					"name": &graphql.Field{
 						Type: graphql.String,
@@ -58,3 +538,81 @@ var CountResultType = graphql.NewObject(graphql.ObjectConfig{
 		},
 	},
 })
+
+var SyncStatusType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "SyncStatus",
+	Fields: graphql.Fields{
+		"phase": &graphql.Field{
+			Type: graphql.String,
+		},
+		"labelId": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"runId": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"startedAt": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+		"finishedAt": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+		"pagesListed": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"releasesListed": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"releasesPending": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"releasesFetched": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"releasesFailed": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"communityPending": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"communityFetched": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"communityFailed": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"artistsPending": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"artistsEnriched": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"artistsFailed": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"mastersPending": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"mastersFetched": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"mastersFailed": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"pricesPending": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"pricesFetched": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"pricesFailed": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"lastError": &graphql.Field{
+			Type: graphql.String,
+		},
+		"nextRunAt": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+	},
+})
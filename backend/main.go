@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"github.com/LissaGreense/discogs_record_label/backend/api"
 	"github.com/LissaGreense/discogs_record_label/backend/graphQL"
 	"github.com/LissaGreense/discogs_record_label/backend/storage"
@@ -10,29 +12,60 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 )
 
 func main() {
-	db, err := storage.InitDatabase()
+	fullRefresh := flag.Bool("full-refresh", false, "re-fetch every release of the label instead of only missing ones")
+	demo := flag.Bool("demo", false, "serve seeded in-memory data without a database or syncing from Discogs")
+	flag.Parse()
+
+	if *demo {
+		store := storage.NewMemoryStore()
+		if err := seedDemoStore(store); err != nil {
+			log.Fatalf("Error seeding demo data: %v", err)
+		}
+		serve(store, api.NewSyncTracker())
+		return
+	}
+
+	store, err := storage.OpenStore()
 	if err != nil {
 		log.Fatalf("Error connecting to the database: %v", err)
 	}
-	defer db.Close()
+	defer store.Close()
+
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrateCommand(store, flag.Args()[1:]); err != nil {
+			log.Fatalf("Error migrating schema: %v", err)
+		}
+		return
+	}
 
-	if err := storage.CreateSchema(db); err != nil {
-		log.Fatalf("Error creating schema: %v", err)
+	if err := store.Migrate(); err != nil {
+		log.Fatalf("Error migrating schema: %v", err)
 	}
 
-	labelId := getLabelID(err)
+	labelIds := getLabelIDs()
 
-	if err := api.FetchAndStoreReleases(db, labelId); err != nil {
-		log.Fatalf("Error fetching and storing releases: %v", err)
+	syncConfig, err := api.LoadSyncConfig()
+	if err != nil {
+		log.Fatalf("Invalid sync configuration: %v", err)
 	}
+	syncConfig.FullRefresh = syncConfig.FullRefresh || *fullRefresh
+
+	syncTracker := api.NewSyncTracker()
 
-	log.Println("Finished fetching and storing releases.")
+	scheduler := api.NewScheduler(store, labelIds, syncConfig, syncTracker)
+	go scheduler.Run(context.Background())
+
+	serve(store, syncTracker)
+}
 
+// serve answers GraphQL queries on :8080 until the server fails.
+func serve(store storage.Store, syncTracker *api.SyncTracker) {
 	schemaConfig := graphql.SchemaConfig{
-		Query: graphQL.NewQueryType(db),
+		Query: graphQL.NewQueryType(store, syncTracker),
 	}
 
 	schema, err := graphql.NewSchema(schemaConfig)
@@ -74,16 +107,39 @@ func enableCors(next http.Handler) http.Handler {
 	})
 }
 
-func getLabelID(err error) int {
-	labelIdStr := os.Getenv("SELECTED_LABEL")
+// getLabelIDs reads the labels to sync from SELECTED_LABEL, a comma-separated list of label ids,
+// or from the file named by SELECTED_LABELS_FILE, which lists ids separated by commas or newlines.
+func getLabelIDs() []int {
+	labelIdsStr := os.Getenv("SELECTED_LABEL")
 
-	if labelIdStr == "" {
-		log.Fatal("SELECTED_LABEL environment variable is not set")
+	if labelsFile := os.Getenv("SELECTED_LABELS_FILE"); labelsFile != "" {
+		content, err := os.ReadFile(labelsFile)
+		if err != nil {
+			log.Fatalf("Failed to read SELECTED_LABELS_FILE: %v", err)
+		}
+		labelIdsStr = string(content)
 	}
 
-	labelId, err := strconv.Atoi(labelIdStr)
-	if err != nil {
-		log.Fatalf("SELECTED_LABEL must be an integer, got: %v", labelIdStr)
+	if labelIdsStr == "" {
+		log.Fatal("SELECTED_LABEL or SELECTED_LABELS_FILE environment variable is not set")
 	}
-	return labelId
+
+	var labelIds []int
+	for _, labelIdStr := range strings.FieldsFunc(labelIdsStr, isLabelSeparator) {
+		labelIdStr = strings.TrimSpace(labelIdStr)
+		if labelIdStr == "" {
+			continue
+		}
+
+		labelId, err := strconv.Atoi(labelIdStr)
+		if err != nil {
+			log.Fatalf("Label ids must be integers, got: %v", labelIdStr)
+		}
+		labelIds = append(labelIds, labelId)
+	}
+	return labelIds
+}
+
+func isLabelSeparator(r rune) bool {
+	return r == ',' || r == '\n'
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/storage"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = "usage: migrate status | up | down | to <version>"
+
+// runMigrateCommand runs the migrate command: status lists the migrations and whether they were
+// applied, up applies the missing ones, down reverts the latest and to moves to the given version.
+func runMigrateCommand(store storage.Store, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch {
+	case args[0] == "status" && len(args) == 1:
+		return printMigrationStatus(store)
+	case args[0] == "up" && len(args) == 1:
+		return store.Migrate()
+	case args[0] == "down" && len(args) == 1:
+		return store.MigrateDown()
+	case args[0] == "to" && len(args) == 2:
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("version must be an integer, got: %v", args[1])
+		}
+		return store.MigrateTo(version)
+	}
+	return errors.New(migrateUsage)
+}
+
+func printMigrationStatus(store storage.Store) error {
+	migrations, err := store.FetchMigrations()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, migration := range migrations {
+		appliedAt := "pending"
+		if migration.AppliedAt != nil {
+			appliedAt = migration.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, migration.Name, appliedAt)
+	}
+	return w.Flush()
+}
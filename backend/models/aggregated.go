@@ -10,8 +10,29 @@ type NameCount struct {
 }
 
 type CountResult struct {
-	ReleaseCount int         `json:"releaseCount"`
-	ArtistCounts []NameCount `json:"artistCounts"`
-	StyleCounts  []NameCount `json:"styleCounts"`
-	GenreCounts  []NameCount `json:"genreCounts"`
+	ReleaseCount  int            `json:"releaseCount"`
+	ArtistCounts  []ArtistCount  `json:"artistCounts"`
+	StyleCounts   []NameCount    `json:"styleCounts"`
+	GenreCounts   []NameCount    `json:"genreCounts"`
+	FormatCounts  []NameCount    `json:"formatCounts"`
+	CreditCounts  []CreditCount  `json:"creditCounts"`
+	CompanyCounts []CompanyCount `json:"companyCounts"`
+}
+
+type ArtistCount struct {
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type CreditCount struct {
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+	Count int    `json:"count"`
+}
+
+type CompanyCount struct {
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+	Count int    `json:"count"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// CommunityStats is a snapshot of what the Discogs community reported for a release when it was
+// synced: how many users have and want it and how they rated it.
+type CommunityStats struct {
+	Have          int       `json:"have"`
+	Want          int       `json:"want"`
+	RatingAverage float64   `json:"ratingAverage"`
+	RatingCount   int       `json:"ratingCount"`
+	CapturedAt    time.Time `json:"capturedAt"`
+}
+
+// WantGrowth is how much the want count of a release grew over a period.
+type WantGrowth struct {
+	Release   *Release `json:"release"`
+	WantFrom  int      `json:"wantFrom"`
+	WantUntil int      `json:"wantUntil"`
+	Growth    int      `json:"growth"`
+}
@@ -0,0 +1,53 @@
+package models
+
+type Label struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentId *int   `json:"parentId"`
+}
+
+// LabelFilter limits queries to the releases of one label, and optionally of all its sublabels.
+// A zero LabelId matches every label.
+type LabelFilter struct {
+	LabelId          int
+	IncludeSublabels bool
+}
+
+// ReleaseFilter narrows release queries. Empty names and a zero year or master id match everything.
+type ReleaseFilter struct {
+	LabelFilter
+	Artist   string
+	Style    string
+	Genre    string
+	Format   string
+	Track    string
+	Credit   CreditFilter
+	Company  CompanyFilter
+	Catno    string
+	Year     int
+	Country  string
+	MasterId int
+}
+
+// CompanyFilter matches companies whose role and name contain the given ones, such as the role
+// "Pressed By". Empty fields match every company.
+type CompanyFilter struct {
+	Role string
+	Name string
+}
+
+// ReleaseOrder is the order in which release lists are returned.
+type ReleaseOrder string
+
+// Release orders
+const (
+	ReleaseOrderId    ReleaseOrder = "id"
+	ReleaseOrderCatno ReleaseOrder = "catno"
+)
+
+// CreditFilter matches credits whose role and name contain the given ones, such as the role
+// "Mastered By". Empty fields match every credit.
+type CreditFilter struct {
+	Role string
+	Name string
+}
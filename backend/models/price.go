@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// PriceSnapshot is what the Discogs marketplace offered for a release when it was synced. The
+// lowest price is nil while no copy is for sale.
+type PriceSnapshot struct {
+	Currency        string    `json:"currency"`
+	LowestPrice     *float64  `json:"lowestPrice"`
+	NumForSale      int       `json:"numForSale"`
+	BlockedFromSale bool      `json:"blockedFromSale"`
+	CapturedAt      time.Time `json:"capturedAt"`
+}
+
+// PriceStats aggregates the latest marketplace prices of the releases of a style or genre in one
+// currency. Releases with no copy for sale are counted but have no price to aggregate.
+type PriceStats struct {
+	Name         string   `json:"name"`
+	ReleaseCount int      `json:"releaseCount"`
+	PricedCount  int      `json:"pricedCount"`
+	NumForSale   int      `json:"numForSale"`
+	MinPrice     *float64 `json:"minPrice"`
+	MedianPrice  *float64 `json:"medianPrice"`
+}
@@ -1,8 +1,138 @@
 package models
 
+import "time"
+
 type Release struct {
-	Id      int32    `json:"id"`
-	Artists []string `json:"artists"`
-	Styles  []string `json:"styles"`
-	Genres  []string `json:"genres"`
+	Id          int32           `json:"id"`
+	Title       string          `json:"title"`
+	Year        int             `json:"year"`
+	Released    string          `json:"released"`
+	Country     string          `json:"country"`
+	DataQuality string          `json:"dataQuality"`
+	Notes       string          `json:"notes"`
+	URI         string          `json:"uri"`
+	MasterId    int             `json:"masterId"`
+	Labels      []ReleaseLabel  `json:"labels"`
+	Companies   []Company       `json:"companies"`
+	Identifiers []Identifier    `json:"identifiers"`
+	Community   *CommunityStats `json:"community"`
+	Artists     []ReleaseArtist `json:"artists"`
+	Styles      []string        `json:"styles"`
+	Genres      []string        `json:"genres"`
+	Formats     []Format        `json:"formats"`
+	Tracks      []Track         `json:"tracks"`
+	Credits     []Credit        `json:"credits"`
+}
+
+// Master groups the versions of one work, such as its original pressing, represses and
+// reissues on other formats. The details stay empty until the master has been fetched;
+// VersionCount counts the stored releases of the master.
+type Master struct {
+	Id           int        `json:"id"`
+	Title        string     `json:"title"`
+	Year         int        `json:"year"`
+	MainRelease  int        `json:"mainRelease"`
+	URI          string     `json:"uri"`
+	VersionCount int        `json:"versionCount"`
+	FetchedAt    *time.Time `json:"fetchedAt"`
+}
+
+// ReleaseLabel is a label a release came out on, with the release's catalog number on it.
+type ReleaseLabel struct {
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Catno string `json:"catno"`
+}
+
+// Company is a company that worked on a release, such as its pressing plant or distributor. Role
+// is the Discogs entity type, e.g. "Pressed By", and Catno the company's own number, if any.
+type Company struct {
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+	Catno string `json:"catno"`
+}
+
+// Identifier tells copies of a release apart, such as its barcode or the matrix etched in the
+// runout groove. Type is the Discogs identifier type, e.g. "Barcode" or "Matrix / Runout", and
+// Description says where it was found, e.g. "Side A".
+type Identifier struct {
+	Type        string `json:"type"`
+	Value       string `json:"value"`
+	Description string `json:"description"`
+}
+
+// Format is one physical or digital medium of a release, such as a 12" vinyl or a CD.
+type Format struct {
+	Name         string   `json:"name"`
+	Qty          int      `json:"qty"`
+	Text         string   `json:"text"`
+	Descriptions []string `json:"descriptions"`
+}
+
+// Track is one entry of a release's tracklist. Headings and index tracks are kept with their type
+// so the tracklist reads as on Discogs.
+type Track struct {
+	Position        string        `json:"position"`
+	Type            string        `json:"type"`
+	Title           string        `json:"title"`
+	Duration        string        `json:"duration"`
+	DurationSeconds int           `json:"durationSeconds"`
+	Artists         []TrackArtist `json:"artists"`
+}
+
+// TrackArtist credits an artist on a single track. Extra artists, such as remixers, carry their role.
+type TrackArtist struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// Credit names someone who worked on a release, such as its producer or mastering engineer.
+// Credits limited to some tracks list them in Tracks, e.g. "A1 to A3".
+type Credit struct {
+	ArtistId int    `json:"artistId"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Tracks   string `json:"tracks"`
+}
+
+// Artist is a Discogs artist entity. Name is the canonical Discogs name, which carries a numeric
+// suffix such as "(2)" when several artists share it.
+// The profile fields stay empty until the artist's profile has been fetched.
+type Artist struct {
+	Id               int         `json:"id"`
+	Name             string      `json:"name"`
+	ResourceURL      string      `json:"resourceUrl"`
+	RealName         string      `json:"realName"`
+	Profile          string      `json:"profile"`
+	NameVariations   []string    `json:"nameVariations"`
+	Aliases          []ArtistRef `json:"aliases"`
+	Groups           []ArtistRef `json:"groups"`
+	Members          []ArtistRef `json:"members"`
+	ProfileFetchedAt *time.Time  `json:"profileFetchedAt"`
+}
+
+// Kinds of relations between artists
+const (
+	ArtistRelationAlias  = "alias"
+	ArtistRelationGroup  = "group"
+	ArtistRelationMember = "member"
+)
+
+// ArtistRef points to a related artist, which need not be stored itself. Active tells whether a
+// member is still in the group, when Discogs says so.
+type ArtistRef struct {
+	Id     int    `json:"id"`
+	Name   string `json:"name"`
+	Active *bool  `json:"active"`
+}
+
+// ReleaseArtist credits an artist on a release. Anv is the name variation printed on the release
+// and Join the phrase linking the artist to the next one, such as "&" or "feat.".
+type ReleaseArtist struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Anv         string `json:"anv"`
+	Join        string `json:"join"`
+	ResourceURL string `json:"resourceUrl"`
 }
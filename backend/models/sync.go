@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// Sync run statuses
+const (
+	SyncRunRunning     = "running"
+	SyncRunSucceeded   = "succeeded"
+	SyncRunFailed      = "failed"
+	SyncRunInterrupted = "interrupted"
+)
+
+type SyncRun struct {
+	Id              int        `json:"id"`
+	LabelId         int        `json:"labelId"`
+	StartedAt       time.Time  `json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt"`
+	Status          string     `json:"status"`
+	ReleasesListed  int        `json:"releasesListed"`
+	ReleasesFetched int        `json:"releasesFetched"`
+	ReleasesFailed  int        `json:"releasesFailed"`
+	Error           string     `json:"error"`
+}
+
+// SyncState is the checkpoint of an unfinished label sync.
+type SyncState struct {
+	LabelId         int    `json:"labelId"`
+	RunId           int    `json:"runId"`
+	PagesListed     int    `json:"pagesListed"`
+	NextPageURL     string `json:"nextPageUrl"`
+	ListingComplete bool   `json:"listingComplete"`
+}
+
+// Sync phases reported while a sync runs in the background
+const (
+	SyncPhaseIdle      = "idle"
+	SyncPhaseListing   = "listing"
+	SyncPhaseFetching  = "fetching"
+	SyncPhaseCommunity = "community"
+	SyncPhaseMasters   = "masters"
+	SyncPhaseEnriching = "enriching"
+	SyncPhasePrices    = "prices"
+	SyncPhaseSucceeded = "succeeded"
+	SyncPhaseFailed    = "failed"
+)
+
+// SyncStatus is the live progress of the current or last sync.
+type SyncStatus struct {
+	Phase            string     `json:"phase"`
+	LabelId          int        `json:"labelId"`
+	RunId            int        `json:"runId"`
+	StartedAt        *time.Time `json:"startedAt"`
+	FinishedAt       *time.Time `json:"finishedAt"`
+	PagesListed      int        `json:"pagesListed"`
+	ReleasesListed   int        `json:"releasesListed"`
+	ReleasesPending  int        `json:"releasesPending"`
+	ReleasesFetched  int        `json:"releasesFetched"`
+	ReleasesFailed   int        `json:"releasesFailed"`
+	CommunityPending int        `json:"communityPending"`
+	CommunityFetched int        `json:"communityFetched"`
+	CommunityFailed  int        `json:"communityFailed"`
+	ArtistsPending   int        `json:"artistsPending"`
+	ArtistsEnriched  int        `json:"artistsEnriched"`
+	ArtistsFailed    int        `json:"artistsFailed"`
+	MastersPending   int        `json:"mastersPending"`
+	MastersFetched   int        `json:"mastersFetched"`
+	MastersFailed    int        `json:"mastersFailed"`
+	PricesPending    int        `json:"pricesPending"`
+	PricesFetched    int        `json:"pricesFetched"`
+	PricesFailed     int        `json:"pricesFailed"`
+	LastError        string     `json:"lastError"`
+	NextRunAt        *time.Time `json:"nextRunAt"`
+}
+
+// Migration is a versioned schema change and, once it ran, when it was applied.
+type Migration struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt"`
+}
@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"time"
+)
+
+// Artist table names
+const (
+	discogsArtistsTableName       = "discogs_artists"
+	releaseArtistsTableName       = "release_artists"
+	artistNameVariationsTableName = "artist_name_variations"
+	artistRelationsTableName      = "artist_relations"
+)
+
+// SQL queries for artists
+const (
+	upsertDiscogsArtistSQL = `
+		INSERT INTO %s (id, name, resource_url)
+		VALUES ($1, $2, $3)` + renameDiscogsArtistSQL + `;
+	`
+	// renameDiscogsArtistSQL keeps the resource URL of a stored artist when the new copy has none.
+	renameDiscogsArtistSQL = `
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			resource_url = COALESCE(NULLIF(EXCLUDED.resource_url, ''), %s.resource_url)`
+	deleteReleaseArtistsSQL = `
+		DELETE FROM %s WHERE release_id = $1;
+	`
+	insertReleaseArtistSQL = `
+		INSERT INTO %s (release_id, position, artist_id, anv, join_phrase)
+		VALUES ($1, $2, $3, $4, $5);
+	`
+	// releaseArtistNamesSQL stands in for an attribute table in the release joins, so artists
+	// are matched by their canonical name or the name they were credited with on the release.
+	releaseArtistNamesSQL = `(SELECT ra.release_id, ra.artist_id, COALESCE(ra.anv, '') AS anv, da.name
+		FROM %s ra JOIN %s da ON da.id = ra.artist_id)`
+	fetchReleaseArtistsSQL = `
+		SELECT ra.release_id, da.id, da.name, COALESCE(ra.anv, ''), COALESCE(ra.join_phrase, ''), COALESCE(da.resource_url, '')
+		FROM %s ra
+		JOIN %s da ON da.id = ra.artist_id
+		WHERE ra.release_id IN (%s)
+		ORDER BY ra.release_id, ra.position
+	`
+	fetchArtistsSQL = `
+		SELECT da.id, da.name, COALESCE(da.resource_url, ''), COALESCE(da.real_name, ''), COALESCE(da.profile, ''),
+			da.profile_fetched_at
+		FROM %s da
+		WHERE da.id IN (%s)
+		ORDER BY da.name, da.id
+	`
+	// labelArtistIDsSQL selects the artists credited on releases, optionally narrowed by a
+	// label condition.
+	labelArtistIDsSQL = `
+		SELECT ra.artist_id FROM %s ra WHERE 1=1%s
+	`
+	labelArtistsScopeSQL    = ` AND ra.release_id IN (SELECT r.id FROM %s r WHERE %s)`
+	fetchArtistsToEnrichSQL = `
+		SELECT da.id FROM %s da
+		WHERE da.id IN (%s) AND (da.profile_fetched_at IS NULL%s)
+		ORDER BY da.id
+	`
+	upsertArtistProfileSQL = `
+		INSERT INTO %s (id, name, resource_url, real_name, profile, profile_fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			resource_url = COALESCE(NULLIF(EXCLUDED.resource_url, ''), %s.resource_url),
+			real_name = EXCLUDED.real_name,
+			profile = EXCLUDED.profile,
+			profile_fetched_at = EXCLUDED.profile_fetched_at;
+	`
+	deleteArtistDetailsSQL = `
+		DELETE FROM %s WHERE artist_id = $1;
+	`
+	insertArtistNameVariationSQL = `
+		INSERT INTO %s (artist_id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (artist_id, name) DO NOTHING;
+	`
+	insertArtistRelationSQL = `
+		INSERT INTO %s (artist_id, kind, related_id, related_name, active)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (artist_id, kind, related_id) DO NOTHING;
+	`
+	fetchArtistNameVariationsSQL = `
+		SELECT artist_id, name FROM %s WHERE artist_id IN (%s) ORDER BY artist_id, name
+	`
+	fetchArtistRelationsSQL = `
+		SELECT artist_id, kind, related_id, related_name, active FROM %s
+		WHERE artist_id IN (%s)
+		ORDER BY artist_id, kind, related_name
+	`
+)
+
+// releaseArtistNames is the join target used in place of the artists attribute table.
+func releaseArtistNames() string {
+	return fmt.Sprintf(releaseArtistNamesSQL, releaseArtistsTableName, discogsArtistsTableName)
+}
+
+// replaceReleaseArtists stores the artists of a release in order, creating or renaming their
+// artist entities. Name-only rows left in the artists table by older syncs are removed.
+func replaceReleaseArtists(tx *sql.Tx, releaseID int32, artists []models.ReleaseArtist) error {
+	for _, tableName := range []string{ArtistsTableName, releaseArtistsTableName} {
+		if _, err := tx.Exec(fmt.Sprintf(deleteReleaseArtistsSQL, tableName), releaseID); err != nil {
+			return fmt.Errorf("failed to delete from table %s: %v", tableName, err)
+		}
+	}
+
+	artistQuery := fmt.Sprintf(upsertDiscogsArtistSQL, discogsArtistsTableName, discogsArtistsTableName)
+	releaseArtistQuery := fmt.Sprintf(insertReleaseArtistSQL, releaseArtistsTableName)
+
+	for position, artist := range artists {
+		if _, err := tx.Exec(artistQuery, artist.Id, artist.Name, artist.ResourceURL); err != nil {
+			return fmt.Errorf("failed to insert into table %s: %v", discogsArtistsTableName, err)
+		}
+		if _, err := tx.Exec(releaseArtistQuery, releaseID, position, artist.Id, artist.Anv, artist.Join); err != nil {
+			return fmt.Errorf("failed to insert into table %s: %v", releaseArtistsTableName, err)
+		}
+	}
+	return nil
+}
+
+func fetchReleaseArtists(db *sql.DB, releases map[int32]*models.Release, placeholders string, args []interface{}) error {
+	query := fmt.Sprintf(fetchReleaseArtistsSQL, releaseArtistsTableName, discogsArtistsTableName, placeholders)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch release artists: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var releaseID int32
+		var artist models.ReleaseArtist
+		if err := rows.Scan(&releaseID, &artist.Id, &artist.Name, &artist.Anv, &artist.Join, &artist.ResourceURL); err != nil {
+			return fmt.Errorf("failed to scan release artist: %v", err)
+		}
+		release := releases[releaseID]
+		release.Artists = append(release.Artists, artist)
+	}
+	return rows.Err()
+}
+
+// labelArtistIDs returns a query for the ids of the artists credited on the releases of the
+// filtered label, or on any release when no label is given, with its arguments.
+func labelArtistIDs(labelFilter models.LabelFilter) (string, []interface{}) {
+	var labelScope string
+	var args []interface{}
+	if labelFilter.LabelId != 0 {
+		labelScope = fmt.Sprintf(labelArtistsScopeSQL, releasesTableName, labelCondition(labelFilter, 1))
+		args = append(args, labelFilter.LabelId)
+	}
+	return fmt.Sprintf(labelArtistIDsSQL, releaseArtistsTableName, labelScope), args
+}
+
+// FetchUniqueArtists returns the artists credited on stored releases with their profiles,
+// limited to the releases of the filtered label unless no label is given.
+func FetchUniqueArtists(db *sql.DB, labelFilter models.LabelFilter) ([]*models.Artist, error) {
+	artistIDs, args := labelArtistIDs(labelFilter)
+	return fetchArtists(db, artistIDs, args)
+}
+
+// FetchArtist returns the artist with its profile, or nil when no release credits it.
+func FetchArtist(db *sql.DB, artistID int) (*models.Artist, error) {
+	artists, err := fetchArtists(db, "$1", []interface{}{artistID})
+	if err != nil || len(artists) == 0 {
+		return nil, err
+	}
+	return artists[0], nil
+}
+
+// fetchArtists loads the artists selected by artistIDs, a list of placeholders or a subquery,
+// along with their name variations and relations.
+func fetchArtists(db *sql.DB, artistIDs string, args []interface{}) ([]*models.Artist, error) {
+	rows, err := db.Query(fmt.Sprintf(fetchArtistsSQL, discogsArtistsTableName, artistIDs), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch artists: %v", err)
+	}
+	defer rows.Close()
+
+	var artists []*models.Artist
+	byID := make(map[int]*models.Artist)
+	for rows.Next() {
+		artist := &models.Artist{}
+		var profileFetchedAt sql.NullTime
+		if err := rows.Scan(&artist.Id, &artist.Name, &artist.ResourceURL, &artist.RealName, &artist.Profile, &profileFetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan artist: %v", err)
+		}
+		if profileFetchedAt.Valid {
+			artist.ProfileFetchedAt = &profileFetchedAt.Time
+		}
+		artists = append(artists, artist)
+		byID[artist.Id] = artist
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read artists: %v", err)
+	}
+	rows.Close()
+
+	if len(artists) == 0 {
+		return artists, nil
+	}
+	if err := fetchArtistNameVariations(db, byID, artistIDs, args); err != nil {
+		return nil, err
+	}
+	if err := fetchArtistRelations(db, byID, artistIDs, args); err != nil {
+		return nil, err
+	}
+	return artists, nil
+}
+
+func fetchArtistNameVariations(db *sql.DB, artists map[int]*models.Artist, artistIDs string, args []interface{}) error {
+	query := fmt.Sprintf(fetchArtistNameVariationsSQL, artistNameVariationsTableName, artistIDs)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch artist name variations: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var artistID int
+		var name string
+		if err := rows.Scan(&artistID, &name); err != nil {
+			return fmt.Errorf("failed to scan artist name variation: %v", err)
+		}
+		if artist, ok := artists[artistID]; ok {
+			artist.NameVariations = append(artist.NameVariations, name)
+		}
+	}
+	return rows.Err()
+}
+
+func fetchArtistRelations(db *sql.DB, artists map[int]*models.Artist, artistIDs string, args []interface{}) error {
+	query := fmt.Sprintf(fetchArtistRelationsSQL, artistRelationsTableName, artistIDs)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch artist relations: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var artistID int
+		var kind string
+		var related models.ArtistRef
+		var active sql.NullBool
+		if err := rows.Scan(&artistID, &kind, &related.Id, &related.Name, &active); err != nil {
+			return fmt.Errorf("failed to scan artist relation: %v", err)
+		}
+		if active.Valid {
+			related.Active = &active.Bool
+		}
+
+		artist, ok := artists[artistID]
+		if !ok {
+			continue
+		}
+		switch kind {
+		case models.ArtistRelationAlias:
+			artist.Aliases = append(artist.Aliases, related)
+		case models.ArtistRelationGroup:
+			artist.Groups = append(artist.Groups, related)
+		case models.ArtistRelationMember:
+			artist.Members = append(artist.Members, related)
+		}
+	}
+	return rows.Err()
+}
+
+// FetchArtistsToEnrich returns the ids of the artists credited on the filtered label's releases
+// whose profile was never fetched or, when refetchBefore is set, was fetched before it.
+func FetchArtistsToEnrich(db *sql.DB, labelFilter models.LabelFilter, refetchBefore *time.Time) ([]int, error) {
+	artistIDs, args := labelArtistIDs(labelFilter)
+	var staleCondition string
+	if refetchBefore != nil {
+		args = append(args, *refetchBefore)
+		staleCondition = fmt.Sprintf(" OR da.profile_fetched_at < $%d", len(args))
+	}
+
+	query := fmt.Sprintf(fetchArtistsToEnrichSQL, discogsArtistsTableName, artistIDs, staleCondition)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch artists to enrich: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan artist id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// StoreArtistProfile stores a fetched artist profile, replacing its name variations and its
+// aliases, groups and members.
+func StoreArtistProfile(db *sql.DB, artist *models.Artist, fetchedAt time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	profileQuery := fmt.Sprintf(upsertArtistProfileSQL, discogsArtistsTableName, discogsArtistsTableName)
+	if _, err := tx.Exec(profileQuery, artist.Id, artist.Name, artist.ResourceURL, artist.RealName, artist.Profile, fetchedAt); err != nil {
+		return fmt.Errorf("failed to store profile of artist %d: %v", artist.Id, err)
+	}
+
+	for _, tableName := range []string{artistNameVariationsTableName, artistRelationsTableName} {
+		if _, err := tx.Exec(fmt.Sprintf(deleteArtistDetailsSQL, tableName), artist.Id); err != nil {
+			return fmt.Errorf("failed to delete from table %s: %v", tableName, err)
+		}
+	}
+
+	variationQuery := fmt.Sprintf(insertArtistNameVariationSQL, artistNameVariationsTableName)
+	for _, name := range artist.NameVariations {
+		if _, err := tx.Exec(variationQuery, artist.Id, name); err != nil {
+			return fmt.Errorf("failed to insert into table %s: %v", artistNameVariationsTableName, err)
+		}
+	}
+
+	relationQuery := fmt.Sprintf(insertArtistRelationSQL, artistRelationsTableName)
+	relations := map[string][]models.ArtistRef{
+		models.ArtistRelationAlias:  artist.Aliases,
+		models.ArtistRelationGroup:  artist.Groups,
+		models.ArtistRelationMember: artist.Members,
+	}
+	for _, kind := range []string{models.ArtistRelationAlias, models.ArtistRelationGroup, models.ArtistRelationMember} {
+		for _, related := range relations[kind] {
+			if _, err := tx.Exec(relationQuery, artist.Id, kind, related.Id, related.Name, related.Active); err != nil {
+				return fmt.Errorf("failed to insert into table %s: %v", artistRelationsTableName, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
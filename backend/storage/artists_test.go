@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+func TestFetchUniqueArtistsByLabel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "resource_url", "real_name", "profile", "profile_fetched_at"}).
+		AddRow(355, "Unknown Artist", "https://api.discogs.com/artists/355", "", "", nil).
+		AddRow(11, "John Smith (2)", "", "John Smith", "", time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC))
+
+	labelArtists := `da.id IN \( SELECT ra.artist_id FROM release_artists ra WHERE 1=1 AND ra.release_id IN \(SELECT r.id FROM releases r WHERE r.id IN \(SELECT release_id FROM label_releases WHERE label_id = \$1\)\) \)`
+	mock.ExpectQuery(`SELECT da.id, da.name, .* FROM discogs_artists da WHERE ` + labelArtists + ` ORDER BY da.name, da.id`).
+		WithArgs(5).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT artist_id, name FROM artist_name_variations WHERE artist_id IN \( SELECT ra.artist_id`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"artist_id", "name"}).AddRow(11, "J. Smith"))
+	mock.ExpectQuery(`FROM artist_relations WHERE artist_id IN \( SELECT ra.artist_id`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"artist_id", "kind", "related_id", "related_name", "active"}).
+			AddRow(11, models.ArtistRelationAlias, 12, "Smithy", nil).
+			AddRow(11, models.ArtistRelationGroup, 13, "The Smiths (3)", false))
+
+	artists, err := FetchUniqueArtists(db, models.LabelFilter{LabelId: 5})
+	if err != nil {
+		t.Fatalf("failed to fetch unique artists: %v", err)
+	}
+	if len(artists) != 2 || artists[0].Id != 355 || artists[1].Name != "John Smith (2)" {
+		t.Fatalf("unexpected artists: %+v", artists)
+	}
+	if artists[0].ProfileFetchedAt != nil || artists[1].ProfileFetchedAt == nil {
+		t.Errorf("unexpected profile fetch times: %v %v", artists[0].ProfileFetchedAt, artists[1].ProfileFetchedAt)
+	}
+	smith := artists[1]
+	if len(smith.NameVariations) != 1 || len(smith.Aliases) != 1 || smith.Aliases[0].Active != nil {
+		t.Errorf("unexpected name variations or aliases: %+v", smith)
+	}
+	if len(smith.Groups) != 1 || smith.Groups[0].Active == nil || *smith.Groups[0].Active {
+		t.Errorf("unexpected groups: %+v", smith.Groups)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchArtistsToEnrich(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	refetchBefore := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT da.id FROM discogs_artists da WHERE da.id IN \(.*\) AND \(da.profile_fetched_at IS NULL OR da.profile_fetched_at < \$2\) ORDER BY da.id`).
+		WithArgs(5, refetchBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11).AddRow(12))
+
+	ids, err := FetchArtistsToEnrich(db, models.LabelFilter{LabelId: 5}, &refetchBefore)
+	if err != nil {
+		t.Fatalf("failed to fetch artists to enrich: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("expected 2 artists, got %v", ids)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreArtistProfile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	active := true
+	artist := &models.Artist{
+		Id:             11,
+		Name:           "John Smith (2)",
+		RealName:       "John Smith",
+		Profile:        "Drummer.",
+		NameVariations: []string{"J. Smith"},
+		Groups:         []models.ArtistRef{{Id: 13, Name: "The Smiths (3)", Active: &active}},
+	}
+	fetchedAt := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO discogs_artists").
+		WithArgs(11, "John Smith (2)", "", "John Smith", "Drummer.", fetchedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM artist_name_variations WHERE artist_id = \\$1").WithArgs(11).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM artist_relations WHERE artist_id = \\$1").WithArgs(11).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO artist_name_variations").WithArgs(11, "J. Smith").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO artist_relations").
+		WithArgs(11, models.ArtistRelationGroup, 13, "The Smiths (3)", true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := StoreArtistProfile(db, artist, fetchedAt); err != nil {
+		t.Fatalf("failed to store artist profile: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreArtistProfileRollsBackWhenInsertingRelationsFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	artist := &models.Artist{Id: 11, Name: "John Smith (2)", Aliases: []models.ArtistRef{{Id: 12, Name: "J.S."}}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO discogs_artists").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM artist_name_variations WHERE artist_id = \\$1").WithArgs(11).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM artist_relations WHERE artist_id = \\$1").WithArgs(11).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO artist_relations").WillReturnError(fmt.Errorf("connection lost"))
+	mock.ExpectRollback().WillReturnError(fmt.Errorf("connection closed"))
+
+	err = StoreArtistProfile(db, artist, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Fatalf("expected the relation insert error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
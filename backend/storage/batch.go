@@ -0,0 +1,334 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"log"
+	"strings"
+	"time"
+)
+
+// maxBatchArgs keeps multi-row statements under the bind parameter limits, 65535 in Postgres and
+// 32766 in SQLite. Larger inserts are split into several statements.
+const maxBatchArgs = 30000
+
+// SQL queries for batches
+const (
+	insertRowsSQL = `
+		INSERT INTO %s (%s)
+		VALUES %s%s
+	`
+	deleteBatchAttributesSQL = `
+		DELETE FROM %s WHERE release_id IN (%s)
+	`
+	ignoreAttributeConflictSQL   = ` ON CONFLICT (release_id, name) DO NOTHING`
+	ignoreDescriptionConflictSQL = ` ON CONFLICT (format_id, description) DO NOTHING`
+	ignoreSnapshotConflictSQL    = ` ON CONFLICT (release_id, captured_at) DO NOTHING`
+	// Formats and tracks return their release and place, so their rows can be matched to the
+	// descriptions and artists inserted next.
+	returningFormatIDsSQL = ` RETURNING id, release_id, position`
+	returningTrackIDsSQL  = ` RETURNING id, release_id, sequence`
+)
+
+// releaseAttributeTables are replaced for every release in a batch. Format descriptions and track
+// artists go along with their formats and tracks.
+var releaseAttributeTables = []string{ArtistsTableName, releaseArtistsTableName, GenresTableName, StylesTableName,
+	formatsTableName, tracksTableName, creditsTableName, releaseCompaniesTableName, identifiersTableName}
+
+// batchRows are the rows of one table to insert in a batch.
+type batchRows struct {
+	tableName string
+	columns   string
+	suffix    string
+	rows      [][]interface{}
+}
+
+// releasePlace identifies a format or track by its release and position, before it has an id.
+type releasePlace struct {
+	releaseID int32
+	position  int
+}
+
+// StoreReleases stores the releases in a single transaction, writing each table with multi-row
+// inserts instead of a statement per row. When the batch cannot be written, the releases are
+// stored one by one, so that a bad release only fails itself. It returns the errors of the
+// releases that could not be stored by id.
+func StoreReleases(db *sql.DB, releases []*models.Release) map[int32]error {
+	if len(releases) == 0 {
+		return nil
+	}
+
+	err := storeReleaseBatch(db, releases)
+	if err == nil {
+		log.Printf("Stored a batch of %d releases", len(releases))
+		return nil
+	}
+	log.Printf("Failed to store a batch of %d releases, storing them one by one: %v", len(releases), err)
+
+	failed := make(map[int32]error)
+	for _, release := range releases {
+		if err := StoreRelease(db, release); err != nil {
+			failed[release.Id] = err
+		}
+	}
+	return failed
+}
+
+func storeReleaseBatch(db *sql.DB, releases []*models.Release) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := writeReleaseBatch(tx, uniqueReleases(releases), time.Now().UTC()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// uniqueReleases keeps the last copy of a release listed more than once, as one statement cannot
+// update a row twice.
+func uniqueReleases(releases []*models.Release) []*models.Release {
+	positions := make(map[int32]int, len(releases))
+	unique := make([]*models.Release, 0, len(releases))
+	for _, release := range releases {
+		if position, ok := positions[release.Id]; ok {
+			unique[position] = release
+			continue
+		}
+		positions[release.Id] = len(unique)
+		unique = append(unique, release)
+	}
+	return unique
+}
+
+// writeReleaseBatch writes the same rows as StoreRelease does for each release, a table at a time.
+func writeReleaseBatch(tx *sql.Tx, releases []*models.Release, fetchedAt time.Time) error {
+	releaseRows := batchRows{tableName: releasesTableName, columns: releaseColumns, suffix: upsertReleaseSQL}
+	releaseIDs := make([]interface{}, 0, len(releases))
+	for _, release := range releases {
+		releaseRows.rows = append(releaseRows.rows, []interface{}{release.Id, release.Title, nullIfZero(release.Year),
+			release.Released, release.Country, release.DataQuality, release.Notes, release.URI,
+			nullIfZero(release.MasterId), fetchedAt})
+		releaseIDs = append(releaseIDs, release.Id)
+	}
+	if err := insertRows(tx, releaseRows, nil); err != nil {
+		return err
+	}
+	if err := deleteBatchAttributes(tx, releaseIDs); err != nil {
+		return err
+	}
+
+	attributes := releaseAttributeRows(releases, fetchedAt)
+	for _, rows := range attributes {
+		if err := insertRows(tx, rows, nil); err != nil {
+			return err
+		}
+	}
+	if err := insertFormatRows(tx, releases); err != nil {
+		return err
+	}
+	return insertTrackRows(tx, releases)
+}
+
+func deleteBatchAttributes(tx *sql.Tx, releaseIDs []interface{}) error {
+	placeholders := make([]string, len(releaseIDs))
+	for i := range releaseIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	for _, tableName := range releaseAttributeTables {
+		query := fmt.Sprintf(deleteBatchAttributesSQL, tableName, strings.Join(placeholders, ", "))
+		if _, err := tx.Exec(query, releaseIDs...); err != nil {
+			return fmt.Errorf("failed to delete from table %s: %v", tableName, err)
+		}
+	}
+	return nil
+}
+
+// releaseAttributeRows collects the rows of the tables that need no generated ids, in an order
+// that satisfies their foreign keys.
+func releaseAttributeRows(releases []*models.Release, fetchedAt time.Time) []batchRows {
+	artists := batchRows{tableName: discogsArtistsTableName, columns: "id, name, resource_url",
+		suffix: fmt.Sprintf(renameDiscogsArtistSQL, discogsArtistsTableName)}
+	releaseArtists := batchRows{tableName: releaseArtistsTableName, columns: "release_id, position, artist_id, anv, join_phrase"}
+	genres := batchRows{tableName: GenresTableName, columns: "release_id, name", suffix: ignoreAttributeConflictSQL}
+	styles := batchRows{tableName: StylesTableName, columns: "release_id, name", suffix: ignoreAttributeConflictSQL}
+	credits := batchRows{tableName: creditsTableName, columns: "release_id, sequence, artist_id, name, role, tracks"}
+	companies := batchRows{tableName: releaseCompaniesTableName, columns: "release_id, kind, position, company_id, name, role, catno"}
+	identifiers := batchRows{tableName: identifiersTableName,
+		columns: "release_id, position, type, value, description, normalized_value"}
+	snapshots := batchRows{tableName: communitySnapshotsTableName,
+		columns: "release_id, captured_at, have, want, rating_average, rating_count", suffix: ignoreSnapshotConflictSQL}
+
+	// An artist credited on several releases is upserted once, with the last name seen and the
+	// last resource URL known.
+	artistRows := make(map[int]int)
+	for _, release := range releases {
+		for position, artist := range release.Artists {
+			if row, ok := artistRows[artist.Id]; ok {
+				artists.rows[row][1] = artist.Name
+				if artist.ResourceURL != "" {
+					artists.rows[row][2] = artist.ResourceURL
+				}
+			} else {
+				artistRows[artist.Id] = len(artists.rows)
+				artists.rows = append(artists.rows, []interface{}{artist.Id, artist.Name, artist.ResourceURL})
+			}
+			releaseArtists.rows = append(releaseArtists.rows, []interface{}{release.Id, position, artist.Id, artist.Anv, artist.Join})
+		}
+		for _, genre := range release.Genres {
+			genres.rows = append(genres.rows, []interface{}{release.Id, genre})
+		}
+		for _, style := range release.Styles {
+			styles.rows = append(styles.rows, []interface{}{release.Id, style})
+		}
+		for sequence, credit := range release.Credits {
+			credits.rows = append(credits.rows, []interface{}{release.Id, sequence, nullIfZero(credit.ArtistId),
+				credit.Name, credit.Role, credit.Tracks})
+		}
+		for position, label := range release.Labels {
+			companies.rows = append(companies.rows, []interface{}{release.Id, releaseCompanyKindLabel, position,
+				nullIfZero(label.Id), label.Name, "", label.Catno})
+		}
+		for position, company := range release.Companies {
+			companies.rows = append(companies.rows, []interface{}{release.Id, releaseCompanyKindCompany, position,
+				nullIfZero(company.Id), company.Name, company.Role, company.Catno})
+		}
+		for position, identifier := range release.Identifiers {
+			identifiers.rows = append(identifiers.rows, []interface{}{release.Id, position, identifier.Type,
+				identifier.Value, identifier.Description, normalizeIdentifier(identifier.Value)})
+		}
+		if community := release.Community; community != nil {
+			snapshots.rows = append(snapshots.rows, []interface{}{release.Id, fetchedAt, community.Have, community.Want,
+				sql.NullFloat64{Float64: community.RatingAverage, Valid: community.RatingCount > 0},
+				community.RatingCount})
+		}
+	}
+
+	return []batchRows{artists, releaseArtists, genres, styles, credits, companies, identifiers, snapshots}
+}
+
+func insertFormatRows(tx *sql.Tx, releases []*models.Release) error {
+	formats := batchRows{tableName: formatsTableName, columns: "release_id, position, name, qty, text", suffix: returningFormatIDsSQL}
+	for _, release := range releases {
+		for position, format := range release.Formats {
+			formats.rows = append(formats.rows, []interface{}{release.Id, position, format.Name, nullIfZero(format.Qty), format.Text})
+		}
+	}
+
+	formatIDs := make(map[releasePlace]int)
+	if err := insertRows(tx, formats, func(rows *sql.Rows) error {
+		var formatID int
+		var place releasePlace
+		if err := rows.Scan(&formatID, &place.releaseID, &place.position); err != nil {
+			return err
+		}
+		formatIDs[place] = formatID
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	descriptions := batchRows{tableName: formatDescriptionsTableName, columns: "format_id, description", suffix: ignoreDescriptionConflictSQL}
+	for _, release := range releases {
+		for position, format := range release.Formats {
+			for _, description := range format.Descriptions {
+				descriptions.rows = append(descriptions.rows, []interface{}{formatIDs[releasePlace{release.Id, position}], description})
+			}
+		}
+	}
+	return insertRows(tx, descriptions, nil)
+}
+
+func insertTrackRows(tx *sql.Tx, releases []*models.Release) error {
+	tracks := batchRows{tableName: tracksTableName,
+		columns: "release_id, sequence, position, type, title, duration, duration_seconds", suffix: returningTrackIDsSQL}
+	for _, release := range releases {
+		for sequence, track := range release.Tracks {
+			tracks.rows = append(tracks.rows, []interface{}{release.Id, sequence, track.Position, track.Type, track.Title,
+				track.Duration, nullIfZero(track.DurationSeconds)})
+		}
+	}
+
+	trackIDs := make(map[releasePlace]int)
+	if err := insertRows(tx, tracks, func(rows *sql.Rows) error {
+		var trackID int
+		var place releasePlace
+		if err := rows.Scan(&trackID, &place.releaseID, &place.position); err != nil {
+			return err
+		}
+		trackIDs[place] = trackID
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	artists := batchRows{tableName: trackArtistsTableName, columns: "track_id, sequence, name, role"}
+	for _, release := range releases {
+		for sequence, track := range release.Tracks {
+			for artistSequence, artist := range track.Artists {
+				artists.rows = append(artists.rows, []interface{}{trackIDs[releasePlace{release.Id, sequence}],
+					artistSequence, artist.Name, artist.Role})
+			}
+		}
+	}
+	return insertRows(tx, artists, nil)
+}
+
+// insertRows inserts the rows with as few statements as the bind parameter limit allows. When
+// scan is given, the statements return rows, which scan reads one at a time.
+func insertRows(tx *sql.Tx, batch batchRows, scan func(rows *sql.Rows) error) error {
+	if len(batch.rows) == 0 {
+		return nil
+	}
+
+	rowsPerStatement := maxBatchArgs / len(batch.rows[0])
+	for start := 0; start < len(batch.rows); start += rowsPerStatement {
+		end := min(start+rowsPerStatement, len(batch.rows))
+
+		values := make([]string, 0, end-start)
+		var args []interface{}
+		for _, row := range batch.rows[start:end] {
+			placeholders := make([]string, len(row))
+			for i, value := range row {
+				args = append(args, value)
+				placeholders[i] = fmt.Sprintf("$%d", len(args))
+			}
+			values = append(values, "("+strings.Join(placeholders, ", ")+")")
+		}
+		query := fmt.Sprintf(insertRowsSQL, batch.tableName, batch.columns, strings.Join(values, ", "), batch.suffix)
+
+		if scan == nil {
+			if _, err := tx.Exec(query, args...); err != nil {
+				return fmt.Errorf("failed to insert into table %s: %v", batch.tableName, err)
+			}
+			continue
+		}
+		if err := queryRows(tx, query, args, scan); err != nil {
+			return fmt.Errorf("failed to insert into table %s: %v", batch.tableName, err)
+		}
+	}
+	return nil
+}
+
+func queryRows(tx *sql.Tx, query string, args []interface{}, scan func(rows *sql.Rows) error) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
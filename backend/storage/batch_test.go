@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+func TestStoreReleases(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	releases := []*models.Release{
+		{
+			Id: 1, Title: "Title 1",
+			Artists: []models.ReleaseArtist{{Id: 11, Name: "Artist 1"}},
+			Genres:  []string{"Genre 1"},
+			Formats: []models.Format{{Name: "Vinyl", Qty: 1, Descriptions: []string{"LP"}}},
+			Tracks:  []models.Track{{Position: "A1", Title: "Track 1", Artists: []models.TrackArtist{{Name: "Remixer 1", Role: "Remix"}}}},
+		},
+		{
+			Id: 2, Title: "Title 2",
+			Artists: []models.ReleaseArtist{{Id: 11, Name: "Artist 1 (2)", ResourceURL: "https://api.discogs.com/artists/11"}},
+			Genres:  []string{"Genre 1", "Genre 2"},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO releases \\(id, title, .*\\) VALUES \\(\\$1, .*\\), \\(\\$11, .*\\) ON CONFLICT \\(id\\) DO UPDATE").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	for _, tableName := range releaseAttributeTables {
+		mock.ExpectExec("DELETE FROM "+tableName+" WHERE release_id IN \\(\\$1, \\$2\\)").WithArgs(int32(1), int32(2)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	// The artist of both releases is upserted once.
+	mock.ExpectExec("INSERT INTO discogs_artists \\(id, name, resource_url\\) VALUES \\(\\$1, \\$2, \\$3\\) ON CONFLICT").
+		WithArgs(11, "Artist 1 (2)", "https://api.discogs.com/artists/11").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO release_artists").WithArgs(int32(1), 0, 11, "", "", int32(2), 0, 11, "", "").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO genres \\(release_id, name\\) VALUES \\(\\$1, \\$2\\), \\(\\$3, \\$4\\), \\(\\$5, \\$6\\) ON CONFLICT").
+		WithArgs(int32(1), "Genre 1", int32(2), "Genre 1", int32(2), "Genre 2").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery("INSERT INTO formats .* RETURNING id, release_id, position").
+		WithArgs(int32(1), 0, "Vinyl", sqlmock.AnyArg(), "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "release_id", "position"}).AddRow(30, 1, 0))
+	mock.ExpectExec("INSERT INTO format_descriptions").WithArgs(30, "LP").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO tracks .* RETURNING id, release_id, sequence").
+		WithArgs(int32(1), 0, "A1", "", "Track 1", "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "release_id", "sequence"}).AddRow(40, 1, 0))
+	mock.ExpectExec("INSERT INTO track_artists").WithArgs(40, 0, "Remixer 1", "Remix").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if failed := StoreReleases(db, releases); len(failed) != 0 {
+		t.Fatalf("failed to store releases: %v", failed)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStoreReleasesReportsFailuresPerRelease(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	releases := []*models.Release{{Id: 1, Title: "Title 1"}, {Id: 2, Title: "Title 2"}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO releases").WillReturnError(fmt.Errorf("value too long"))
+	mock.ExpectRollback()
+
+	// Stored one by one, the first release succeeds and the second fails again.
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO releases").WithArgs(int32(1), "Title 1", sqlmock.AnyArg(), "", "", "", "", "",
+		sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, tableName := range []string{"artists", "release_artists", "genres", "styles", "formats", "tracks", "credits",
+		"release_companies", "identifiers"} {
+		mock.ExpectExec("DELETE FROM " + tableName + " WHERE release_id").WithArgs(int32(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO releases").WithArgs(int32(2), "Title 2", sqlmock.AnyArg(), "", "", "", "", "",
+		sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(fmt.Errorf("value too long"))
+	mock.ExpectRollback()
+
+	// The cause is reported, not the outcome of the rollback.
+	failed := StoreReleases(db, releases)
+	if len(failed) != 1 || failed[2] == nil || !strings.Contains(failed[2].Error(), "failed to insert release: value too long") {
+		t.Errorf("expected only release 2 to fail, got %v", failed)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,253 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"time"
+)
+
+// Community table names
+const (
+	communitySnapshotsTableName = "community_snapshots"
+)
+
+// SQL queries for community statistics
+const (
+	insertCommunitySnapshotSQL = `
+		INSERT INTO %s (release_id, captured_at, have, want, rating_average, rating_count)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (release_id, captured_at) DO NOTHING;
+	`
+	fetchReleasesToSnapshotSQL = `
+		SELECT r.id FROM %s r
+		WHERE %s AND NOT EXISTS (
+			SELECT 1 FROM %s cs WHERE cs.release_id = r.id AND cs.captured_at >= $2
+		)
+		ORDER BY r.id
+	`
+	communitySnapshotColumns = `cs.release_id, cs.captured_at, cs.have, cs.want, COALESCE(cs.rating_average, 0),
+			COALESCE(cs.rating_count, 0)`
+	// latestSnapshotSQL keeps the snapshots aliased as cs that are the latest of their release.
+	latestSnapshotSQL       = `cs.captured_at = (SELECT MAX(l.captured_at) FROM %s l WHERE l.release_id = cs.release_id)`
+	fetchLatestCommunitySQL = `
+		SELECT ` + communitySnapshotColumns + `
+		FROM %s cs
+		WHERE cs.release_id IN (%s) AND %s
+	`
+	fetchCommunityHistorySQL = `
+		SELECT ` + communitySnapshotColumns + `
+		FROM %s cs
+		WHERE cs.release_id = $1%s
+		ORDER BY cs.captured_at
+	`
+	fetchMostWantedSQL = `
+		SELECT cs.release_id FROM %s cs
+		WHERE %s%s
+		ORDER BY cs.want DESC, cs.release_id
+		LIMIT $%d
+	`
+	// Want growth is measured between the first and the last snapshot of a release taken in the
+	// period, so releases first synced during the period are compared from their first snapshot.
+	fetchTrendingSQL = `
+		SELECT period_start.release_id, period_start.want, period_end.want,
+			period_end.want - period_start.want AS growth
+		FROM %s period_start
+		JOIN %s period_end ON period_end.release_id = period_start.release_id
+		WHERE period_start.captured_at = (
+				SELECT MIN(p.captured_at) FROM %s p
+				WHERE p.release_id = period_start.release_id AND p.captured_at BETWEEN $1 AND $2
+			)
+			AND period_end.captured_at = (
+				SELECT MAX(p.captured_at) FROM %s p
+				WHERE p.release_id = period_start.release_id AND p.captured_at BETWEEN $1 AND $2
+			)%s
+		ORDER BY growth DESC, period_start.release_id
+		LIMIT $%d
+	`
+	communityLabelScopeSQL = ` AND %s.release_id IN (SELECT r.id FROM %s r WHERE %s)`
+)
+
+// FetchReleasesToSnapshot returns the ids of the stored releases of the filtered label without a
+// community snapshot taken since capturedSince. Releases stored during a sync already have one.
+func FetchReleasesToSnapshot(db *sql.DB, labelFilter models.LabelFilter, capturedSince time.Time) ([]int32, error) {
+	query := fmt.Sprintf(fetchReleasesToSnapshotSQL, releasesTableName, labelCondition(labelFilter, 1),
+		communitySnapshotsTableName)
+	rows, err := db.Query(query, labelFilter.LabelId, capturedSince.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases to snapshot: %v", err)
+	}
+	defer rows.Close()
+
+	var releaseIDs []int32
+	for rows.Next() {
+		var releaseID int32
+		if err := rows.Scan(&releaseID); err != nil {
+			return nil, fmt.Errorf("failed to scan release id: %v", err)
+		}
+		releaseIDs = append(releaseIDs, releaseID)
+	}
+	return releaseIDs, rows.Err()
+}
+
+// insertCommunitySnapshot records the community statistics of a release as they were when it was
+// fetched. Earlier snapshots are kept to follow the statistics over time.
+func insertCommunitySnapshot(tx *sql.Tx, releaseID int32, community *models.CommunityStats, capturedAt time.Time) error {
+	if community == nil {
+		return nil
+	}
+
+	query := fmt.Sprintf(insertCommunitySnapshotSQL, communitySnapshotsTableName)
+	if _, err := tx.Exec(query, releaseID, capturedAt, community.Have, community.Want,
+		sql.NullFloat64{Float64: community.RatingAverage, Valid: community.RatingCount > 0},
+		community.RatingCount); err != nil {
+		return fmt.Errorf("failed to insert into table %s: %v", communitySnapshotsTableName, err)
+	}
+	return nil
+}
+
+func scanCommunitySnapshots(rows *sql.Rows, add func(releaseID int32, community models.CommunityStats)) error {
+	defer rows.Close()
+
+	for rows.Next() {
+		var releaseID int32
+		var community models.CommunityStats
+		if err := rows.Scan(&releaseID, &community.CapturedAt, &community.Have, &community.Want,
+			&community.RatingAverage, &community.RatingCount); err != nil {
+			return fmt.Errorf("failed to scan community snapshot: %v", err)
+		}
+		add(releaseID, community)
+	}
+	return rows.Err()
+}
+
+func fetchReleaseCommunity(db *sql.DB, releases map[int32]*models.Release, placeholders string, args []interface{}) error {
+	query := fmt.Sprintf(fetchLatestCommunitySQL, communitySnapshotsTableName, placeholders, latestSnapshot())
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch release community statistics: %v", err)
+	}
+	return scanCommunitySnapshots(rows, func(releaseID int32, community models.CommunityStats) {
+		releases[releaseID].Community = &community
+	})
+}
+
+func latestSnapshot() string {
+	return fmt.Sprintf(latestSnapshotSQL, communitySnapshotsTableName)
+}
+
+// FetchCommunityHistory returns the community statistics snapshots of a release in the order they
+// were taken, optionally limited to those taken from and until the given times.
+func FetchCommunityHistory(db *sql.DB, releaseID int32, from *time.Time, until *time.Time) ([]models.CommunityStats, error) {
+	args := []interface{}{releaseID}
+	var period string
+	if from != nil {
+		args = append(args, *from)
+		period += fmt.Sprintf(" AND cs.captured_at >= $%d", len(args))
+	}
+	if until != nil {
+		args = append(args, *until)
+		period += fmt.Sprintf(" AND cs.captured_at <= $%d", len(args))
+	}
+
+	rows, err := db.Query(fmt.Sprintf(fetchCommunityHistorySQL, communitySnapshotsTableName, period), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch community history of release %d: %v", releaseID, err)
+	}
+
+	history := []models.CommunityStats{}
+	err = scanCommunitySnapshots(rows, func(_ int32, community models.CommunityStats) {
+		history = append(history, community)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// FetchMostWanted returns the releases of the filtered label most wanted by the Discogs community
+// when last synced.
+func FetchMostWanted(db *sql.DB, labelFilter models.LabelFilter, limit int) ([]*models.Release, error) {
+	var args []interface{}
+	var labelScope string
+	if labelFilter.LabelId != 0 {
+		args = append(args, labelFilter.LabelId)
+		labelScope = communityLabelScope("cs", labelFilter, len(args))
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf(fetchMostWantedSQL, communitySnapshotsTableName, latestSnapshot(), labelScope, len(args))
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch most wanted releases: %v", err)
+	}
+	defer rows.Close()
+
+	var releaseIDs []int32
+	for rows.Next() {
+		var releaseID int32
+		if err := rows.Scan(&releaseID); err != nil {
+			return nil, fmt.Errorf("failed to scan release id: %v", err)
+		}
+		releaseIDs = append(releaseIDs, releaseID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	return fetchReleasesByIDs(db, releaseIDs)
+}
+
+// FetchTrending returns the releases of the filtered label whose want count grew the most between
+// from and until, ranked by that growth.
+func FetchTrending(db *sql.DB, labelFilter models.LabelFilter, from time.Time, until time.Time, limit int) ([]models.WantGrowth, error) {
+	args := []interface{}{from, until}
+	var labelScope string
+	if labelFilter.LabelId != 0 {
+		args = append(args, labelFilter.LabelId)
+		labelScope = communityLabelScope("period_start", labelFilter, len(args))
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf(fetchTrendingSQL, communitySnapshotsTableName, communitySnapshotsTableName,
+		communitySnapshotsTableName, communitySnapshotsTableName, labelScope, len(args))
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trending releases: %v", err)
+	}
+	defer rows.Close()
+
+	var trending []models.WantGrowth
+	var releaseIDs []int32
+	for rows.Next() {
+		var releaseID int32
+		var growth models.WantGrowth
+		if err := rows.Scan(&releaseID, &growth.WantFrom, &growth.WantUntil, &growth.Growth); err != nil {
+			return nil, fmt.Errorf("failed to scan want growth: %v", err)
+		}
+		trending = append(trending, growth)
+		releaseIDs = append(releaseIDs, releaseID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	releases, err := fetchReleasesByIDs(db, releaseIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int32]*models.Release, len(releases))
+	for _, release := range releases {
+		byID[release.Id] = release
+	}
+	for i, releaseID := range releaseIDs {
+		trending[i].Release = byID[releaseID]
+	}
+	return trending, nil
+}
+
+func communityLabelScope(alias string, labelFilter models.LabelFilter, argIndex int) string {
+	return fmt.Sprintf(communityLabelScopeSQL, alias, releasesTableName, labelCondition(labelFilter, argIndex))
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+func TestFetchCommunityHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM community_snapshots cs WHERE cs.release_id = \$1 AND cs.captured_at >= \$2 ORDER BY cs.captured_at`).
+		WithArgs(int32(7), from).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "captured_at", "have", "want", "rating_average", "rating_count"}).
+			AddRow(7, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 100, 40, 4.5, 12).
+			AddRow(7, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 110, 52, 4.6, 15))
+
+	history, err := FetchCommunityHistory(db, 7, &from, nil)
+	if err != nil {
+		t.Fatalf("failed to fetch community history: %v", err)
+	}
+	if len(history) != 2 || history[0].Want != 40 || history[1].Want != 52 || history[1].RatingCount != 15 {
+		t.Errorf("unexpected community history: %+v", history)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchMostWanted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT cs.release_id FROM community_snapshots cs WHERE cs.captured_at = \(SELECT MAX\(l.captured_at\) FROM community_snapshots l WHERE l.release_id = cs.release_id\) AND cs.release_id IN \(SELECT r.id FROM releases r WHERE .*\) ORDER BY cs.want DESC, cs.release_id LIMIT \$2`).
+		WithArgs(5, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id"}))
+
+	releases, err := FetchMostWanted(db, models.LabelFilter{LabelId: 5}, 10)
+	if err != nil {
+		t.Fatalf("failed to fetch most wanted releases: %v", err)
+	}
+	if len(releases) != 0 {
+		t.Errorf("expected no releases, got %d", len(releases))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchTrending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM community_snapshots period_start JOIN community_snapshots period_end .* ORDER BY growth DESC, period_start.release_id LIMIT \$3`).
+		WithArgs(from, until, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "want_from", "want_until", "growth"}).
+			AddRow(2, 10, 60, 50).
+			AddRow(1, 40, 52, 12))
+	// Release 2 was removed after its snapshots were taken.
+	mock.ExpectQuery(`FROM releases r WHERE r.id IN \(\$1, \$2\)`).
+		WithArgs(int32(2), int32(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "year", "released", "country", "data_quality", "notes", "uri", "master_id"}))
+
+	trending, err := FetchTrending(db, models.LabelFilter{}, from, until, 5)
+	if err != nil {
+		t.Fatalf("failed to fetch trending releases: %v", err)
+	}
+	if len(trending) != 2 || trending[0].Growth != 50 || trending[0].WantUntil != 60 || trending[1].Growth != 12 {
+		t.Errorf("unexpected trending releases: %+v", trending)
+	}
+	if trending[0].Release != nil {
+		t.Errorf("expected no release for a removed release, got %+v", trending[0].Release)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
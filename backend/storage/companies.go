@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+// Company table names
+const (
+	releaseCompaniesTableName = "release_companies"
+)
+
+// Kinds of release companies. Labels carry the release's catalog number; other companies, such as
+// pressing plants, carry their role.
+const (
+	releaseCompanyKindLabel   = "label"
+	releaseCompanyKindCompany = "company"
+)
+
+// SQL queries for companies
+const (
+	deleteReleaseCompaniesSQL = `
+		DELETE FROM %s WHERE release_id = $1;
+	`
+	insertReleaseCompanySQL = `
+		INSERT INTO %s (release_id, kind, position, company_id, name, role, catno)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);
+	`
+	fetchReleaseCompaniesSQL = `
+		SELECT release_id, kind, COALESCE(company_id, 0), name, COALESCE(role, ''), COALESCE(catno, '')
+		FROM %s
+		WHERE release_id IN (%s)
+		ORDER BY release_id, kind, position
+	`
+	catnoFilterSQL   = `r.id IN (SELECT release_id FROM %s WHERE kind = '%s' AND catno ILIKE $%d)`
+	companyFilterSQL = `r.id IN (SELECT release_id FROM %s co WHERE co.kind = '%s'%s)`
+	// A release is listed under the lowest of its catalog numbers; releases without one come last.
+	// Labels without a catalog number are stored with an empty one.
+	catnoOrderSQL = `(SELECT MIN(NULLIF(rc.catno, '')) FROM %s rc WHERE rc.release_id = r.id AND rc.kind = '%s') NULLS LAST`
+	// Companies are counted per release, so a plant that pressed both discs of a release counts
+	// once for it.
+	fetchCompanyCountsSQL = `
+		SELECT co.name, co.role, COUNT(DISTINCT %s) AS release_count FROM %s co
+		JOIN %s r ON r.id = co.release_id
+		WHERE co.kind = '%s' AND co.release_id IN (%s)%s
+		GROUP BY co.name, co.role
+		ORDER BY release_count DESC, co.name, co.role
+	`
+)
+
+// replaceCompanies swaps the stored labels and companies of a release for the given ones.
+func replaceCompanies(tx *sql.Tx, releaseID int32, labels []models.ReleaseLabel, companies []models.Company) error {
+	if _, err := tx.Exec(fmt.Sprintf(deleteReleaseCompaniesSQL, releaseCompaniesTableName), releaseID); err != nil {
+		return fmt.Errorf("failed to delete from table %s: %v", releaseCompaniesTableName, err)
+	}
+
+	query := fmt.Sprintf(insertReleaseCompanySQL, releaseCompaniesTableName)
+	for position, label := range labels {
+		if _, err := tx.Exec(query, releaseID, releaseCompanyKindLabel, position, nullIfZero(label.Id), label.Name,
+			"", label.Catno); err != nil {
+			return fmt.Errorf("failed to insert into table %s: %v", releaseCompaniesTableName, err)
+		}
+	}
+	for position, company := range companies {
+		if _, err := tx.Exec(query, releaseID, releaseCompanyKindCompany, position, nullIfZero(company.Id), company.Name,
+			company.Role, company.Catno); err != nil {
+			return fmt.Errorf("failed to insert into table %s: %v", releaseCompaniesTableName, err)
+		}
+	}
+	return nil
+}
+
+func fetchReleaseCompanies(db *sql.DB, releases map[int32]*models.Release, placeholders string, args []interface{}) error {
+	rows, err := db.Query(fmt.Sprintf(fetchReleaseCompaniesSQL, releaseCompaniesTableName, placeholders), args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch release companies: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var releaseID int32
+		var kind string
+		var company models.Company
+		if err := rows.Scan(&releaseID, &kind, &company.Id, &company.Name, &company.Role, &company.Catno); err != nil {
+			return fmt.Errorf("failed to scan release company: %v", err)
+		}
+
+		release := releases[releaseID]
+		switch kind {
+		case releaseCompanyKindLabel:
+			release.Labels = append(release.Labels, models.ReleaseLabel{Id: company.Id, Name: company.Name, Catno: company.Catno})
+		case releaseCompanyKindCompany:
+			release.Companies = append(release.Companies, company)
+		}
+	}
+	return rows.Err()
+}
+
+// catnoCondition limits releases to those with a catalog number containing the argument at argIndex.
+func catnoCondition(argIndex int) string {
+	return fmt.Sprintf(catnoFilterSQL, releaseCompaniesTableName, releaseCompanyKindLabel, argIndex)
+}
+
+// catnoOrder orders releases by their catalog number.
+func catnoOrder() string {
+	return fmt.Sprintf(catnoOrderSQL, releaseCompaniesTableName, releaseCompanyKindLabel)
+}
+
+// companyConditions matches companies by role and name, numbering its arguments from argIndex.
+func companyConditions(filter models.CompanyFilter, argIndex int) (string, []interface{}) {
+	var conditions string
+	var args []interface{}
+	if filter.Role != "" {
+		conditions += fmt.Sprintf(" AND co.role ILIKE $%d", argIndex)
+		args = append(args, "%"+filter.Role+"%")
+		argIndex++
+	}
+	if filter.Name != "" {
+		conditions += fmt.Sprintf(" AND co.name ILIKE $%d", argIndex)
+		args = append(args, "%"+filter.Name+"%")
+	}
+	return conditions, args
+}
+
+// companyCondition limits releases to those with a company matching both the role and the name.
+func companyCondition(filter models.CompanyFilter, argIndex int) (string, []interface{}) {
+	conditions, args := companyConditions(filter, argIndex)
+	return fmt.Sprintf(companyFilterSQL, releaseCompaniesTableName, releaseCompanyKindCompany, conditions), args
+}
+
+// fetchCompanyCounts counts the releases selected by filteredIDs per company name and role. Only
+// companies matching the company filter are counted, so the role narrows the counts to, for
+// example, pressing plants.
+func fetchCompanyCounts(db *sql.DB, filteredIDs string, args []interface{}, filter models.CompanyFilter, countUnit string) ([]models.CompanyCount, error) {
+	conditions, companyArgs := companyConditions(filter, len(args)+1)
+	query := fmt.Sprintf(fetchCompanyCountsSQL, countUnit, releaseCompaniesTableName, releasesTableName,
+		releaseCompanyKindCompany, filteredIDs, conditions)
+
+	rows, err := db.Query(query, append(args, companyArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch company counts: %v", err)
+	}
+	defer rows.Close()
+
+	companyCounts := []models.CompanyCount{}
+	for rows.Next() {
+		var companyCount models.CompanyCount
+		if err := rows.Scan(&companyCount.Name, &companyCount.Role, &companyCount.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan company count: %v", err)
+		}
+		companyCounts = append(companyCounts, companyCount)
+	}
+	return companyCounts, rows.Err()
+}
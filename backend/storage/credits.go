@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+// Credit table names
+const (
+	creditsTableName = "credits"
+)
+
+// SQL queries for credits
+const (
+	deleteCreditsSQL = `
+		DELETE FROM %s WHERE release_id = $1;
+	`
+	insertCreditSQL = `
+		INSERT INTO %s (release_id, sequence, artist_id, name, role, tracks)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`
+	fetchReleaseCreditsSQL = `
+		SELECT release_id, COALESCE(artist_id, 0), name, role, COALESCE(tracks, '')
+		FROM %s
+		WHERE release_id IN (%s)
+		ORDER BY release_id, sequence
+	`
+	creditFilterSQL = `r.id IN (SELECT release_id FROM %s c WHERE 1=1%s)`
+	// Credits are counted per release, so a producer credited on several tracks of a release
+	// counts once for it.
+	fetchCreditCountsSQL = `
+		SELECT c.name, c.role, COUNT(DISTINCT %s) AS release_count FROM %s c
+		JOIN %s r ON r.id = c.release_id
+		WHERE c.release_id IN (%s)%s
+		GROUP BY c.name, c.role
+		ORDER BY release_count DESC, c.name, c.role
+	`
+)
+
+// replaceCredits swaps the stored credits of a release for the given ones.
+func replaceCredits(tx *sql.Tx, releaseID int32, credits []models.Credit) error {
+	if _, err := tx.Exec(fmt.Sprintf(deleteCreditsSQL, creditsTableName), releaseID); err != nil {
+		return fmt.Errorf("failed to delete from table %s: %v", creditsTableName, err)
+	}
+
+	query := fmt.Sprintf(insertCreditSQL, creditsTableName)
+	for sequence, credit := range credits {
+		if _, err := tx.Exec(query, releaseID, sequence, nullIfZero(credit.ArtistId), credit.Name, credit.Role, credit.Tracks); err != nil {
+			return fmt.Errorf("failed to insert into table %s: %v", creditsTableName, err)
+		}
+	}
+	return nil
+}
+
+func fetchReleaseCredits(db *sql.DB, releases map[int32]*models.Release, placeholders string, args []interface{}) error {
+	rows, err := db.Query(fmt.Sprintf(fetchReleaseCreditsSQL, creditsTableName, placeholders), args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch release credits: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var releaseID int32
+		var credit models.Credit
+		if err := rows.Scan(&releaseID, &credit.ArtistId, &credit.Name, &credit.Role, &credit.Tracks); err != nil {
+			return fmt.Errorf("failed to scan release credit: %v", err)
+		}
+		release := releases[releaseID]
+		release.Credits = append(release.Credits, credit)
+	}
+	return rows.Err()
+}
+
+// creditConditions matches credits by role and name, numbering its arguments from argIndex.
+func creditConditions(filter models.CreditFilter, argIndex int) (string, []interface{}) {
+	var conditions string
+	var args []interface{}
+	if filter.Role != "" {
+		conditions += fmt.Sprintf(" AND c.role ILIKE $%d", argIndex)
+		args = append(args, "%"+filter.Role+"%")
+		argIndex++
+	}
+	if filter.Name != "" {
+		conditions += fmt.Sprintf(" AND c.name ILIKE $%d", argIndex)
+		args = append(args, "%"+filter.Name+"%")
+	}
+	return conditions, args
+}
+
+// creditCondition limits releases to those with a credit matching both the role and the name.
+func creditCondition(filter models.CreditFilter, argIndex int) (string, []interface{}) {
+	conditions, args := creditConditions(filter, argIndex)
+	return fmt.Sprintf(creditFilterSQL, creditsTableName, conditions), args
+}
+
+// fetchCreditCounts counts the releases selected by filteredIDs per credited name and role. Only
+// credits matching the credit filter are counted.
+func fetchCreditCounts(db *sql.DB, filteredIDs string, args []interface{}, filter models.CreditFilter, countUnit string) ([]models.CreditCount, error) {
+	conditions, creditArgs := creditConditions(filter, len(args)+1)
+	query := fmt.Sprintf(fetchCreditCountsSQL, countUnit, creditsTableName, releasesTableName, filteredIDs, conditions)
+
+	rows, err := db.Query(query, append(args, creditArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch credit counts: %v", err)
+	}
+	defer rows.Close()
+
+	creditCounts := []models.CreditCount{}
+	for rows.Next() {
+		var creditCount models.CreditCount
+		if err := rows.Scan(&creditCount.Name, &creditCount.Role, &creditCount.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan credit count: %v", err)
+		}
+		creditCounts = append(creditCounts, creditCount)
+	}
+	return creditCounts, rows.Err()
+}
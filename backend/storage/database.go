@@ -6,10 +6,20 @@ import (
 	"github.com/LissaGreense/discogs_record_label/backend/models"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
+// Facets of the release counts, as named by fetchReleaseCountsSQL
+const (
+	releaseCountFacetTotal  = "total"
+	releaseCountFacetArtist = "artist"
+	releaseCountFacetStyle  = "style"
+	releaseCountFacetGenre  = "genre"
+)
+
 // Table names
 const (
 	ArtistsTableName  = "artists"
@@ -18,49 +28,99 @@ const (
 	releasesTableName = "releases"
 )
 
-// SQL statements for creating tables
-const (
-	createTableSQL = `
-	CREATE TABLE IF NOT EXISTS %s (
-		%s
-	);`
-	releasesColumnDef   = `id INT PRIMARY KEY`
-	attributesColumnDef = `id SERIAL PRIMARY KEY,
-		release_id INT REFERENCES %s(id) ON DELETE CASCADE,
-		name TEXT NOT NULL`
-)
-
 // SQL queries for insertion and fetching
 const (
+	releaseColumns   = `id, title, year, released, country, data_quality, notes, uri, master_id, fetched_at`
 	insertReleaseSQL = `
-		INSERT INTO %s (id)
-		VALUES ($1)
-		ON CONFLICT (id) DO NOTHING;
+		INSERT INTO %s (` + releaseColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)` + upsertReleaseSQL + `;
+	`
+	upsertReleaseSQL = `
+		ON CONFLICT (id) DO UPDATE SET
+			title = EXCLUDED.title,
+			year = EXCLUDED.year,
+			released = EXCLUDED.released,
+			country = EXCLUDED.country,
+			data_quality = EXCLUDED.data_quality,
+			notes = EXCLUDED.notes,
+			uri = EXCLUDED.uri,
+			master_id = EXCLUDED.master_id,
+			fetched_at = EXCLUDED.fetched_at`
+
+	deleteAttributesSQL = `
+		DELETE FROM %s WHERE release_id = $1;
 	`
 
 	insertAttributeSQL = `
 		INSERT INTO %s (release_id, name)
-		VALUES ($1, $2);
+		VALUES ($1, $2)
+		ON CONFLICT (release_id, name) DO NOTHING;
+	`
+	// fetchReleaseCountsSQL counts the filtered releases in total and per artist, style and genre
+	// in one query. Every count is of distinct releases, or of masters when grouped by master.
+	fetchReleaseCountsSQL = `
+		WITH filtered AS (
+			SELECT r.id, %s AS unit FROM %s r WHERE r.id IN (%s)
+		)
+		SELECT '` + releaseCountFacetTotal + `' AS facet, 0 AS artist_id, '' AS name, COUNT(DISTINCT f.unit) AS release_count
+		FROM filtered f
+		UNION ALL
+		SELECT '` + releaseCountFacetArtist + `', a.artist_id, a.name, COUNT(DISTINCT f.unit) FROM filtered f
+		JOIN %s a ON a.release_id = f.id
+		WHERE 1=1%s
+		GROUP BY a.artist_id, a.name
+		UNION ALL
+		SELECT '` + releaseCountFacetStyle + `', 0, s.name, COUNT(DISTINCT f.unit) FROM filtered f
+		JOIN %s s ON s.release_id = f.id
+		WHERE 1=1%s
+		GROUP BY s.name
+		UNION ALL
+		SELECT '` + releaseCountFacetGenre + `', 0, g.name, COUNT(DISTINCT f.unit) FROM filtered f
+		JOIN %s g ON g.release_id = f.id
+		WHERE 1=1%s
+		GROUP BY g.name
+		ORDER BY facet, release_count DESC, name, artist_id
 	`
-	fetchAttrsNamesSQL = `
-		SELECT
-			COUNT(DISTINCT r.id) as releaseCount,
-			COALESCE(a.name, '') as artistName,
-			COALESCE(s.name, '') as styleName,
-			COALESCE(g.name, '') as genreName
+	artistFilterSQL = ` AND (a.name ILIKE $%d OR a.anv ILIKE $%d)`
+	styleFilterSQL  = ` AND s.name ILIKE $%d`
+	genreFilterSQL  = ` AND g.name ILIKE $%d`
+
+	fetchReleasesSQL = `
+		SELECT r.id, COALESCE(r.title, ''), COALESCE(r.year, 0), COALESCE(r.released, ''),
+			COALESCE(r.country, ''), COALESCE(r.data_quality, ''), COALESCE(r.notes, ''), COALESCE(r.uri, ''),
+			COALESCE(r.master_id, 0)
 		FROM %s r
+	`
+	filteredReleaseIDsSQL = `
+		SELECT r.id FROM %s r
 		LEFT JOIN %s a ON r.id = a.release_id
 		LEFT JOIN %s s ON r.id = s.release_id
 		LEFT JOIN %s g ON r.id = g.release_id
 		WHERE 1=1
 	`
+	fetchReleaseAttributesSQL = `
+		SELECT release_id, name FROM %s WHERE release_id IN (%s) ORDER BY id
+	`
+
+	// Releases with rows in the name-only artists table were stored before artist entities and
+	// are left out, so that the next sync fetches them again.
+	fetchReleaseFetchTimesSQL = `
+		SELECT r.id, r.fetched_at FROM %s r
+		WHERE NOT EXISTS (SELECT 1 FROM %s a WHERE a.release_id = r.id)
+	`
 
 	fetchUniqueNamesSQL = `
 		SELECT DISTINCT name FROM %s ORDER BY name
 	`
+
+	fetchLabelUniqueNamesSQL = `
+		SELECT DISTINCT name FROM %s
+		WHERE release_id IN (SELECT r.id FROM %s r WHERE %s)
+		ORDER BY name
+	`
 )
 
-func InitDatabase() (*sql.DB, error) {
+func openPostgres() (*sql.DB, error) {
 	pgUser := os.Getenv("POSTGRES_USER")
 	pgPassword := os.Getenv("POSTGRES_PASSWORD")
 	pgDB := os.Getenv("POSTGRES_DB")
@@ -80,90 +140,82 @@ func InitDatabase() (*sql.DB, error) {
 	return db, nil
 }
 
-func CreateSchema(db *sql.DB) error {
-	creationFailedMsg := "failed to create %s table: %v"
-
-	if err := createTable(db, releasesColumnDef, releasesTableName); err != nil {
-		return fmt.Errorf(creationFailedMsg, releasesTableName, err)
-	}
-
-	if err := createTable(db, attributesColumnDef, ArtistsTableName, releasesTableName); err != nil {
-		return fmt.Errorf(creationFailedMsg, ArtistsTableName, err)
-	}
-
-	if err := createTable(db, attributesColumnDef, GenresTableName, releasesTableName); err != nil {
-		return fmt.Errorf(creationFailedMsg, GenresTableName, err)
-	}
-
-	if err := createTable(db, attributesColumnDef, StylesTableName, releasesTableName); err != nil {
-		return fmt.Errorf(creationFailedMsg, StylesTableName, err)
-	}
-
-	log.Println("Tables created successfully")
-	return nil
-}
-
-func createTable(db *sql.DB, columnDef string, tableName string, additionalArgs ...any) error {
-	filledColumDefs := fmt.Sprintf(columnDef, additionalArgs...)
-	finalCreateTableStatement := fmt.Sprintf(createTableSQL, tableName, filledColumDefs)
-
-	_, err := db.Exec(finalCreateTableStatement)
-	return err
-}
-
 func StoreRelease(db *sql.DB, release *models.Release) error {
 	tx, err := db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
+	defer tx.Rollback()
 
-	if err := insertRelease(tx, release); err != nil {
+	fetchedAt := time.Now().UTC()
+	if err := insertRelease(tx, release, fetchedAt); err != nil {
 		return err
 	}
 
-	if err := insertAttributes(tx, release.Id, release.Artists, ArtistsTableName); err != nil {
+	if err := replaceReleaseArtists(tx, release.Id, release.Artists); err != nil {
 		return fmt.Errorf("failed to insert artists: %v", err)
 	}
-	if err := insertAttributes(tx, release.Id, release.Genres, GenresTableName); err != nil {
+	if err := replaceAttributes(tx, release.Id, release.Genres, GenresTableName); err != nil {
 		return fmt.Errorf("failed to insert genres: %v", err)
 	}
-	if err := insertAttributes(tx, release.Id, release.Styles, StylesTableName); err != nil {
+	if err := replaceAttributes(tx, release.Id, release.Styles, StylesTableName); err != nil {
 		return fmt.Errorf("failed to insert styles: %v", err)
 	}
+	if err := replaceFormats(tx, release.Id, release.Formats); err != nil {
+		return fmt.Errorf("failed to insert formats: %v", err)
+	}
+	if err := replaceTracks(tx, release.Id, release.Tracks); err != nil {
+		return fmt.Errorf("failed to insert tracks: %v", err)
+	}
+	if err := replaceCredits(tx, release.Id, release.Credits); err != nil {
+		return fmt.Errorf("failed to insert credits: %v", err)
+	}
+	if err := replaceCompanies(tx, release.Id, release.Labels, release.Companies); err != nil {
+		return fmt.Errorf("failed to insert companies: %v", err)
+	}
+	if err := replaceIdentifiers(tx, release.Id, release.Identifiers); err != nil {
+		return fmt.Errorf("failed to insert identifiers: %v", err)
+	}
+	if err := insertCommunitySnapshot(tx, release.Id, release.Community, fetchedAt); err != nil {
+		return fmt.Errorf("failed to insert community statistics: %v", err)
+	}
 
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
-	log.Printf("Stored release ID: %d with artists, genres, and styles", release.Id)
+	log.Printf("Stored release ID: %d with artists, genres, styles, formats, tracks, credits, companies and identifiers", release.Id)
 	return nil
 }
 
-func insertRelease(tx *sql.Tx, release *models.Release) error {
+func insertRelease(tx *sql.Tx, release *models.Release, fetchedAt time.Time) error {
 	releaseQuery := fmt.Sprintf(insertReleaseSQL, releasesTableName)
 
-	_, err := tx.Exec(releaseQuery, release.Id)
+	_, err := tx.Exec(releaseQuery, release.Id, release.Title, nullIfZero(release.Year), release.Released,
+		release.Country, release.DataQuality, release.Notes, release.URI, nullIfZero(release.MasterId), fetchedAt)
 	if err != nil {
-		err := tx.Rollback()
-		if err != nil {
-			return err
-		}
 		return fmt.Errorf("failed to insert release: %v", err)
 	}
 	return nil
 }
 
-func insertAttributes(tx *sql.Tx, releaseID int32, attributes []string, tableName string) error {
+// nullIfZero stores unknown numbers, which Discogs reports as 0, as NULL.
+func nullIfZero(value int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(value), Valid: value != 0}
+}
+
+func replaceAttributes(tx *sql.Tx, releaseID int32, attributes []string, tableName string) error {
+	deleteQuery := fmt.Sprintf(deleteAttributesSQL, tableName)
+
+	if _, err := tx.Exec(deleteQuery, releaseID); err != nil {
+		return fmt.Errorf("failed to delete from table %s: %v", tableName, err)
+	}
+
 	attributeQuery := fmt.Sprintf(insertAttributeSQL, tableName)
 
 	for _, attr := range attributes {
-		_, err := tx.Exec(attributeQuery, releaseID, attr)
-		if err != nil {
-			err := tx.Rollback()
-			if err != nil {
-				return err
-			}
+		if _, err := tx.Exec(attributeQuery, releaseID, attr); err != nil {
 			return fmt.Errorf("failed to insert into table %s: %v", tableName, err)
 		}
 	}
@@ -171,21 +223,39 @@ func insertAttributes(tx *sql.Tx, releaseID int32, attributes []string, tableNam
 	return nil
 }
 
-func FetchReleaseCounts(db *sql.DB, artist, style, genre string) (models.CountResult, error) {
-	query := fmt.Sprintf(fetchAttrsNamesSQL, releasesTableName, ArtistsTableName, StylesTableName, GenresTableName)
+// FetchReleaseCounts counts the releases matching the filter, in total and per artist, style, genre,
+// format, credit and company. With groupByMaster the versions of a master are counted once.
+func FetchReleaseCounts(db *sql.DB, filter models.ReleaseFilter, groupByMaster bool) (models.CountResult, error) {
+	countUnit := releaseCountUnit(groupByMaster)
+	filteredIDs := fmt.Sprintf(filteredReleaseIDsSQL, releasesTableName, releaseArtistNames(), StylesTableName, GenresTableName)
+	args, filteredIDs := createFilterQueries(filter, filteredIDs)
 
-	args, query := createFilterQueries(artist, query, style, genre)
+	artistCondition, styleCondition, genreCondition, facetArgs := releaseFacetConditions(filter, len(args)+1)
+	query := fmt.Sprintf(fetchReleaseCountsSQL, countUnit, releasesTableName, filteredIDs, releaseArtistNames(), artistCondition,
+		StylesTableName, styleCondition, GenresTableName, genreCondition)
 
-	query += " GROUP BY a.name, s.name, g.name"
-
-	rows, err := db.Query(query, args...)
+	rows, err := db.Query(query, append(args, facetArgs...)...)
 	if err != nil {
 		return models.CountResult{}, fmt.Errorf("failed to execute query: %v", err)
 	}
 
 	defer rows.Close()
 
-	countResult, err := fetchReleaseCountFromRows(rows)
+	countResult, err := scanReleaseCounts(rows)
+	if err != nil {
+		return models.CountResult{}, err
+	}
+	rows.Close()
+
+	countResult.FormatCounts, err = fetchFormatCounts(db, filteredIDs, args, countUnit)
+	if err != nil {
+		return models.CountResult{}, err
+	}
+	countResult.CreditCounts, err = fetchCreditCounts(db, filteredIDs, args, filter.Credit, countUnit)
+	if err != nil {
+		return models.CountResult{}, err
+	}
+	countResult.CompanyCounts, err = fetchCompanyCounts(db, filteredIDs, args, filter.Company, countUnit)
 	if err != nil {
 		return models.CountResult{}, err
 	}
@@ -193,84 +263,321 @@ func FetchReleaseCounts(db *sql.DB, artist, style, genre string) (models.CountRe
 	return countResult, nil
 }
 
-func fetchReleaseCountFromRows(rows *sql.Rows) (models.CountResult, error) {
-	artistMap := make(map[string]int)
-	styleMap := make(map[string]int)
-	genreMap := make(map[string]int)
-	totalReleases := 0
+// releaseFacetConditions limits the artist, style and genre counts to the names the filter asks
+// for, so that filtering by a style counts the matching styles rather than every style of the
+// matching releases. Its arguments are numbered from argIndex.
+func releaseFacetConditions(filter models.ReleaseFilter, argIndex int) (string, string, string, []interface{}) {
+	var artistCondition, styleCondition, genreCondition string
+	var args []interface{}
+	if filter.Artist != "" {
+		artistCondition = fmt.Sprintf(artistFilterSQL, argIndex, argIndex)
+		args = append(args, "%"+filter.Artist+"%")
+		argIndex++
+	}
+	if filter.Style != "" {
+		styleCondition = fmt.Sprintf(styleFilterSQL, argIndex)
+		args = append(args, "%"+filter.Style+"%")
+		argIndex++
+	}
+	if filter.Genre != "" {
+		genreCondition = fmt.Sprintf(genreFilterSQL, argIndex)
+		args = append(args, "%"+filter.Genre+"%")
+	}
+	return artistCondition, styleCondition, genreCondition, args
+}
 
-	for rows.Next() {
-		var releaseId int
-		var artistName, styleName, genreName sql.NullString
+// scanReleaseCounts reads the rows of fetchReleaseCountsSQL, which come sorted by count.
+func scanReleaseCounts(rows *sql.Rows) (models.CountResult, error) {
+	countResult := models.CountResult{
+		ArtistCounts: []models.ArtistCount{},
+		StyleCounts:  []models.NameCount{},
+		GenreCounts:  []models.NameCount{},
+	}
 
-		if err := rows.Scan(&releaseId, &artistName, &styleName, &genreName); err != nil {
+	for rows.Next() {
+		var facet, name string
+		var artistID, count int
+		if err := rows.Scan(&facet, &artistID, &name, &count); err != nil {
 			return models.CountResult{}, fmt.Errorf("failed to scan row: %v", err)
 		}
 
-		totalReleases++
-
-		if artistName.Valid && artistName.String != "" {
-			artistMap[artistName.String]++
+		switch facet {
+		case releaseCountFacetTotal:
+			countResult.ReleaseCount = count
+		case releaseCountFacetArtist:
+			countResult.ArtistCounts = append(countResult.ArtistCounts, models.ArtistCount{Id: artistID, Name: name, Count: count})
+		case releaseCountFacetStyle:
+			countResult.StyleCounts = append(countResult.StyleCounts, models.NameCount{Name: name, Count: count})
+		case releaseCountFacetGenre:
+			countResult.GenreCounts = append(countResult.GenreCounts, models.NameCount{Name: name, Count: count})
 		}
+	}
+	return countResult, rows.Err()
+}
 
-		if styleName.Valid && styleName.String != "" {
-			styleMap[styleName.String]++
-		}
+func createFilterQueries(filter models.ReleaseFilter, query string) ([]interface{}, string) {
+	var args []interface{}
+	argIndex := 1
 
-		if genreName.Valid && genreName.String != "" {
-			genreMap[genreName.String]++
-		}
+	if filter.Artist != "" {
+		query += fmt.Sprintf(artistFilterSQL, argIndex, argIndex)
+		args = append(args, "%"+filter.Artist+"%")
+		argIndex++
+	}
+	if filter.Style != "" {
+		query += fmt.Sprintf(styleFilterSQL, argIndex)
+		args = append(args, "%"+filter.Style+"%")
+		argIndex++
 	}
+	if filter.Genre != "" {
+		query += fmt.Sprintf(genreFilterSQL, argIndex)
+		args = append(args, "%"+filter.Genre+"%")
+		argIndex++
+	}
+	if filter.Format != "" {
+		query += " AND " + formatCondition(argIndex)
+		args = append(args, "%"+filter.Format+"%")
+		argIndex++
+	}
+	if filter.Track != "" {
+		query += " AND " + trackCondition(argIndex)
+		args = append(args, "%"+filter.Track+"%")
+		argIndex++
+	}
+	if filter.Credit != (models.CreditFilter{}) {
+		condition, creditArgs := creditCondition(filter.Credit, argIndex)
+		query += " AND " + condition
+		args = append(args, creditArgs...)
+		argIndex += len(creditArgs)
+	}
+	if filter.Company != (models.CompanyFilter{}) {
+		condition, companyArgs := companyCondition(filter.Company, argIndex)
+		query += " AND " + condition
+		args = append(args, companyArgs...)
+		argIndex += len(companyArgs)
+	}
+	if filter.Catno != "" {
+		query += " AND " + catnoCondition(argIndex)
+		args = append(args, "%"+filter.Catno+"%")
+		argIndex++
+	}
+	if filter.Year != 0 {
+		query += fmt.Sprintf(" AND r.year = $%d", argIndex)
+		args = append(args, filter.Year)
+		argIndex++
+	}
+	if filter.Country != "" {
+		query += fmt.Sprintf(" AND r.country ILIKE $%d", argIndex)
+		args = append(args, filter.Country)
+		argIndex++
+	}
+	if filter.MasterId != 0 {
+		query += fmt.Sprintf(" AND r.master_id = $%d", argIndex)
+		args = append(args, filter.MasterId)
+		argIndex++
+	}
+	if filter.LabelId != 0 {
+		query += " AND " + labelCondition(filter.LabelFilter, argIndex)
+		args = append(args, filter.LabelId)
+		argIndex++
+	}
+	return args, query
+}
+
+// FetchRelease returns the stored release with its artists, styles and genres, or nil when the
+// release has not been synced.
+func FetchRelease(db *sql.DB, releaseID int32) (*models.Release, error) {
+	query := fmt.Sprintf(fetchReleasesSQL, releasesTableName) + " WHERE r.id = $1"
+
+	rows, err := db.Query(query, releaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release %d: %v", releaseID, err)
+	}
+	releases, err := scanReleases(db, rows)
+	if err != nil || len(releases) == 0 {
+		return nil, err
+	}
+	return releases[0], nil
+}
+
+// FetchReleases returns a page of the releases matching the filter in the given order. Releases
+// that sort equally are ordered by id.
+func FetchReleases(db *sql.DB, filter models.ReleaseFilter, order models.ReleaseOrder, limit int, offset int) ([]*models.Release, error) {
+	filteredIDs := fmt.Sprintf(filteredReleaseIDsSQL, releasesTableName, releaseArtistNames(), StylesTableName, GenresTableName)
+	args, filteredIDs := createFilterQueries(filter, filteredIDs)
 
-	artistCounts := make([]models.NameCount, 0, len(artistMap))
-	for name, count := range artistMap {
-		artistCounts = append(artistCounts, models.NameCount{Name: name, Count: count})
+	orderBy := "r.id"
+	if order == models.ReleaseOrderCatno {
+		orderBy = catnoOrder() + ", r.id"
 	}
+	query := fmt.Sprintf(fetchReleasesSQL, releasesTableName) +
+		fmt.Sprintf(" WHERE r.id IN (%s) ORDER BY %s LIMIT $%d OFFSET $%d", filteredIDs, orderBy, len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
 
-	styleCounts := make([]models.NameCount, 0, len(styleMap))
-	for name, count := range styleMap {
-		styleCounts = append(styleCounts, models.NameCount{Name: name, Count: count})
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases: %v", err)
 	}
+	return scanReleases(db, rows)
+}
 
-	genreCounts := make([]models.NameCount, 0, len(genreMap))
-	for name, count := range genreMap {
-		genreCounts = append(genreCounts, models.NameCount{Name: name, Count: count})
+// fetchReleasesByIDs returns the releases with the given ids in the same order.
+func fetchReleasesByIDs(db *sql.DB, releaseIDs []int32) ([]*models.Release, error) {
+	if len(releaseIDs) == 0 {
+		return []*models.Release{}, nil
 	}
 
-	countResult := models.CountResult{
-		ReleaseCount: totalReleases,
-		ArtistCounts: artistCounts,
-		StyleCounts:  styleCounts,
-		GenreCounts:  genreCounts,
+	placeholders := make([]string, 0, len(releaseIDs))
+	args := make([]interface{}, 0, len(releaseIDs))
+	for i, releaseID := range releaseIDs {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, releaseID)
 	}
-	return countResult, nil
+
+	query := fmt.Sprintf(fetchReleasesSQL, releasesTableName) + fmt.Sprintf(" WHERE r.id IN (%s)", strings.Join(placeholders, ", "))
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases: %v", err)
+	}
+	releases, err := scanReleases(db, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int32]*models.Release, len(releases))
+	for _, release := range releases {
+		byID[release.Id] = release
+	}
+	ordered := make([]*models.Release, 0, len(releaseIDs))
+	for _, releaseID := range releaseIDs {
+		if release, ok := byID[releaseID]; ok {
+			ordered = append(ordered, release)
+		}
+	}
+	return ordered, nil
 }
 
-func createFilterQueries(artist string, query string, style string, genre string) ([]interface{}, string) {
-	var args []interface{}
-	argIndex := 1
+// scanReleases reads release rows selected by fetchReleasesSQL, closes them and loads the
+// attributes of the releases read.
+func scanReleases(db *sql.DB, rows *sql.Rows) ([]*models.Release, error) {
+	defer rows.Close()
 
-	if artist != "" {
-		query += fmt.Sprintf(" AND a.name ILIKE $%d", argIndex)
-		args = append(args, "%"+artist+"%")
-		argIndex++
+	var releases []*models.Release
+	for rows.Next() {
+		release := &models.Release{}
+		if err := rows.Scan(&release.Id, &release.Title, &release.Year, &release.Released,
+			&release.Country, &release.DataQuality, &release.Notes, &release.URI, &release.MasterId); err != nil {
+			return nil, fmt.Errorf("failed to scan release: %v", err)
+		}
+		releases = append(releases, release)
 	}
-	if style != "" {
-		query += fmt.Sprintf(" AND s.name ILIKE $%d", argIndex)
-		args = append(args, "%"+style+"%")
-		argIndex++
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read releases: %v", err)
 	}
-	if genre != "" {
-		query += fmt.Sprintf(" AND g.name ILIKE $%d", argIndex)
-		args = append(args, "%"+genre+"%")
-		argIndex++
+	rows.Close()
+
+	if len(releases) == 0 {
+		return releases, nil
 	}
-	return args, query
+	if err := fetchReleaseAttributes(db, releases); err != nil {
+		return nil, err
+	}
+	return releases, nil
 }
 
-func FetchUniqueNames(db *sql.DB, tableName string) ([]*models.UniqueName, error) {
-	query := fmt.Sprintf(fetchUniqueNamesSQL, tableName)
+func fetchReleaseAttributes(db *sql.DB, releases []*models.Release) error {
+	byID := make(map[int32]*models.Release, len(releases))
+	placeholders := make([]string, 0, len(releases))
+	args := make([]interface{}, 0, len(releases))
+	for i, release := range releases {
+		byID[release.Id] = release
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, release.Id)
+	}
+	releaseIDs := strings.Join(placeholders, ", ")
+
+	if err := fetchReleaseArtists(db, byID, releaseIDs, args); err != nil {
+		return err
+	}
+
+	for _, tableName := range []string{StylesTableName, GenresTableName} {
+		query := fmt.Sprintf(fetchReleaseAttributesSQL, tableName, releaseIDs)
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to fetch release %s: %v", tableName, err)
+		}
+
+		for rows.Next() {
+			var releaseID int32
+			var name string
+			if err := rows.Scan(&releaseID, &name); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan release %s: %v", tableName, err)
+			}
+
+			release := byID[releaseID]
+			switch tableName {
+			case StylesTableName:
+				release.Styles = append(release.Styles, name)
+			case GenresTableName:
+				release.Genres = append(release.Genres, name)
+			}
+		}
+		rows.Close()
+	}
+
+	if err := fetchReleaseFormats(db, byID, releaseIDs, args); err != nil {
+		return err
+	}
+	if err := fetchReleaseTracks(db, byID, releaseIDs, args); err != nil {
+		return err
+	}
+	if err := fetchReleaseCredits(db, byID, releaseIDs, args); err != nil {
+		return err
+	}
+	if err := fetchReleaseCompanies(db, byID, releaseIDs, args); err != nil {
+		return err
+	}
+	if err := fetchReleaseIdentifiers(db, byID, releaseIDs, args); err != nil {
+		return err
+	}
+	return fetchReleaseCommunity(db, byID, releaseIDs, args)
+}
+
+// FetchReleaseFetchTimes returns when each stored release was last fetched. Releases stored
+// before fetch times were recorded are reported with the zero time.
+func FetchReleaseFetchTimes(db *sql.DB) (map[int32]time.Time, error) {
+	query := fmt.Sprintf(fetchReleaseFetchTimesSQL, releasesTableName, ArtistsTableName)
 	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release fetch times: %v", err)
+	}
+	defer rows.Close()
+
+	fetchedAt := make(map[int32]time.Time)
+	for rows.Next() {
+		var id int32
+		var lastFetch sql.NullTime
+		if err := rows.Scan(&id, &lastFetch); err != nil {
+			return nil, fmt.Errorf("failed to scan release fetch time: %v", err)
+		}
+		fetchedAt[id] = lastFetch.Time
+	}
+
+	return fetchedAt, rows.Err()
+}
+
+// FetchUniqueNames returns the distinct names in an attribute table, limited to the releases of
+// the filtered label unless no label is given.
+func FetchUniqueNames(db *sql.DB, tableName string, labelFilter models.LabelFilter) ([]*models.UniqueName, error) {
+	query := fmt.Sprintf(fetchUniqueNamesSQL, tableName)
+	var args []interface{}
+	if labelFilter.LabelId != 0 {
+		query = fmt.Sprintf(fetchLabelUniqueNamesSQL, tableName, releasesTableName, labelCondition(labelFilter, 1))
+		args = append(args, labelFilter.LabelId)
+	}
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch unique names from %s: %v", tableName, err)
 	}
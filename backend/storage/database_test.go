@@ -1,7 +1,11 @@
 package storage
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/LissaGreense/discogs_record_label/backend/models"
@@ -15,17 +19,52 @@ func TestStoreRelease(t *testing.T) {
 	defer db.Close()
 
 	release := &models.Release{
-		Id:      1,
-		Artists: []string{"Artist 1"},
-		Genres:  []string{"Genre 1"},
-		Styles:  []string{"Style 1"},
+		Id:          1,
+		Title:       "Title 1",
+		Year:        1999,
+		Released:    "1999-03-00",
+		Country:     "UK",
+		Artists:     []models.ReleaseArtist{{Id: 11, Name: "Artist 1 (2)", Anv: "Artist 1", Join: "&"}},
+		Genres:      []string{"Genre 1"},
+		Styles:      []string{"Style 1"},
+		Formats:     []models.Format{{Name: "Vinyl", Qty: 1, Descriptions: []string{`12"`, "LP"}}},
+		Credits:     []models.Credit{{ArtistId: 9, Name: "Engineer 1", Role: "Mastered By"}},
+		Labels:      []models.ReleaseLabel{{Id: 5, Name: "Label 1", Catno: "LBL 001"}},
+		Companies:   []models.Company{{Id: 12, Name: "Plant 1", Role: "Pressed By"}},
+		Identifiers: []models.Identifier{{Type: "Barcode", Value: "5 012345 678900"}},
+		Community:   &models.CommunityStats{Have: 120, Want: 45},
 	}
 
 	mock.ExpectBegin()
-	mock.ExpectExec("INSERT INTO releases").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("INSERT INTO artists").WithArgs(release.Id, release.Artists[0]).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO releases").WithArgs(release.Id, release.Title, sqlmock.AnyArg(), release.Released,
+		release.Country, release.DataQuality, release.Notes, release.URI, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM artists WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM release_artists WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO discogs_artists").WithArgs(11, "Artist 1 (2)", "").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO release_artists").WithArgs(release.Id, 0, 11, "Artist 1", "&").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM genres WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec("INSERT INTO genres").WithArgs(release.Id, release.Genres[0]).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM styles WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec("INSERT INTO styles").WithArgs(release.Id, release.Styles[0]).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM formats WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO formats").WithArgs(release.Id, 0, "Vinyl", sqlmock.AnyArg(), "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec("INSERT INTO format_descriptions").WithArgs(3, `12"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO format_descriptions").WithArgs(3, "LP").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM tracks WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM credits WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO credits").WithArgs(release.Id, 0, sqlmock.AnyArg(), "Engineer 1", "Mastered By", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM release_companies WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO release_companies").WithArgs(release.Id, "label", 0, sqlmock.AnyArg(), "Label 1", "", "LBL 001").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO release_companies").WithArgs(release.Id, "company", 0, sqlmock.AnyArg(), "Plant 1", "Pressed By", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM identifiers WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO identifiers").WithArgs(release.Id, 0, "Barcode", "5 012345 678900", "", "5012345678900").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO community_snapshots").WithArgs(release.Id, sqlmock.AnyArg(), 120, 45, sqlmock.AnyArg(), 0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
 	if err := StoreRelease(db, release); err != nil {
@@ -37,78 +76,191 @@ func TestStoreRelease(t *testing.T) {
 	}
 }
 
-func TestFetchReleaseCounts(t *testing.T) {
+func TestStoreReleaseRollsBackWhenReplacingAttributesFails(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("failed to open mock database: %v", err)
 	}
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"releaseCount", "artistName", "styleName", "genreName"}).
-		AddRow(1, "SomeArtist", "SomeStyle", "SomeGenre").
-		AddRow(2, "SomeArtist", "SomeStyleTwo", "SomeGenreTwo")
-
-	query := `SELECT COUNT\(DISTINCT r.id\) as releaseCount, 
-                    COALESCE\(a.name, ''\) as artistName, 
-                    COALESCE\(s.name, ''\) as styleName, 
-                    COALESCE\(g.name, ''\) as genreName 
-              FROM releases r 
-              LEFT JOIN artists a ON r.id = a.release_id 
-              LEFT JOIN styles s ON r.id = s.release_id 
-              LEFT JOIN genres g ON r.id = g.release_id 
-              WHERE 1=1 
-              AND a.name ILIKE \$1 
-              GROUP BY a.name, s.name, g.name`
+	release := &models.Release{Id: 1, Artists: []models.ReleaseArtist{{Id: 11, Name: "Artist 1"}}}
 
-	mock.ExpectQuery(query).WithArgs("%SomeArtist%").WillReturnRows(rows)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO releases").WithArgs(release.Id, release.Title, sqlmock.AnyArg(), release.Released,
+		release.Country, release.DataQuality, release.Notes, release.URI, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM artists WHERE release_id").WithArgs(release.Id).WillReturnError(fmt.Errorf("connection lost"))
+	mock.ExpectRollback()
 
-	countResult, err := FetchReleaseCounts(db, "SomeArtist", "", "")
-	if err != nil {
-		t.Fatalf("failed to fetch release counts: %v", err)
+	if err := StoreRelease(db, release); err == nil {
+		t.Fatal("expected an error when deleting old artists fails")
 	}
-	if countResult.ReleaseCount != 2 {
-		t.Fatalf("expected release count 2, got %d", countResult.ReleaseCount)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
+}
 
-	if len(countResult.ArtistCounts) != 1 {
-		t.Fatalf("expected 1 ArtistCounts object, got %d", len(countResult.ArtistCounts))
+func TestStoreReleaseRollsBackWhenInsertingGenresFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
 	}
-	if countResult.ArtistCounts[0].Name != "SomeArtist" || countResult.ArtistCounts[0].Count != 2 {
-		t.Errorf("expected ArtistCounts to have name 'SomeArtist' and count 1, got name '%s' and count %d", countResult.ArtistCounts[0].Name, countResult.ArtistCounts[0].Count)
+	defer db.Close()
+
+	release := &models.Release{Id: 1, Genres: []string{"Jazz"}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO releases").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM artists WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM release_artists WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM genres WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO genres").WithArgs(release.Id, "Jazz").WillReturnError(fmt.Errorf("connection lost"))
+	mock.ExpectRollback()
+
+	err = StoreRelease(db, release)
+	if err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Fatalf("expected the genre insert error, got %v", err)
 	}
 
-	if len(countResult.StyleCounts) != 2 {
-		t.Fatalf("expected 2 StyleCounts, got %d", len(countResult.StyleCounts))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
-	expectedStyles := map[string]int{
-		"SomeStyle":    1,
-		"SomeStyleTwo": 1,
+}
+
+func TestStoreReleaseRollsBackWhenAStepFails(t *testing.T) {
+	// The tables StoreRelease clears, in order, before inserting the release's rows into them.
+	tableNames := []string{"artists", "release_artists", "genres", "styles", "formats", "tracks", "credits",
+		"release_companies", "identifiers"}
+	tests := []struct {
+		failingTable string
+		expected     string
+	}{
+		{failingTable: "release_artists", expected: "failed to insert artists"},
+		{failingTable: "credits", expected: "failed to insert credits"},
+		{failingTable: "release_companies", expected: "failed to insert companies"},
+		{failingTable: "identifiers", expected: "failed to insert identifiers"},
 	}
-	for _, style := range countResult.StyleCounts {
-		if count, ok := expectedStyles[style.Name]; ok {
-			if style.Count != count {
-				t.Errorf("expected style '%s' count %d, got %d", style.Name, count, style.Count)
+
+	for _, test := range tests {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to open mock database: %v", err)
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO releases").WillReturnResult(sqlmock.NewResult(1, 1))
+		for _, tableName := range tableNames {
+			deleteExpectation := mock.ExpectExec("DELETE FROM " + tableName + " WHERE release_id").WithArgs(int32(1))
+			if tableName == test.failingTable {
+				deleteExpectation.WillReturnError(fmt.Errorf("connection lost"))
+				break
 			}
-		} else {
-			t.Errorf("unexpected style name '%s'", style.Name)
+			deleteExpectation.WillReturnResult(sqlmock.NewResult(0, 0))
 		}
+		mock.ExpectRollback()
+
+		err = StoreRelease(db, &models.Release{Id: 1})
+		if err == nil || !strings.HasPrefix(err.Error(), test.expected) || !strings.Contains(err.Error(), "connection lost") {
+			t.Errorf("%s: expected %q with the delete error, got %v", test.failingTable, test.expected, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("%s: there were unfulfilled expectations: %s", test.failingTable, err)
+		}
+		db.Close()
+	}
+}
+
+func TestStoreReleaseReportsInsertErrorWhenRollbackFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
 	}
+	defer db.Close()
+
+	release := &models.Release{Id: 1, Formats: []models.Format{{Name: "Vinyl", Qty: 1}}}
 
-	if len(countResult.GenreCounts) != 2 {
-		t.Fatalf("expected 2 GenreCounts, got %d", len(countResult.GenreCounts))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO releases").WillReturnResult(sqlmock.NewResult(1, 1))
+	for _, tableName := range []string{"artists", "release_artists", "genres", "styles", "formats"} {
+		mock.ExpectExec("DELETE FROM " + tableName + " WHERE release_id").WithArgs(release.Id).WillReturnResult(sqlmock.NewResult(0, 0))
 	}
-	expectedGenres := map[string]int{
-		"SomeGenre":    1,
-		"SomeGenreTwo": 1,
+	mock.ExpectQuery("INSERT INTO formats").WillReturnError(fmt.Errorf("value too long"))
+	mock.ExpectRollback().WillReturnError(fmt.Errorf("connection lost"))
+
+	err = StoreRelease(db, release)
+	if err == nil || !strings.Contains(err.Error(), "failed to insert formats") || !strings.Contains(err.Error(), "value too long") {
+		t.Fatalf("expected the format insert error, got %v", err)
 	}
-	for _, genre := range countResult.GenreCounts {
-		if count, ok := expectedGenres[genre.Name]; ok {
-			if genre.Count != count {
-				t.Errorf("expected genre '%s' count %d, got %d", genre.Name, count, genre.Count)
-			}
-		} else {
-			t.Errorf("unexpected genre name '%s'", genre.Name)
-		}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchReleaseCounts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"facet", "artist_id", "name", "release_count"}).
+		AddRow("artist", 11, "SomeArtist", 2).
+		AddRow("genre", 0, "SomeGenre", 2).
+		AddRow("genre", 0, "SomeGenreTwo", 1).
+		AddRow("style", 0, "SomeStyle", 2).
+		AddRow("style", 0, "SomeStyleTwo", 1).
+		AddRow("total", 0, "", 2)
+
+	query := `WITH filtered AS \( SELECT r.id, r.id AS unit FROM releases r WHERE r.id IN \( .* WHERE 1=1 AND \(a.name ILIKE \$1 OR a.anv ILIKE \$1\)\) \)
+              SELECT 'total' AS facet, 0 AS artist_id, '' AS name, COUNT\(DISTINCT f.unit\) AS release_count FROM filtered f
+              UNION ALL
+              SELECT 'artist', a.artist_id, a.name, COUNT\(DISTINCT f.unit\) FROM filtered f
+              JOIN \(SELECT ra.release_id, ra.artist_id, COALESCE\(ra.anv, ''\) AS anv, da.name
+                    FROM release_artists ra JOIN discogs_artists da ON da.id = ra.artist_id\) a ON a.release_id = f.id
+              WHERE 1=1 AND \(a.name ILIKE \$2 OR a.anv ILIKE \$2\)
+              GROUP BY a.artist_id, a.name
+              UNION ALL
+              SELECT 'style', 0, s.name, COUNT\(DISTINCT f.unit\) FROM filtered f
+              JOIN styles s ON s.release_id = f.id
+              WHERE 1=1
+              GROUP BY s.name
+              UNION ALL
+              SELECT 'genre', 0, g.name, COUNT\(DISTINCT f.unit\) FROM filtered f
+              JOIN genres g ON g.release_id = f.id
+              WHERE 1=1
+              GROUP BY g.name
+              ORDER BY facet, release_count DESC, name, artist_id`
+
+	mock.ExpectQuery(query).WithArgs("%SomeArtist%", "%SomeArtist%").WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT term, COUNT\(DISTINCT r.id\) AS release_count FROM \( SELECT f.release_id, f.name AS term FROM formats f .* JOIN releases r ON r.id = terms.release_id WHERE r.id IN \( .* WHERE 1=1 AND \(a.name ILIKE \$1 OR a.anv ILIKE \$1\)\) GROUP BY term ORDER BY release_count DESC, term`).
+		WithArgs("%SomeArtist%").
+		WillReturnRows(sqlmock.NewRows([]string{"term", "count"}).AddRow("Vinyl", 2).AddRow(`12"`, 1))
+	mock.ExpectQuery("SELECT c.name, c.role").WithArgs("%SomeArtist%").WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+	mock.ExpectQuery("SELECT co.name, co.role").WithArgs("%SomeArtist%").WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+
+	countResult, err := FetchReleaseCounts(db, models.ReleaseFilter{Artist: "SomeArtist"}, false)
+	if err != nil {
+		t.Fatalf("failed to fetch release counts: %v", err)
+	}
+	if countResult.ReleaseCount != 2 {
+		t.Fatalf("expected release count 2, got %d", countResult.ReleaseCount)
+	}
+
+	expectedArtists := []models.ArtistCount{{Id: 11, Name: "SomeArtist", Count: 2}}
+	if !reflect.DeepEqual(countResult.ArtistCounts, expectedArtists) {
+		t.Errorf("expected artist counts %v, got %v", expectedArtists, countResult.ArtistCounts)
+	}
+	expectedStyles := []models.NameCount{{Name: "SomeStyle", Count: 2}, {Name: "SomeStyleTwo", Count: 1}}
+	if !reflect.DeepEqual(countResult.StyleCounts, expectedStyles) {
+		t.Errorf("expected style counts %v, got %v", expectedStyles, countResult.StyleCounts)
+	}
+	expectedGenres := []models.NameCount{{Name: "SomeGenre", Count: 2}, {Name: "SomeGenreTwo", Count: 1}}
+	if !reflect.DeepEqual(countResult.GenreCounts, expectedGenres) {
+		t.Errorf("expected genre counts %v, got %v", expectedGenres, countResult.GenreCounts)
+	}
+	if len(countResult.FormatCounts) != 2 || countResult.FormatCounts[0].Name != "Vinyl" || countResult.FormatCounts[0].Count != 2 {
+		t.Errorf("unexpected FormatCounts %v", countResult.FormatCounts)
 	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -130,7 +282,7 @@ func TestFetchUniqueNames(t *testing.T) {
 
 	mock.ExpectQuery("SELECT DISTINCT name FROM artists ORDER BY name").WillReturnRows(rows)
 
-	uniqueNames, err := FetchUniqueNames(db, ArtistsTableName)
+	uniqueNames, err := FetchUniqueNames(db, ArtistsTableName, models.LabelFilter{})
 	if err != nil {
 		t.Fatalf("failed to fetch unique names: %v", err)
 	}
@@ -142,3 +294,198 @@ func TestFetchUniqueNames(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestFetchReleaseFetchTimes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	fetchedAt := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "fetched_at"}).
+		AddRow(1, fetchedAt).
+		AddRow(2, nil)
+
+	mock.ExpectQuery("SELECT r.id, r.fetched_at FROM releases r WHERE NOT EXISTS \\(SELECT 1 FROM artists a").WillReturnRows(rows)
+
+	fetchTimes, err := FetchReleaseFetchTimes(db)
+	if err != nil {
+		t.Fatalf("failed to fetch release fetch times: %v", err)
+	}
+	if len(fetchTimes) != 2 {
+		t.Fatalf("expected 2 fetch times, got %d", len(fetchTimes))
+	}
+	if !fetchTimes[1].Equal(fetchedAt) {
+		t.Errorf("expected release 1 fetched at %v, got %v", fetchedAt, fetchTimes[1])
+	}
+	if !fetchTimes[2].IsZero() {
+		t.Errorf("expected release 2 without fetch time, got %v", fetchTimes[2])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchRelease(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT r.id, COALESCE\\(r.title, ''\\).* FROM releases r WHERE r.id = \\$1").
+		WithArgs(int32(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "year", "released", "country", "data_quality", "notes", "uri", "master_id"}).
+			AddRow(1, "Title 1", 1999, "1999-03-00", "UK", "Correct", "", "https://www.discogs.com/release/1", 0))
+	mock.ExpectQuery("FROM release_artists ra JOIN discogs_artists da ON da.id = ra.artist_id WHERE ra.release_id IN \\(\\$1\\) ORDER BY ra.release_id, ra.position").
+		WithArgs(int32(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "id", "name", "anv", "join_phrase", "resource_url"}).
+			AddRow(1, 11, "Artist 1 (2)", "Artist 1", "&", "").
+			AddRow(1, 12, "Artist 2", "", "", ""))
+	mock.ExpectQuery("SELECT release_id, name FROM styles").
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "name"}).AddRow(1, "Style 1"))
+	mock.ExpectQuery("SELECT release_id, name FROM genres").
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "name"}))
+	mock.ExpectQuery("FROM formats f LEFT JOIN format_descriptions d ON d.format_id = f.id WHERE f.release_id IN \\(\\$1\\)").
+		WithArgs(int32(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "id", "name", "qty", "text", "description"}).
+			AddRow(1, 3, "Vinyl", 2, "", `12"`).
+			AddRow(1, 3, "Vinyl", 2, "", "LP").
+			AddRow(1, 4, "CD", 1, "Bonus", ""))
+	mock.ExpectQuery("FROM tracks t LEFT JOIN track_artists ta ON ta.track_id = t.id WHERE t.release_id IN \\(\\$1\\)").
+		WithArgs(int32(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "id", "position", "type", "title", "duration", "duration_seconds", "name", "role"}).
+			AddRow(1, 8, "A1", "track", "Intro", "1:05", 65, "", "").
+			AddRow(1, 9, "A2", "track", "Song", "4:00", 240, "Artist 2", "").
+			AddRow(1, 9, "A2", "track", "Song", "4:00", 240, "Remixer", "Remix"))
+	mock.ExpectQuery("SELECT release_id, COALESCE\\(artist_id, 0\\), name, role, COALESCE\\(tracks, ''\\) FROM credits").
+		WithArgs(int32(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "artist_id", "name", "role", "tracks"}).
+			AddRow(1, 9, "Engineer 1", "Mastered By", ""))
+	mock.ExpectQuery("SELECT release_id, kind, COALESCE\\(company_id, 0\\), name, COALESCE\\(role, ''\\), COALESCE\\(catno, ''\\) FROM release_companies").
+		WithArgs(int32(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "kind", "company_id", "name", "role", "catno"}).
+			AddRow(1, "company", 12, "Plant 1", "Pressed By", "").
+			AddRow(1, "label", 5, "Label 1", "", "LBL 001"))
+	mock.ExpectQuery("SELECT release_id, type, value, COALESCE\\(description, ''\\) FROM identifiers").
+		WithArgs(int32(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "type", "value", "description"}).
+			AddRow(1, "Matrix / Runout", "LBL-001-A", "Side A"))
+	mock.ExpectQuery("FROM community_snapshots cs WHERE cs.release_id IN \\(\\$1\\) AND cs.captured_at = \\(SELECT MAX").
+		WithArgs(int32(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "captured_at", "have", "want", "rating_average", "rating_count"}).
+			AddRow(1, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), 120, 45, 4.5, 10))
+
+	release, err := FetchRelease(db, 1)
+	if err != nil {
+		t.Fatalf("failed to fetch release: %v", err)
+	}
+	if release.Title != "Title 1" || release.Year != 1999 || release.Country != "UK" {
+		t.Errorf("unexpected release metadata: %+v", release)
+	}
+	if len(release.Artists) != 2 || release.Artists[0].Anv != "Artist 1" || len(release.Styles) != 1 || len(release.Genres) != 0 {
+		t.Errorf("unexpected release attributes: %+v", release)
+	}
+	if len(release.Formats) != 2 || len(release.Formats[0].Descriptions) != 2 || release.Formats[1].Descriptions != nil {
+		t.Errorf("unexpected release formats: %+v", release.Formats)
+	}
+	if len(release.Credits) != 1 || release.Credits[0].Role != "Mastered By" {
+		t.Errorf("unexpected release credits: %+v", release.Credits)
+	}
+	if len(release.Labels) != 1 || release.Labels[0].Catno != "LBL 001" {
+		t.Errorf("unexpected release labels: %+v", release.Labels)
+	}
+	if len(release.Companies) != 1 || release.Companies[0].Role != "Pressed By" {
+		t.Errorf("unexpected release companies: %+v", release.Companies)
+	}
+	if len(release.Identifiers) != 1 || release.Identifiers[0].Description != "Side A" {
+		t.Errorf("unexpected release identifiers: %+v", release.Identifiers)
+	}
+	if release.Community == nil || release.Community.Want != 45 || release.Community.RatingAverage != 4.5 {
+		t.Errorf("unexpected release community statistics: %+v", release.Community)
+	}
+	if len(release.Tracks) != 2 || release.Tracks[0].Artists != nil || len(release.Tracks[1].Artists) != 2 {
+		t.Errorf("unexpected release tracks: %+v", release.Tracks)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchReleaseNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM releases r WHERE r.id = \\$1").
+		WithArgs(int32(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "year", "released", "country", "data_quality", "notes", "uri", "master_id"}))
+
+	release, err := FetchRelease(db, 2)
+	if err != nil {
+		t.Fatalf("failed to fetch release: %v", err)
+	}
+	if release != nil {
+		t.Errorf("expected no release, got %+v", release)
+	}
+}
+
+func TestFetchReleases(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM releases r WHERE r.id IN \\(.* AND r.year = \\$1 AND r.country ILIKE \\$2\\) ORDER BY r.id LIMIT \\$3 OFFSET \\$4").
+		WithArgs(1999, "UK", 10, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "year", "released", "country", "data_quality", "notes", "uri", "master_id"}).
+			AddRow(1, "Title 1", 1999, "", "UK", "", "", "", 0).
+			AddRow(2, "Title 2", 1999, "", "UK", "", "", "", 0))
+	mock.ExpectQuery("FROM release_artists ra").
+		WithArgs(int32(1), int32(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "id", "name", "anv", "join_phrase", "resource_url"}).AddRow(2, 11, "Artist", "", "", ""))
+	for _, tableName := range []string{"styles", "genres"} {
+		mock.ExpectQuery("SELECT release_id, name FROM "+tableName+" WHERE release_id IN \\(\\$1, \\$2\\)").
+			WithArgs(int32(1), int32(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"release_id", "name"}).AddRow(2, "Name"))
+	}
+	mock.ExpectQuery("FROM formats f").
+		WithArgs(int32(1), int32(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "id", "name", "qty", "text", "description"}))
+	mock.ExpectQuery("FROM tracks t").
+		WithArgs(int32(1), int32(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "id", "position", "type", "title", "duration", "duration_seconds", "name", "role"}))
+	mock.ExpectQuery("FROM credits").
+		WithArgs(int32(1), int32(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "artist_id", "name", "role", "tracks"}))
+	mock.ExpectQuery("FROM release_companies").
+		WithArgs(int32(1), int32(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "kind", "company_id", "name", "role", "catno"}))
+	mock.ExpectQuery("FROM identifiers").
+		WithArgs(int32(1), int32(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "type", "value", "description"}))
+	mock.ExpectQuery("FROM community_snapshots").
+		WithArgs(int32(1), int32(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"release_id", "captured_at", "have", "want", "rating_average", "rating_count"}))
+
+	releases, err := FetchReleases(db, models.ReleaseFilter{Year: 1999, Country: "UK"}, models.ReleaseOrderId, 10, 20)
+	if err != nil {
+		t.Fatalf("failed to fetch releases: %v", err)
+	}
+	if len(releases) != 2 {
+		t.Fatalf("expected 2 releases, got %d", len(releases))
+	}
+	if len(releases[0].Artists) != 0 || len(releases[1].Artists) != 1 || len(releases[1].Genres) != 1 {
+		t.Errorf("attributes were assigned to the wrong releases: %+v %+v", releases[0], releases[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
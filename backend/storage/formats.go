@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+// Format table names
+const (
+	formatsTableName            = "formats"
+	formatDescriptionsTableName = "format_descriptions"
+)
+
+// SQL queries for formats
+const (
+	deleteFormatsSQL = `
+		DELETE FROM %s WHERE release_id = $1;
+	`
+	insertFormatSQL = `
+		INSERT INTO %s (release_id, position, name, qty, text)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id;
+	`
+	insertFormatDescriptionSQL = `
+		INSERT INTO %s (format_id, description)
+		VALUES ($1, $2)
+		ON CONFLICT (format_id, description) DO NOTHING;
+	`
+	fetchReleaseFormatsSQL = `
+		SELECT f.release_id, f.id, f.name, COALESCE(f.qty, 0), COALESCE(f.text, ''), COALESCE(d.description, '')
+		FROM %s f
+		LEFT JOIN %s d ON d.format_id = f.id
+		WHERE f.release_id IN (%s)
+		ORDER BY f.release_id, f.position, d.description
+	`
+	formatFilterSQL = `r.id IN (
+			SELECT f.release_id FROM %s f
+			LEFT JOIN %s d ON d.format_id = f.id
+			WHERE f.name ILIKE $%d OR d.description ILIKE $%d
+		)`
+	// A format term is either the name of a format, such as Vinyl, or one of its descriptions,
+	// such as 12" or Reissue.
+	fetchFormatCountsSQL = `
+		SELECT term, COUNT(DISTINCT %s) AS release_count FROM (
+			SELECT f.release_id, f.name AS term FROM %s f
+			UNION ALL
+			SELECT f.release_id, d.description AS term FROM %s f JOIN %s d ON d.format_id = f.id
+		) terms
+		JOIN %s r ON r.id = terms.release_id
+		WHERE r.id IN (%s)
+		GROUP BY term
+		ORDER BY release_count DESC, term
+	`
+)
+
+// replaceFormats swaps the stored formats of a release, with their descriptions, for the given
+// ones.
+func replaceFormats(tx *sql.Tx, releaseID int32, formats []models.Format) error {
+	if _, err := tx.Exec(fmt.Sprintf(deleteFormatsSQL, formatsTableName), releaseID); err != nil {
+		return fmt.Errorf("failed to delete from table %s: %v", formatsTableName, err)
+	}
+
+	formatQuery := fmt.Sprintf(insertFormatSQL, formatsTableName)
+	descriptionQuery := fmt.Sprintf(insertFormatDescriptionSQL, formatDescriptionsTableName)
+
+	for position, format := range formats {
+		var formatID int
+		if err := tx.QueryRow(formatQuery, releaseID, position, format.Name, nullIfZero(format.Qty), format.Text).Scan(&formatID); err != nil {
+			return fmt.Errorf("failed to insert into table %s: %v", formatsTableName, err)
+		}
+
+		for _, description := range format.Descriptions {
+			if _, err := tx.Exec(descriptionQuery, formatID, description); err != nil {
+				return fmt.Errorf("failed to insert into table %s: %v", formatDescriptionsTableName, err)
+			}
+		}
+	}
+	return nil
+}
+
+func fetchReleaseFormats(db *sql.DB, releases map[int32]*models.Release, placeholders string, args []interface{}) error {
+	query := fmt.Sprintf(fetchReleaseFormatsSQL, formatsTableName, formatDescriptionsTableName, placeholders)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch release formats: %v", err)
+	}
+	defer rows.Close()
+
+	lastFormatID := 0
+	for rows.Next() {
+		var releaseID int32
+		var formatID int
+		var format models.Format
+		var description string
+		if err := rows.Scan(&releaseID, &formatID, &format.Name, &format.Qty, &format.Text, &description); err != nil {
+			return fmt.Errorf("failed to scan release format: %v", err)
+		}
+
+		release := releases[releaseID]
+		if formatID != lastFormatID {
+			release.Formats = append(release.Formats, format)
+			lastFormatID = formatID
+		}
+		if description != "" {
+			last := &release.Formats[len(release.Formats)-1]
+			last.Descriptions = append(last.Descriptions, description)
+		}
+	}
+	return rows.Err()
+}
+
+func formatCondition(argIndex int) string {
+	return fmt.Sprintf(formatFilterSQL, formatsTableName, formatDescriptionsTableName, argIndex, argIndex)
+}
+
+// fetchFormatCounts counts the releases selected by filteredIDs per format name and description.
+func fetchFormatCounts(db *sql.DB, filteredIDs string, args []interface{}, countUnit string) ([]models.NameCount, error) {
+	query := fmt.Sprintf(fetchFormatCountsSQL, countUnit, formatsTableName, formatsTableName, formatDescriptionsTableName,
+		releasesTableName, filteredIDs)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch format counts: %v", err)
+	}
+	defer rows.Close()
+
+	formatCounts := []models.NameCount{}
+	for rows.Next() {
+		var formatCount models.NameCount
+		if err := rows.Scan(&formatCount.Name, &formatCount.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan format count: %v", err)
+		}
+		formatCounts = append(formatCounts, formatCount)
+	}
+	return formatCounts, rows.Err()
+}
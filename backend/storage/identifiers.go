@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"strings"
+	"unicode"
+)
+
+// Identifier table names
+const (
+	identifiersTableName = "identifiers"
+)
+
+// SQL queries for identifiers
+const (
+	deleteIdentifiersSQL = `
+		DELETE FROM %s WHERE release_id = $1;
+	`
+	insertIdentifierSQL = `
+		INSERT INTO %s (release_id, position, type, value, description, normalized_value)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`
+	fetchReleaseIdentifiersSQL = `
+		SELECT release_id, type, value, COALESCE(description, '')
+		FROM %s
+		WHERE release_id IN (%s)
+		ORDER BY release_id, position
+	`
+	identifierFilterSQL = `r.id IN (SELECT release_id FROM %s i WHERE i.normalized_value = $1%s)`
+)
+
+// normalizeIdentifier reduces an identifier to the characters that tell copies apart, so a
+// scanned "0 7464-40566 1 8" matches the stored "074644056618". Whitespace and dashes are
+// dropped and letters upper-cased.
+func normalizeIdentifier(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.Is(unicode.Pd, r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, value)
+}
+
+// replaceIdentifiers swaps the stored identifiers of a release for the given ones.
+func replaceIdentifiers(tx *sql.Tx, releaseID int32, identifiers []models.Identifier) error {
+	if _, err := tx.Exec(fmt.Sprintf(deleteIdentifiersSQL, identifiersTableName), releaseID); err != nil {
+		return fmt.Errorf("failed to delete from table %s: %v", identifiersTableName, err)
+	}
+
+	query := fmt.Sprintf(insertIdentifierSQL, identifiersTableName)
+	for position, identifier := range identifiers {
+		if _, err := tx.Exec(query, releaseID, position, identifier.Type, identifier.Value, identifier.Description,
+			normalizeIdentifier(identifier.Value)); err != nil {
+			return fmt.Errorf("failed to insert into table %s: %v", identifiersTableName, err)
+		}
+	}
+	return nil
+}
+
+func fetchReleaseIdentifiers(db *sql.DB, releases map[int32]*models.Release, placeholders string, args []interface{}) error {
+	rows, err := db.Query(fmt.Sprintf(fetchReleaseIdentifiersSQL, identifiersTableName, placeholders), args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch release identifiers: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var releaseID int32
+		var identifier models.Identifier
+		if err := rows.Scan(&releaseID, &identifier.Type, &identifier.Value, &identifier.Description); err != nil {
+			return fmt.Errorf("failed to scan release identifier: %v", err)
+		}
+		release := releases[releaseID]
+		release.Identifiers = append(release.Identifiers, identifier)
+	}
+	return rows.Err()
+}
+
+// FetchReleasesByIdentifier returns the releases with an identifier matching the value once both are
+// normalized, ordered by id. An identifier type, such as "Barcode", narrows the match to identifiers
+// of that type. Several releases can match, for example represses sharing a barcode.
+func FetchReleasesByIdentifier(db *sql.DB, value string, identifierType string) ([]*models.Release, error) {
+	args := []interface{}{normalizeIdentifier(value)}
+	var typeCondition string
+	if identifierType != "" {
+		args = append(args, identifierType)
+		typeCondition = " AND i.type ILIKE $2"
+	}
+
+	condition := fmt.Sprintf(identifierFilterSQL, identifiersTableName, typeCondition)
+	query := fmt.Sprintf(fetchReleasesSQL, releasesTableName) + " WHERE " + condition + " ORDER BY r.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases by identifier: %v", err)
+	}
+	return scanReleases(db, rows)
+}
@@ -0,0 +1,19 @@
+package storage
+
+import "testing"
+
+func TestNormalizeIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"0 7464-40566 1 8":   "074644056618",
+		"blp-1577-a  rvg":    "BLP1577ARVG",
+		"LBL 001 – A":        "LBL001A",
+		"\t5012345678900\n":  "5012345678900",
+		"Matrix / Runout: 1": "MATRIX/RUNOUT:1",
+	}
+
+	for value, expected := range tests {
+		if normalized := normalizeIdentifier(value); normalized != expected {
+			t.Errorf("normalizeIdentifier(%q) = %q, expected %q", value, normalized, expected)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+// Label table names
+const (
+	labelsTableName        = "labels"
+	labelReleasesTableName = "label_releases"
+)
+
+// SQL queries for labels
+const (
+	upsertLabelSQL = `
+		INSERT INTO %s (id, name, parent_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, parent_id = COALESCE(EXCLUDED.parent_id, %s.parent_id);
+	`
+	insertLabelReleaseSQL = `
+		INSERT INTO %s (label_id, release_id)
+		VALUES ($1, $2)
+		ON CONFLICT (label_id, release_id) DO NOTHING;
+	`
+	fetchLabelsSQL = `
+		SELECT id, name, parent_id FROM %s
+	`
+	labelFilterSQL              = `r.id IN (SELECT release_id FROM %s WHERE label_id = $%d)`
+	labelWithSublabelsFilterSQL = `r.id IN (SELECT release_id FROM %s WHERE label_id IN (
+			WITH RECURSIVE label_tree(id) AS (
+				SELECT CAST($%d AS INT)
+				UNION
+				SELECT l.id FROM %s l JOIN label_tree t ON l.parent_id = t.id
+			)
+			SELECT id FROM label_tree
+		))`
+)
+
+// StoreLabel creates or renames a label. A known parent is kept when the label is stored again
+// without one.
+func StoreLabel(db *sql.DB, label *models.Label) error {
+	query := fmt.Sprintf(upsertLabelSQL, labelsTableName, labelsTableName)
+	if _, err := db.Exec(query, label.Id, label.Name, label.ParentId); err != nil {
+		return fmt.Errorf("failed to store label %d: %v", label.Id, err)
+	}
+	return nil
+}
+
+func insertLabelReleases(tx *sql.Tx, labelID int, releaseIDs []int32) error {
+	query := fmt.Sprintf(insertLabelReleaseSQL, labelReleasesTableName)
+	for _, releaseID := range releaseIDs {
+		if _, err := tx.Exec(query, labelID, releaseID); err != nil {
+			return fmt.Errorf("failed to link release %d to label %d: %v", releaseID, labelID, err)
+		}
+	}
+	return nil
+}
+
+// FetchLabels returns the stored labels, or only the direct sublabels of parentID if it is not zero.
+func FetchLabels(db *sql.DB, parentID int) ([]*models.Label, error) {
+	query := fmt.Sprintf(fetchLabelsSQL, labelsTableName)
+	var args []interface{}
+	if parentID != 0 {
+		query += " WHERE parent_id = $1"
+		args = append(args, parentID)
+	}
+	query += " ORDER BY name"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch labels: %v", err)
+	}
+	defer rows.Close()
+
+	var labels []*models.Label
+	for rows.Next() {
+		var label models.Label
+		var labelParentID sql.NullInt64
+		if err := rows.Scan(&label.Id, &label.Name, &labelParentID); err != nil {
+			return nil, fmt.Errorf("failed to scan label: %v", err)
+		}
+		if labelParentID.Valid {
+			parent := int(labelParentID.Int64)
+			label.ParentId = &parent
+		}
+		labels = append(labels, &label)
+	}
+
+	return labels, rows.Err()
+}
+
+// labelCondition restricts the releases aliased as r to the ones listed by the label and, if
+// requested, by any of its sublabels. The label id is expected as argument argIndex.
+func labelCondition(filter models.LabelFilter, argIndex int) string {
+	if filter.IncludeSublabels {
+		return fmt.Sprintf(labelWithSublabelsFilterSQL, labelReleasesTableName, argIndex, labelsTableName)
+	}
+	return fmt.Sprintf(labelFilterSQL, labelReleasesTableName, argIndex)
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+func TestStoreLabel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	parentID := 5
+	mock.ExpectExec("INSERT INTO labels").WithArgs(5, "Tone Addiction", nil).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO labels").WithArgs(6, "Tone Addiction Jazz", &parentID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := StoreLabel(db, &models.Label{Id: 5, Name: "Tone Addiction"}); err != nil {
+		t.Fatalf("failed to store label: %v", err)
+	}
+	if err := StoreLabel(db, &models.Label{Id: 6, Name: "Tone Addiction Jazz", ParentId: &parentID}); err != nil {
+		t.Fatalf("failed to store sublabel: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchReleaseCountsByLabel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"facet", "artist_id", "name", "release_count"}).
+		AddRow("artist", 11, "SomeArtist", 1).
+		AddRow("genre", 0, "SomeGenre", 1).
+		AddRow("style", 0, "SomeStyle", 1).
+		AddRow("total", 0, "", 1)
+
+	mock.ExpectQuery(`AND s.name ILIKE \$1 AND r.id IN \(SELECT release_id FROM label_releases WHERE label_id = \$2\)\) \) .* JOIN styles s ON s.release_id = f.id WHERE 1=1 AND s.name ILIKE \$3`).
+		WithArgs("%SomeStyle%", 5, "%SomeStyle%").
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT term, COUNT").WithArgs("%SomeStyle%", 5).WillReturnRows(sqlmock.NewRows([]string{"term", "count"}))
+	mock.ExpectQuery("SELECT c.name, c.role").WithArgs("%SomeStyle%", 5).WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+	mock.ExpectQuery("SELECT co.name, co.role").WithArgs("%SomeStyle%", 5).WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+
+	countResult, err := FetchReleaseCounts(db, models.ReleaseFilter{Style: "SomeStyle", LabelFilter: models.LabelFilter{LabelId: 5}}, false)
+	if err != nil {
+		t.Fatalf("failed to fetch release counts: %v", err)
+	}
+	if countResult.ReleaseCount != 1 {
+		t.Errorf("expected release count 1, got %d", countResult.ReleaseCount)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchUniqueNamesByLabel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"name"}).AddRow("Rock")
+
+	mock.ExpectQuery(`SELECT DISTINCT name FROM styles WHERE release_id IN \(SELECT r.id FROM releases r WHERE r.id IN \(SELECT release_id FROM label_releases WHERE label_id = \$1\)\)`).
+		WithArgs(5).
+		WillReturnRows(rows)
+
+	uniqueNames, err := FetchUniqueNames(db, StylesTableName, models.LabelFilter{LabelId: 5})
+	if err != nil {
+		t.Fatalf("failed to fetch unique names: %v", err)
+	}
+	if len(uniqueNames) != 1 || uniqueNames[0].Name != "Rock" {
+		t.Errorf("expected only style 'Rock', got %v", uniqueNames)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchReleaseCountsByLabelWithSublabels(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"facet", "artist_id", "name", "release_count"}).
+		AddRow("artist", 11, "SomeArtist", 1).
+		AddRow("genre", 0, "SomeGenre", 1).
+		AddRow("style", 0, "SomeStyle", 1).
+		AddRow("total", 0, "", 1)
+
+	mock.ExpectQuery(`WITH RECURSIVE label_tree\(id\) AS \( SELECT CAST\(\$1 AS INT\) UNION SELECT l.id FROM labels l JOIN label_tree t ON l.parent_id = t.id \)`).
+		WithArgs(5).
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT term, COUNT").WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"term", "count"}))
+	mock.ExpectQuery("SELECT c.name, c.role").WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+	mock.ExpectQuery("SELECT co.name, co.role").WithArgs(5).WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+
+	filter := models.ReleaseFilter{LabelFilter: models.LabelFilter{LabelId: 5, IncludeSublabels: true}}
+	if _, err := FetchReleaseCounts(db, filter, false); err != nil {
+		t.Fatalf("failed to fetch release counts: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchSublabels(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(6, "Tone Addiction Jazz", 5)
+
+	mock.ExpectQuery(`SELECT id, name, parent_id FROM labels WHERE parent_id = \$1 ORDER BY name`).
+		WithArgs(5).
+		WillReturnRows(rows)
+
+	labels, err := FetchLabels(db, 5)
+	if err != nil {
+		t.Fatalf("failed to fetch sublabels: %v", err)
+	}
+	if len(labels) != 1 || labels[0].ParentId == nil || *labels[0].ParentId != 5 {
+		t.Errorf("expected one sublabel of label 5, got %v", labels)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"time"
+)
+
+// Master table names
+const (
+	mastersTableName = "masters"
+)
+
+// SQL queries for masters
+const (
+	upsertMasterSQL = `
+		INSERT INTO %s (id, title, year, main_release, uri, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET
+			title = EXCLUDED.title,
+			year = EXCLUDED.year,
+			main_release = EXCLUDED.main_release,
+			uri = EXCLUDED.uri,
+			fetched_at = EXCLUDED.fetched_at;
+	`
+	// Masters are read through their stored versions, so a master whose details were never
+	// fetched is still found, with its version count only.
+	fetchMasterSQL = `
+		SELECT r.master_id, COALESCE(m.title, ''), COALESCE(m.year, 0), COALESCE(m.main_release, 0),
+			COALESCE(m.uri, ''), m.fetched_at, COUNT(r.id)
+		FROM %s r
+		LEFT JOIN %s m ON m.id = r.master_id
+		WHERE r.master_id = $1
+		GROUP BY r.master_id, m.title, m.year, m.main_release, m.uri, m.fetched_at
+	`
+	fetchMastersToFetchSQL = `
+		SELECT DISTINCT r.master_id FROM %s r
+		LEFT JOIN %s m ON m.id = r.master_id
+		WHERE r.master_id IS NOT NULL AND (m.fetched_at IS NULL%s)%s
+		ORDER BY r.master_id
+	`
+)
+
+// releaseCountUnit is what release counts count: each release, or with groupByMaster each master,
+// where releases without a master still count on their own. Master ids are negated so they cannot
+// collide with release ids.
+func releaseCountUnit(groupByMaster bool) string {
+	if groupByMaster {
+		return "COALESCE(-r.master_id, r.id)"
+	}
+	return "r.id"
+}
+
+// FetchMaster returns the master with the number of its stored versions, or nil when no stored
+// release belongs to it.
+func FetchMaster(db *sql.DB, masterID int) (*models.Master, error) {
+	query := fmt.Sprintf(fetchMasterSQL, releasesTableName, mastersTableName)
+
+	master := &models.Master{}
+	var fetchedAt sql.NullTime
+	err := db.QueryRow(query, masterID).Scan(&master.Id, &master.Title, &master.Year, &master.MainRelease,
+		&master.URI, &fetchedAt, &master.VersionCount)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch master %d: %v", masterID, err)
+	}
+	if fetchedAt.Valid {
+		master.FetchedAt = &fetchedAt.Time
+	}
+	return master, nil
+}
+
+// FetchMastersToFetch returns the ids of the masters of the filtered label's releases whose details
+// were never fetched or, when refetchBefore is set, were fetched before it.
+func FetchMastersToFetch(db *sql.DB, labelFilter models.LabelFilter, refetchBefore *time.Time) ([]int, error) {
+	var args []interface{}
+	var staleCondition, labelScope string
+	if labelFilter.LabelId != 0 {
+		args = append(args, labelFilter.LabelId)
+		labelScope = " AND " + labelCondition(labelFilter, len(args))
+	}
+	if refetchBefore != nil {
+		args = append(args, *refetchBefore)
+		staleCondition = fmt.Sprintf(" OR m.fetched_at < $%d", len(args))
+	}
+
+	query := fmt.Sprintf(fetchMastersToFetchSQL, releasesTableName, mastersTableName, staleCondition, labelScope)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch masters to fetch: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan master id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// StoreMaster stores the fetched details of a master.
+func StoreMaster(db *sql.DB, master *models.Master, fetchedAt time.Time) error {
+	query := fmt.Sprintf(upsertMasterSQL, mastersTableName)
+	if _, err := db.Exec(query, master.Id, master.Title, nullIfZero(master.Year), nullIfZero(master.MainRelease),
+		master.URI, fetchedAt); err != nil {
+		return fmt.Errorf("failed to store master %d: %v", master.Id, err)
+	}
+	return nil
+}
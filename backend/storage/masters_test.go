@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+func TestFetchReleaseCountsGroupedByMaster(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	countUnit := `COUNT\(DISTINCT COALESCE\(-r.master_id, r.id\)\)`
+	mock.ExpectQuery(`WITH filtered AS \( SELECT r.id, COALESCE\(-r.master_id, r.id\) AS unit FROM releases r .* WHERE 1=1 AND r.year = \$1\) \)`).
+		WithArgs(1957).
+		WillReturnRows(sqlmock.NewRows([]string{"facet", "artist_id", "name", "release_count"}).
+			AddRow("artist", 11, "John Coltrane", 1).
+			AddRow("total", 0, "", 1))
+	mock.ExpectQuery(`SELECT term, ` + countUnit + ` AS release_count FROM`).
+		WithArgs(1957).
+		WillReturnRows(sqlmock.NewRows([]string{"term", "count"}).AddRow("Vinyl", 1).AddRow("CD", 1))
+	mock.ExpectQuery(`SELECT c.name, c.role, ` + countUnit + ` AS release_count FROM credits c`).
+		WithArgs(1957).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+	mock.ExpectQuery(`SELECT co.name, co.role, ` + countUnit + ` AS release_count FROM release_companies co`).
+		WithArgs(1957).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "role", "count"}))
+
+	countResult, err := FetchReleaseCounts(db, models.ReleaseFilter{Year: 1957}, true)
+	if err != nil {
+		t.Fatalf("failed to fetch release counts: %v", err)
+	}
+	if len(countResult.FormatCounts) != 2 || countResult.FormatCounts[0].Count != 1 {
+		t.Errorf("unexpected format counts: %+v", countResult.FormatCounts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchMaster(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM releases r LEFT JOIN masters m ON m.id = r.master_id WHERE r.master_id = \$1`).
+		WithArgs(4321).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "year", "main_release", "uri", "fetched_at", "versions"}).
+			AddRow(4321, "", 0, 0, "", nil, 3))
+	mock.ExpectQuery(`WHERE r.master_id = \$1`).
+		WithArgs(404).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "year", "main_release", "uri", "fetched_at", "versions"}))
+
+	master, err := FetchMaster(db, 4321)
+	if err != nil {
+		t.Fatalf("failed to fetch master: %v", err)
+	}
+	if master.Id != 4321 || master.VersionCount != 3 || master.FetchedAt != nil {
+		t.Errorf("unexpected master: %+v", master)
+	}
+
+	missing, err := FetchMaster(db, 404)
+	if err != nil || missing != nil {
+		t.Errorf("expected no master without stored versions, got %+v, %v", missing, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchMastersToFetch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	refetchBefore := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT DISTINCT r.master_id FROM releases r LEFT JOIN masters m ON m.id = r.master_id WHERE r.master_id IS NOT NULL AND \(m.fetched_at IS NULL OR m.fetched_at < \$2\) AND r.id IN \(SELECT release_id FROM label_releases WHERE label_id = \$1\) ORDER BY r.master_id`).
+		WithArgs(5, refetchBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"master_id"}).AddRow(4321))
+
+	ids, err := FetchMastersToFetch(db, models.LabelFilter{LabelId: 5}, &refetchBefore)
+	if err != nil {
+		t.Fatalf("failed to fetch masters to fetch: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != 4321 {
+		t.Errorf("unexpected masters: %v", ids)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
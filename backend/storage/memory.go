@@ -0,0 +1,1149 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is the Store kept in memory. It answers like the SQL stores, filters and counts
+// included, so tests can check behaviour instead of queries and the demo mode needs no database.
+// Nothing survives a restart.
+type MemoryStore struct {
+	mu              sync.RWMutex
+	releases        map[int32]*memoryRelease
+	artists         map[int]*models.Artist
+	masters         map[int]*models.Master
+	labels          map[int]*models.Label
+	labelReleases   map[int]map[int32]bool
+	prices          map[int32][]models.PriceSnapshot
+	syncRuns        []*models.SyncRun
+	syncStates      map[int]*models.SyncState
+	pendingReleases map[int]map[int32]bool
+	failedReleases  map[int]map[int32]string
+}
+
+// memoryRelease is a stored release. Its artists keep only what the release credits them with,
+// their names are read from the artist entities.
+type memoryRelease struct {
+	release   models.Release
+	fetchedAt time.Time
+	community []models.CommunityStats
+}
+
+// nameCountKey identifies a credited or company name in its role.
+type nameCountKey struct {
+	name string
+	role string
+}
+
+// distinctCounter counts distinct count units, releases or masters, per key.
+type distinctCounter[K comparable] map[K]map[int64]bool
+
+func (c distinctCounter[K]) add(key K, unit int64) {
+	if c[key] == nil {
+		c[key] = map[int64]bool{}
+	}
+	c[key][unit] = true
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		releases:        map[int32]*memoryRelease{},
+		artists:         map[int]*models.Artist{},
+		masters:         map[int]*models.Master{},
+		labels:          map[int]*models.Label{},
+		labelReleases:   map[int]map[int32]bool{},
+		prices:          map[int32][]models.PriceSnapshot{},
+		syncStates:      map[int]*models.SyncState{},
+		pendingReleases: map[int]map[int32]bool{},
+		failedReleases:  map[int]map[int32]string{},
+	}
+}
+
+// Migrate does nothing, the in-memory store has no schema.
+func (m *MemoryStore) Migrate() error {
+	return nil
+}
+
+func (m *MemoryStore) MigrateDown() error {
+	return fmt.Errorf("the in-memory store has no schema to migrate")
+}
+
+func (m *MemoryStore) MigrateTo(version int) error {
+	return fmt.Errorf("the in-memory store has no schema to migrate")
+}
+
+func (m *MemoryStore) FetchMigrations() ([]models.Migration, error) {
+	return []models.Migration{}, nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+func (m *MemoryStore) StoreRelease(release *models.Release) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fetchedAt := time.Now().UTC()
+	stored, ok := m.releases[release.Id]
+	if !ok {
+		stored = &memoryRelease{}
+		m.releases[release.Id] = stored
+	}
+	stored.release = cloneRelease(release)
+	stored.release.Community = nil
+	stored.release.Genres = uniqueNames(release.Genres)
+	stored.release.Styles = uniqueNames(release.Styles)
+	for i := range stored.release.Formats {
+		stored.release.Formats[i].Descriptions = uniqueNames(stored.release.Formats[i].Descriptions)
+	}
+	stored.fetchedAt = fetchedAt
+
+	for _, artist := range release.Artists {
+		entity, ok := m.artists[artist.Id]
+		if !ok {
+			entity = &models.Artist{Id: artist.Id}
+			m.artists[artist.Id] = entity
+		}
+		entity.Name = artist.Name
+		if artist.ResourceURL != "" {
+			entity.ResourceURL = artist.ResourceURL
+		}
+	}
+
+	if release.Community != nil {
+		stored.community = insertSnapshot(stored.community, *release.Community, fetchedAt)
+	}
+	return nil
+}
+
+// StoreReleases stores the releases one at a time; a batch is no cheaper in memory.
+func (m *MemoryStore) StoreReleases(releases []*models.Release) map[int32]error {
+	failed := make(map[int32]error)
+	for _, release := range releases {
+		if err := m.StoreRelease(release); err != nil {
+			failed[release.Id] = err
+		}
+	}
+	return failed
+}
+
+// insertSnapshot adds a community snapshot taken at capturedAt in the order they were taken, unless
+// one was taken at the same time. Like the SQL store, it keeps no average without ratings.
+func insertSnapshot(snapshots []models.CommunityStats, snapshot models.CommunityStats, capturedAt time.Time) []models.CommunityStats {
+	snapshot.CapturedAt = capturedAt
+	if snapshot.RatingCount == 0 {
+		snapshot.RatingAverage = 0
+	}
+	i := sort.Search(len(snapshots), func(i int) bool { return !snapshots[i].CapturedAt.Before(snapshot.CapturedAt) })
+	if i < len(snapshots) && snapshots[i].CapturedAt.Equal(snapshot.CapturedAt) {
+		return snapshots
+	}
+	snapshots = append(snapshots, models.CommunityStats{})
+	copy(snapshots[i+1:], snapshots[i:])
+	snapshots[i] = snapshot
+	return snapshots
+}
+
+func (m *MemoryStore) FetchRelease(releaseID int32) (*models.Release, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored, ok := m.releases[releaseID]
+	if !ok {
+		return nil, nil
+	}
+	return m.assembleRelease(stored), nil
+}
+
+func (m *MemoryStore) FetchReleases(filter models.ReleaseFilter, order models.ReleaseOrder, limit int, offset int) ([]*models.Release, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	releases := m.filterReleases(filter)
+	if order == models.ReleaseOrderCatno {
+		sort.SliceStable(releases, func(i, j int) bool {
+			catnoI, okI := lowestCatno(releases[i])
+			catnoJ, okJ := lowestCatno(releases[j])
+			if okI != okJ {
+				return okI
+			}
+			return catnoI < catnoJ
+		})
+	}
+
+	if offset >= len(releases) {
+		return nil, nil
+	}
+	releases = releases[offset:]
+	if limit < len(releases) {
+		releases = releases[:limit]
+	}
+	return releases, nil
+}
+
+// lowestCatno returns the catalog number a release is listed under and whether it has any.
+func lowestCatno(release *models.Release) (string, bool) {
+	lowest, found := "", false
+	for _, label := range release.Labels {
+		if label.Catno != "" && (!found || label.Catno < lowest) {
+			lowest, found = label.Catno, true
+		}
+	}
+	return lowest, found
+}
+
+func (m *MemoryStore) FetchReleasesByIdentifier(value string, identifierType string) ([]*models.Release, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	normalized := normalizeIdentifier(value)
+	var releases []*models.Release
+	for _, stored := range m.sortedReleases() {
+		for _, identifier := range stored.release.Identifiers {
+			if normalizeIdentifier(identifier.Value) == normalized &&
+				(identifierType == "" || strings.EqualFold(identifier.Type, identifierType)) {
+				releases = append(releases, m.assembleRelease(stored))
+				break
+			}
+		}
+	}
+	return releases, nil
+}
+
+func (m *MemoryStore) FetchReleaseCounts(filter models.ReleaseFilter, groupByMaster bool) (models.CountResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	releaseUnits := map[int64]bool{}
+	artists := distinctCounter[models.ArtistCount]{}
+	styles := distinctCounter[string]{}
+	genres := distinctCounter[string]{}
+	formats := distinctCounter[string]{}
+	credits := distinctCounter[nameCountKey]{}
+	companies := distinctCounter[nameCountKey]{}
+
+	for _, release := range m.filterReleases(filter) {
+		unit := int64(release.Id)
+		if groupByMaster && release.MasterId != 0 {
+			unit = -int64(release.MasterId)
+		}
+		releaseUnits[unit] = true
+
+		for _, artist := range release.Artists {
+			if matchesArtist(artist, filter.Artist) {
+				artists.add(models.ArtistCount{Id: artist.Id, Name: artist.Name}, unit)
+			}
+		}
+		for _, style := range release.Styles {
+			if containsFold(style, filter.Style) {
+				styles.add(style, unit)
+			}
+		}
+		for _, genre := range release.Genres {
+			if containsFold(genre, filter.Genre) {
+				genres.add(genre, unit)
+			}
+		}
+		for _, format := range release.Formats {
+			formats.add(format.Name, unit)
+			for _, description := range format.Descriptions {
+				formats.add(description, unit)
+			}
+		}
+		for _, credit := range release.Credits {
+			if containsFold(credit.Role, filter.Credit.Role) && containsFold(credit.Name, filter.Credit.Name) {
+				credits.add(nameCountKey{name: credit.Name, role: credit.Role}, unit)
+			}
+		}
+		for _, company := range release.Companies {
+			if containsFold(company.Role, filter.Company.Role) && containsFold(company.Name, filter.Company.Name) {
+				companies.add(nameCountKey{name: company.Name, role: company.Role}, unit)
+			}
+		}
+	}
+
+	countResult := models.CountResult{
+		ReleaseCount:  len(releaseUnits),
+		ArtistCounts:  make([]models.ArtistCount, 0, len(artists)),
+		StyleCounts:   nameCounts(styles),
+		GenreCounts:   nameCounts(genres),
+		FormatCounts:  nameCounts(formats),
+		CreditCounts:  make([]models.CreditCount, 0, len(credits)),
+		CompanyCounts: make([]models.CompanyCount, 0, len(companies)),
+	}
+	for artist, units := range artists {
+		artist.Count = len(units)
+		countResult.ArtistCounts = append(countResult.ArtistCounts, artist)
+	}
+	sort.Slice(countResult.ArtistCounts, func(i, j int) bool {
+		a, b := countResult.ArtistCounts[i], countResult.ArtistCounts[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Id < b.Id
+	})
+	for key, units := range credits {
+		countResult.CreditCounts = append(countResult.CreditCounts, models.CreditCount{Name: key.name, Role: key.role, Count: len(units)})
+	}
+	sort.Slice(countResult.CreditCounts, func(i, j int) bool {
+		a, b := countResult.CreditCounts[i], countResult.CreditCounts[j]
+		return lessNameCount(a.Count, a.Name, a.Role, b.Count, b.Name, b.Role)
+	})
+	for key, units := range companies {
+		countResult.CompanyCounts = append(countResult.CompanyCounts, models.CompanyCount{Name: key.name, Role: key.role, Count: len(units)})
+	}
+	sort.Slice(countResult.CompanyCounts, func(i, j int) bool {
+		a, b := countResult.CompanyCounts[i], countResult.CompanyCounts[j]
+		return lessNameCount(a.Count, a.Name, a.Role, b.Count, b.Name, b.Role)
+	})
+	return countResult, nil
+}
+
+// nameCounts lists the counted names from the most counted, names counted equally by name.
+func nameCounts(counter distinctCounter[string]) []models.NameCount {
+	counts := make([]models.NameCount, 0, len(counter))
+	for name, units := range counter {
+		counts = append(counts, models.NameCount{Name: name, Count: len(units)})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return lessNameCount(counts[i].Count, counts[i].Name, "", counts[j].Count, counts[j].Name, "")
+	})
+	return counts
+}
+
+func lessNameCount(countA int, nameA string, roleA string, countB int, nameB string, roleB string) bool {
+	if countA != countB {
+		return countA > countB
+	}
+	if nameA != nameB {
+		return nameA < nameB
+	}
+	return roleA < roleB
+}
+
+func (m *MemoryStore) FetchReleaseFetchTimes() (map[int32]time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	fetchedAt := make(map[int32]time.Time, len(m.releases))
+	for id, stored := range m.releases {
+		fetchedAt[id] = stored.fetchedAt
+	}
+	return fetchedAt, nil
+}
+
+func (m *MemoryStore) FetchUniqueNames(facet Facet, labelFilter models.LabelFilter) ([]*models.UniqueName, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := map[string]bool{}
+	inLabel := m.labelScope(labelFilter)
+	for id, stored := range m.releases {
+		if !inLabel(id) {
+			continue
+		}
+		names, err := facetNames(&stored.release, facet)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+
+	var uniqueNames []*models.UniqueName
+	for _, name := range sortedKeys(seen) {
+		uniqueNames = append(uniqueNames, &models.UniqueName{Name: name})
+	}
+	return uniqueNames, nil
+}
+
+// facetNames returns the names a release has for a facet.
+func facetNames(release *models.Release, facet Facet) ([]string, error) {
+	switch facet {
+	case FacetGenre:
+		return release.Genres, nil
+	case FacetStyle:
+		return release.Styles, nil
+	}
+	return nil, fmt.Errorf("unknown facet: %s", facet)
+}
+
+func (m *MemoryStore) FetchUniqueArtists(labelFilter models.LabelFilter) ([]*models.Artist, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var artists []*models.Artist
+	for _, id := range m.creditedArtistIDs(labelFilter) {
+		artists = append(artists, cloneArtist(m.artists[id]))
+	}
+	sort.Slice(artists, func(i, j int) bool {
+		if artists[i].Name != artists[j].Name {
+			return artists[i].Name < artists[j].Name
+		}
+		return artists[i].Id < artists[j].Id
+	})
+	return artists, nil
+}
+
+// creditedArtistIDs returns the ids of the artists credited on the filtered label's releases, or
+// on any release when no label is given, in ascending order.
+func (m *MemoryStore) creditedArtistIDs(labelFilter models.LabelFilter) []int {
+	credited := map[int]bool{}
+	inLabel := m.labelScope(labelFilter)
+	for id, stored := range m.releases {
+		if !inLabel(id) {
+			continue
+		}
+		for _, artist := range stored.release.Artists {
+			credited[artist.Id] = true
+		}
+	}
+	return sortedKeys(credited)
+}
+
+func (m *MemoryStore) FetchArtist(artistID int) (*models.Artist, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	artist, ok := m.artists[artistID]
+	if !ok {
+		return nil, nil
+	}
+	return cloneArtist(artist), nil
+}
+
+func (m *MemoryStore) FetchArtistsToEnrich(labelFilter models.LabelFilter, refetchBefore *time.Time) ([]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var ids []int
+	for _, id := range m.creditedArtistIDs(labelFilter) {
+		fetchedAt := m.artists[id].ProfileFetchedAt
+		if fetchedAt == nil || (refetchBefore != nil && fetchedAt.Before(*refetchBefore)) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *MemoryStore) StoreArtistProfile(artist *models.Artist, fetchedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := cloneArtist(artist)
+	if existing, ok := m.artists[artist.Id]; ok && stored.ResourceURL == "" {
+		stored.ResourceURL = existing.ResourceURL
+	}
+	stored.NameVariations = uniqueNames(stored.NameVariations)
+	stored.Aliases = uniqueArtistRefs(stored.Aliases)
+	stored.Groups = uniqueArtistRefs(stored.Groups)
+	stored.Members = uniqueArtistRefs(stored.Members)
+	stored.ProfileFetchedAt = &fetchedAt
+	m.artists[artist.Id] = stored
+	return nil
+}
+
+func (m *MemoryStore) FetchMaster(masterID int) (*models.Master, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	versionCount := 0
+	for _, stored := range m.releases {
+		if stored.release.MasterId == masterID {
+			versionCount++
+		}
+	}
+	if versionCount == 0 || masterID == 0 {
+		return nil, nil
+	}
+
+	master := &models.Master{Id: masterID}
+	if stored, ok := m.masters[masterID]; ok {
+		*master = *stored
+	}
+	master.VersionCount = versionCount
+	return master, nil
+}
+
+func (m *MemoryStore) FetchMastersToFetch(labelFilter models.LabelFilter, refetchBefore *time.Time) ([]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	due := map[int]bool{}
+	inLabel := m.labelScope(labelFilter)
+	for id, stored := range m.releases {
+		masterID := stored.release.MasterId
+		if masterID == 0 || !inLabel(id) {
+			continue
+		}
+		master, fetched := m.masters[masterID]
+		if !fetched || (refetchBefore != nil && master.FetchedAt.Before(*refetchBefore)) {
+			due[masterID] = true
+		}
+	}
+	ids := sortedKeys(due)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return ids, nil
+}
+
+func (m *MemoryStore) StoreMaster(master *models.Master, fetchedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := *master
+	stored.VersionCount = 0
+	stored.FetchedAt = &fetchedAt
+	m.masters[master.Id] = &stored
+	return nil
+}
+
+func (m *MemoryStore) StoreLabel(label *models.Label) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := &models.Label{Id: label.Id, Name: label.Name, ParentId: label.ParentId}
+	if existing, ok := m.labels[label.Id]; ok && stored.ParentId == nil {
+		stored.ParentId = existing.ParentId
+	}
+	if stored.ParentId != nil {
+		parentID := *stored.ParentId
+		stored.ParentId = &parentID
+	}
+	m.labels[label.Id] = stored
+	return nil
+}
+
+func (m *MemoryStore) FetchLabels(parentID int) ([]*models.Label, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var labels []*models.Label
+	for _, label := range m.labels {
+		if parentID != 0 && (label.ParentId == nil || *label.ParentId != parentID) {
+			continue
+		}
+		copied := *label
+		if label.ParentId != nil {
+			parent := *label.ParentId
+			copied.ParentId = &parent
+		}
+		labels = append(labels, &copied)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Name != labels[j].Name {
+			return labels[i].Name < labels[j].Name
+		}
+		return labels[i].Id < labels[j].Id
+	})
+	return labels, nil
+}
+
+func (m *MemoryStore) FetchCommunityHistory(releaseID int32, from *time.Time, until *time.Time) ([]models.CommunityStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := []models.CommunityStats{}
+	if stored, ok := m.releases[releaseID]; ok {
+		for _, snapshot := range stored.community {
+			if inPeriod(snapshot.CapturedAt, from, until) {
+				history = append(history, snapshot)
+			}
+		}
+	}
+	return history, nil
+}
+
+func (m *MemoryStore) FetchMostWanted(labelFilter models.LabelFilter, limit int) ([]*models.Release, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var wanted []*memoryRelease
+	inLabel := m.labelScope(labelFilter)
+	for _, stored := range m.sortedReleases() {
+		if len(stored.community) > 0 && inLabel(stored.release.Id) {
+			wanted = append(wanted, stored)
+		}
+	}
+	sort.SliceStable(wanted, func(i, j int) bool {
+		return latestWant(wanted[i]) > latestWant(wanted[j])
+	})
+
+	releases := []*models.Release{}
+	for _, stored := range wanted[:min(max(limit, 0), len(wanted))] {
+		releases = append(releases, m.assembleRelease(stored))
+	}
+	return releases, nil
+}
+
+func latestWant(stored *memoryRelease) int {
+	return stored.community[len(stored.community)-1].Want
+}
+
+func (m *MemoryStore) FetchTrending(labelFilter models.LabelFilter, from time.Time, until time.Time, limit int) ([]models.WantGrowth, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var trending []models.WantGrowth
+	inLabel := m.labelScope(labelFilter)
+	for _, stored := range m.sortedReleases() {
+		if !inLabel(stored.release.Id) {
+			continue
+		}
+		var inRange []models.CommunityStats
+		for _, snapshot := range stored.community {
+			if inPeriod(snapshot.CapturedAt, &from, &until) {
+				inRange = append(inRange, snapshot)
+			}
+		}
+		if len(inRange) == 0 {
+			continue
+		}
+		first, last := inRange[0], inRange[len(inRange)-1]
+		trending = append(trending, models.WantGrowth{
+			Release:   m.assembleRelease(stored),
+			WantFrom:  first.Want,
+			WantUntil: last.Want,
+			Growth:    last.Want - first.Want,
+		})
+	}
+	sort.SliceStable(trending, func(i, j int) bool { return trending[i].Growth > trending[j].Growth })
+
+	if limit < len(trending) {
+		trending = trending[:max(limit, 0)]
+	}
+	return trending, nil
+}
+
+func (m *MemoryStore) FetchReleasesToSnapshot(labelFilter models.LabelFilter, capturedSince time.Time) ([]int32, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	listed := m.labelReleaseIDs(labelFilter)
+	var releaseIDs []int32
+	for _, stored := range m.sortedReleases() {
+		snapshots := stored.community
+		if listed[stored.release.Id] && (len(snapshots) == 0 || snapshots[len(snapshots)-1].CapturedAt.Before(capturedSince)) {
+			releaseIDs = append(releaseIDs, stored.release.Id)
+		}
+	}
+	return releaseIDs, nil
+}
+
+func (m *MemoryStore) FetchReleasesToPrice(labelFilter models.LabelFilter) ([]int32, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Like the SQL query, the label is always applied, so no label lists no release.
+	listed := m.labelReleaseIDs(labelFilter)
+	var releaseIDs []int32
+	for _, stored := range m.sortedReleases() {
+		if listed[stored.release.Id] {
+			releaseIDs = append(releaseIDs, stored.release.Id)
+		}
+	}
+	return releaseIDs, nil
+}
+
+func (m *MemoryStore) StorePriceSnapshot(releaseID int32, price *models.PriceSnapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.releases[releaseID]; !ok {
+		return fmt.Errorf("failed to store price snapshot of release %d: release is not stored", releaseID)
+	}
+	snapshots := m.prices[releaseID]
+	i := sort.Search(len(snapshots), func(i int) bool { return !snapshots[i].CapturedAt.Before(price.CapturedAt) })
+	if i < len(snapshots) && snapshots[i].CapturedAt.Equal(price.CapturedAt) {
+		return nil
+	}
+
+	snapshot := *price
+	if price.LowestPrice != nil {
+		lowestPrice := *price.LowestPrice
+		snapshot.LowestPrice = &lowestPrice
+	}
+	snapshots = append(snapshots, models.PriceSnapshot{})
+	copy(snapshots[i+1:], snapshots[i:])
+	snapshots[i] = snapshot
+	m.prices[releaseID] = snapshots
+	return nil
+}
+
+func (m *MemoryStore) FetchPriceHistory(releaseID int32, currency string, from *time.Time, until *time.Time) ([]models.PriceSnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := []models.PriceSnapshot{}
+	for _, snapshot := range m.prices[releaseID] {
+		if (currency == "" || snapshot.Currency == currency) && inPeriod(snapshot.CapturedAt, from, until) {
+			history = append(history, snapshot)
+		}
+	}
+	return history, nil
+}
+
+func (m *MemoryStore) FetchPriceStats(facet Facet, currency string, labelFilter models.LabelFilter) ([]models.PriceStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byName := map[string]*models.PriceStats{}
+	prices := map[string][]float64{}
+	inLabel := m.labelScope(labelFilter)
+	for releaseID, snapshots := range m.prices {
+		if !inLabel(releaseID) {
+			continue
+		}
+		var latest *models.PriceSnapshot
+		for i := range snapshots {
+			if snapshots[i].Currency == currency {
+				latest = &snapshots[i]
+			}
+		}
+		if latest == nil {
+			continue
+		}
+
+		names, err := facetNames(&m.releases[releaseID].release, facet)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			stats, ok := byName[name]
+			if !ok {
+				stats = &models.PriceStats{Name: name}
+				byName[name] = stats
+			}
+			stats.ReleaseCount++
+			stats.NumForSale += latest.NumForSale
+			if latest.LowestPrice != nil {
+				prices[name] = append(prices[name], *latest.LowestPrice)
+			}
+		}
+	}
+
+	statsList := []models.PriceStats{}
+	for _, name := range sortedKeys(byName) {
+		stats := byName[name]
+		sort.Float64s(prices[name])
+		aggregatePrices(stats, prices[name])
+		statsList = append(statsList, *stats)
+	}
+	sort.SliceStable(statsList, func(i, j int) bool {
+		if statsList[j].MedianPrice == nil {
+			return statsList[i].MedianPrice != nil
+		}
+		return statsList[i].MedianPrice != nil && *statsList[i].MedianPrice > *statsList[j].MedianPrice
+	})
+	return statsList, nil
+}
+
+func (m *MemoryStore) StartSyncRun(labelID int, firstPageURL string, startedAt time.Time) (*models.SyncState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, run := range m.syncRuns {
+		if run.LabelId == labelID && run.Status == models.SyncRunRunning {
+			run.Status = models.SyncRunInterrupted
+			run.FinishedAt = &startedAt
+		}
+	}
+
+	run := &models.SyncRun{Id: len(m.syncRuns) + 1, LabelId: labelID, StartedAt: startedAt, Status: models.SyncRunRunning}
+	m.syncRuns = append(m.syncRuns, run)
+
+	state, ok := m.syncStates[labelID]
+	if !ok {
+		state = &models.SyncState{LabelId: labelID, NextPageURL: firstPageURL}
+		m.syncStates[labelID] = state
+	}
+	state.RunId = run.Id
+
+	copied := *state
+	return &copied, nil
+}
+
+func (m *MemoryStore) SaveListedPage(state *models.SyncState, listedIDs []int32, pendingIDs []int32, nextPageURL string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.labels[state.LabelId]; !ok && len(listedIDs) > 0 {
+		return fmt.Errorf("failed to link releases to label %d: label is not stored", state.LabelId)
+	}
+	addReleaseIDs(m.labelReleases, state.LabelId, listedIDs)
+	addReleaseIDs(m.pendingReleases, state.LabelId, pendingIDs)
+
+	listingComplete := nextPageURL == ""
+	if stored, ok := m.syncStates[state.LabelId]; ok {
+		stored.PagesListed++
+		stored.NextPageURL = nextPageURL
+		stored.ListingComplete = listingComplete
+	}
+	if run := m.syncRun(state.RunId); run != nil {
+		run.ReleasesListed += len(listedIDs)
+	}
+
+	state.PagesListed++
+	state.NextPageURL = nextPageURL
+	state.ListingComplete = listingComplete
+	return nil
+}
+
+func addReleaseIDs(sets map[int]map[int32]bool, key int, releaseIDs []int32) {
+	if len(releaseIDs) == 0 {
+		return
+	}
+	if sets[key] == nil {
+		sets[key] = map[int32]bool{}
+	}
+	for _, releaseID := range releaseIDs {
+		sets[key][releaseID] = true
+	}
+}
+
+func (m *MemoryStore) syncRun(runID int) *models.SyncRun {
+	if runID < 1 || runID > len(m.syncRuns) {
+		return nil
+	}
+	return m.syncRuns[runID-1]
+}
+
+func (m *MemoryStore) FetchPendingReleaseIDs(labelID int) ([]int32, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	releaseIDs := sortedKeys(m.pendingReleases[labelID])
+	if len(releaseIDs) == 0 {
+		return nil, nil
+	}
+	return releaseIDs, nil
+}
+
+func (m *MemoryStore) CompletePendingRelease(state *models.SyncState, releaseID int32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.pendingReleases[state.LabelId], releaseID)
+	if run := m.syncRun(state.RunId); run != nil {
+		run.ReleasesFetched++
+	}
+	return nil
+}
+
+func (m *MemoryStore) FailPendingRelease(state *models.SyncState, releaseID int32, reason error, failedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.pendingReleases[state.LabelId], releaseID)
+	if m.failedReleases[state.RunId] == nil {
+		m.failedReleases[state.RunId] = map[int32]string{}
+	}
+	m.failedReleases[state.RunId][releaseID] = reason.Error()
+	if run := m.syncRun(state.RunId); run != nil {
+		run.ReleasesFailed++
+	}
+	return nil
+}
+
+func (m *MemoryStore) FinishSyncRun(state *models.SyncState, runErr error, finishedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if run := m.syncRun(state.RunId); run != nil {
+		run.Status, run.Error = models.SyncRunSucceeded, ""
+		if runErr != nil {
+			run.Status, run.Error = models.SyncRunFailed, runErr.Error()
+		}
+		run.FinishedAt = &finishedAt
+	}
+	if runErr == nil {
+		delete(m.syncStates, state.LabelId)
+		delete(m.pendingReleases, state.LabelId)
+	}
+	return nil
+}
+
+func (m *MemoryStore) FetchSyncRun(runID int) (*models.SyncRun, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	run := m.syncRun(runID)
+	if run == nil {
+		return nil, fmt.Errorf("failed to fetch sync run %d: no such run", runID)
+	}
+	copied := *run
+	return &copied, nil
+}
+
+func (m *MemoryStore) FetchLastSyncedAt(labelID int) (*time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var lastSyncedAt *time.Time
+	for _, run := range m.syncRuns {
+		if run.Status != models.SyncRunSucceeded || (labelID != 0 && run.LabelId != labelID) || run.FinishedAt == nil {
+			continue
+		}
+		if lastSyncedAt == nil || run.FinishedAt.After(*lastSyncedAt) {
+			finishedAt := *run.FinishedAt
+			lastSyncedAt = &finishedAt
+		}
+	}
+	return lastSyncedAt, nil
+}
+
+// sortedReleases returns the stored releases ordered by id.
+func (m *MemoryStore) sortedReleases() []*memoryRelease {
+	releases := make([]*memoryRelease, 0, len(m.releases))
+	for _, id := range sortedKeys(m.releases) {
+		releases = append(releases, m.releases[id])
+	}
+	return releases
+}
+
+// filterReleases returns the releases matching the filter, ordered by id.
+func (m *MemoryStore) filterReleases(filter models.ReleaseFilter) []*models.Release {
+	inLabel := m.labelScope(filter.LabelFilter)
+	var releases []*models.Release
+	for _, stored := range m.sortedReleases() {
+		if !inLabel(stored.release.Id) {
+			continue
+		}
+		if release := m.assembleRelease(stored); matchesFilter(release, filter) {
+			releases = append(releases, release)
+		}
+	}
+	return releases
+}
+
+// matchesFilter applies the release filter except for the label. Names match when they contain
+// the filtered one in any case, the country when it equals it in any case.
+func matchesFilter(release *models.Release, filter models.ReleaseFilter) bool {
+	if filter.Year != 0 && release.Year != filter.Year {
+		return false
+	}
+	if filter.Country != "" && !strings.EqualFold(release.Country, filter.Country) {
+		return false
+	}
+	if filter.MasterId != 0 && release.MasterId != filter.MasterId {
+		return false
+	}
+	if filter.Artist != "" && !anyMatch(release.Artists, func(artist models.ReleaseArtist) bool {
+		return matchesArtist(artist, filter.Artist)
+	}) {
+		return false
+	}
+	if filter.Style != "" && !anyMatch(release.Styles, func(style string) bool { return containsFold(style, filter.Style) }) {
+		return false
+	}
+	if filter.Genre != "" && !anyMatch(release.Genres, func(genre string) bool { return containsFold(genre, filter.Genre) }) {
+		return false
+	}
+	if filter.Format != "" && !anyMatch(release.Formats, func(format models.Format) bool {
+		return containsFold(format.Name, filter.Format) ||
+			anyMatch(format.Descriptions, func(description string) bool { return containsFold(description, filter.Format) })
+	}) {
+		return false
+	}
+	if filter.Track != "" && !anyMatch(release.Tracks, func(track models.Track) bool { return containsFold(track.Title, filter.Track) }) {
+		return false
+	}
+	if filter.Credit != (models.CreditFilter{}) && !anyMatch(release.Credits, func(credit models.Credit) bool {
+		return containsFold(credit.Role, filter.Credit.Role) && containsFold(credit.Name, filter.Credit.Name)
+	}) {
+		return false
+	}
+	if filter.Company != (models.CompanyFilter{}) && !anyMatch(release.Companies, func(company models.Company) bool {
+		return containsFold(company.Role, filter.Company.Role) && containsFold(company.Name, filter.Company.Name)
+	}) {
+		return false
+	}
+	if filter.Catno != "" && !anyMatch(release.Labels, func(label models.ReleaseLabel) bool { return containsFold(label.Catno, filter.Catno) }) {
+		return false
+	}
+	return true
+}
+
+// matchesArtist matches artists by their canonical name or the name they were credited with.
+func matchesArtist(artist models.ReleaseArtist, name string) bool {
+	return containsFold(artist.Name, name) || containsFold(artist.Anv, name)
+}
+
+func containsFold(value string, part string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(part))
+}
+
+func anyMatch[T any](values []T, match func(T) bool) bool {
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func inPeriod(capturedAt time.Time, from *time.Time, until *time.Time) bool {
+	return (from == nil || !capturedAt.Before(*from)) && (until == nil || !capturedAt.After(*until))
+}
+
+// labelScope returns whether a release belongs to the filtered label, which every release does
+// when no label is given.
+func (m *MemoryStore) labelScope(filter models.LabelFilter) func(releaseID int32) bool {
+	if filter.LabelId == 0 {
+		return func(int32) bool { return true }
+	}
+	listed := m.labelReleaseIDs(filter)
+	return func(releaseID int32) bool { return listed[releaseID] }
+}
+
+// labelReleaseIDs returns the ids of the releases listed by the label and, if requested, by any
+// of its sublabels.
+func (m *MemoryStore) labelReleaseIDs(filter models.LabelFilter) map[int32]bool {
+	labelIDs := map[int]bool{filter.LabelId: true}
+	for filter.IncludeSublabels {
+		added := false
+		for _, label := range m.labels {
+			if label.ParentId != nil && labelIDs[*label.ParentId] && !labelIDs[label.Id] {
+				labelIDs[label.Id] = true
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+
+	listed := map[int32]bool{}
+	for labelID := range labelIDs {
+		for releaseID := range m.labelReleases[labelID] {
+			listed[releaseID] = true
+		}
+	}
+	return listed
+}
+
+// assembleRelease returns a copy of a stored release with the names of its artists and its latest
+// community statistics.
+func (m *MemoryStore) assembleRelease(stored *memoryRelease) *models.Release {
+	release := cloneRelease(&stored.release)
+	for i := range release.Artists {
+		if entity, ok := m.artists[release.Artists[i].Id]; ok {
+			release.Artists[i].Name = entity.Name
+			release.Artists[i].ResourceURL = entity.ResourceURL
+		}
+	}
+	for i := range release.Formats {
+		sort.Strings(release.Formats[i].Descriptions)
+	}
+	if len(stored.community) > 0 {
+		latest := stored.community[len(stored.community)-1]
+		release.Community = &latest
+	}
+	return &release
+}
+
+// cloneRelease copies a release deeply, leaving empty lists nil as the SQL stores read them.
+func cloneRelease(release *models.Release) models.Release {
+	copied := *release
+	copied.Labels = cloneSlice(release.Labels)
+	copied.Companies = cloneSlice(release.Companies)
+	copied.Identifiers = cloneSlice(release.Identifiers)
+	copied.Artists = cloneSlice(release.Artists)
+	copied.Styles = cloneSlice(release.Styles)
+	copied.Genres = cloneSlice(release.Genres)
+	copied.Formats = cloneSlice(release.Formats)
+	for i := range copied.Formats {
+		copied.Formats[i].Descriptions = cloneSlice(copied.Formats[i].Descriptions)
+	}
+	copied.Tracks = cloneSlice(release.Tracks)
+	for i := range copied.Tracks {
+		copied.Tracks[i].Artists = cloneSlice(copied.Tracks[i].Artists)
+	}
+	copied.Credits = cloneSlice(release.Credits)
+	if release.Community != nil {
+		community := *release.Community
+		copied.Community = &community
+	}
+	return copied
+}
+
+// cloneArtist copies an artist deeply, with its relations ordered by name as the SQL stores read
+// them.
+func cloneArtist(artist *models.Artist) *models.Artist {
+	copied := *artist
+	copied.NameVariations = cloneSlice(artist.NameVariations)
+	sort.Strings(copied.NameVariations)
+	copied.Aliases = cloneArtistRefs(artist.Aliases)
+	copied.Groups = cloneArtistRefs(artist.Groups)
+	copied.Members = cloneArtistRefs(artist.Members)
+	if artist.ProfileFetchedAt != nil {
+		fetchedAt := *artist.ProfileFetchedAt
+		copied.ProfileFetchedAt = &fetchedAt
+	}
+	return &copied
+}
+
+func cloneArtistRefs(refs []models.ArtistRef) []models.ArtistRef {
+	copied := cloneSlice(refs)
+	for i := range copied {
+		if copied[i].Active != nil {
+			active := *copied[i].Active
+			copied[i].Active = &active
+		}
+	}
+	sort.SliceStable(copied, func(i, j int) bool { return copied[i].Name < copied[j].Name })
+	return copied
+}
+
+func cloneSlice[T any](values []T) []T {
+	if len(values) == 0 {
+		return nil
+	}
+	return append([]T(nil), values...)
+}
+
+// uniqueNames drops repeated names, keeping the first of each.
+func uniqueNames(names []string) []string {
+	var unique []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// uniqueArtistRefs drops repeated related artists, keeping the first of each.
+func uniqueArtistRefs(refs []models.ArtistRef) []models.ArtistRef {
+	var unique []models.ArtistRef
+	seen := map[int]bool{}
+	for _, ref := range refs {
+		if !seen[ref.Id] {
+			seen[ref.Id] = true
+			unique = append(unique, ref)
+		}
+	}
+	return unique
+}
+
+func sortedKeys[K int | int32 | string, V any](values map[K]V) []K {
+	keys := make([]K, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migrations are numbered SQL files in the directory of their dialect under migrations,
+// NNNN_name.up.sql with the matching NNNN_name.down.sql that reverts it. They are embedded in
+// the binary.
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// Migration table names
+const (
+	schemaMigrationsTableName = "schema_migrations"
+)
+
+// migrationLockKey identifies the Postgres advisory lock held while migrating, so replicas
+// starting at the same time apply each migration once.
+const migrationLockKey = 7240915
+
+// SQL queries for migrations
+const (
+	createSchemaMigrationsSQL = `
+		CREATE TABLE IF NOT EXISTS %s (
+			version INT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at %s NOT NULL
+		);
+	`
+	acquireMigrationLockSQL   = `SELECT pg_advisory_lock($1)`
+	releaseMigrationLockSQL   = `SELECT pg_advisory_unlock($1)`
+	fetchAppliedMigrationsSQL = `SELECT version, applied_at FROM %s ORDER BY version`
+	insertAppliedMigrationSQL = `INSERT INTO %s (version, name, applied_at) VALUES ($1, $2, $3)`
+	deleteAppliedMigrationSQL = `DELETE FROM %s WHERE version = $1`
+)
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// loadMigrations reads the migrations in dir of fsys, ordered by version. Versions must start at
+// 1 without gaps and every migration needs both an up and a down file.
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+
+	byVersion := map[int]*migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %v", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: match[2]}
+			byVersion[version] = m
+		}
+		if m.name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.name, match[2])
+		}
+		if match[3] == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	for i, m := range migrations {
+		if m.version != i+1 {
+			return nil, fmt.Errorf("migration versions must follow each other from 1, missing version %d", i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// Migrate applies the migrations the database is missing.
+func Migrate(db *sql.DB, dialect Dialect) error {
+	return runMigrations(db, dialect, func(current int, latest int) (int, error) {
+		return latest, nil
+	})
+}
+
+// MigrateDown reverts the latest applied migration.
+func MigrateDown(db *sql.DB, dialect Dialect) error {
+	return runMigrations(db, dialect, func(current int, latest int) (int, error) {
+		if current == 0 {
+			return 0, fmt.Errorf("no migration to revert")
+		}
+		return current - 1, nil
+	})
+}
+
+// MigrateTo applies or reverts migrations until the schema is at the given version. Version 0 is
+// the empty schema.
+func MigrateTo(db *sql.DB, dialect Dialect, version int) error {
+	return runMigrations(db, dialect, func(current int, latest int) (int, error) {
+		if version < 0 || version > latest {
+			return 0, fmt.Errorf("version must be between 0 and %d, got: %d", latest, version)
+		}
+		return version, nil
+	})
+}
+
+// FetchMigrations returns the known migrations, with when they were applied for those that were.
+func FetchMigrations(db *sql.DB, dialect Dialect) ([]models.Migration, error) {
+	migrations, err := loadMigrations(migrationFiles, dialect.migrationsDir())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(createSchemaMigrationsTable(dialect)); err != nil {
+		return nil, fmt.Errorf("failed to create %s table: %v", schemaMigrationsTableName, err)
+	}
+	applied, err := fetchAppliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]models.Migration, 0, len(migrations))
+	for _, m := range migrations {
+		status := models.Migration{Version: m.version, Name: m.name}
+		if appliedAt, ok := applied[m.version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func createSchemaMigrationsTable(dialect Dialect) string {
+	return fmt.Sprintf(createSchemaMigrationsSQL, schemaMigrationsTableName, dialect.timestampType())
+}
+
+// queryer is implemented by both *sql.DB and *sql.Conn.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func fetchAppliedMigrations(db queryer) (map[int]time.Time, error) {
+	rows, err := db.QueryContext(context.Background(), fmt.Sprintf(fetchAppliedMigrationsSQL, schemaMigrationsTableName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch applied migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %v", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// runMigrations moves the schema to the version chosen by target from the current and the latest
+// version. On Postgres it holds an advisory lock on one connection meanwhile, so concurrent runs
+// wait for each other and then find the migrations already applied. SQLite databases belong to a
+// single process and need no lock. Each migration runs in its own transaction.
+func runMigrations(db *sql.DB, dialect Dialect, target func(current int, latest int) (int, error)) error {
+	migrations, err := loadMigrations(migrationFiles, dialect.migrationsDir())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open migration connection: %v", err)
+	}
+	defer conn.Close()
+
+	if dialect == Postgres {
+		if _, err := conn.ExecContext(ctx, acquireMigrationLockSQL, migrationLockKey); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %v", err)
+		}
+		defer func() {
+			if _, err := conn.ExecContext(ctx, releaseMigrationLockSQL, migrationLockKey); err != nil {
+				log.Printf("Error releasing migration lock: %v", err)
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, createSchemaMigrationsTable(dialect)); err != nil {
+		return fmt.Errorf("failed to create %s table: %v", schemaMigrationsTableName, err)
+	}
+	applied, err := fetchAppliedMigrations(conn)
+	if err != nil {
+		return err
+	}
+
+	current := 0
+	for version := range applied {
+		if version > len(migrations) {
+			return fmt.Errorf("database schema is at version %d, newer than the %d migrations this binary knows", version, len(migrations))
+		}
+		current = max(current, version)
+	}
+
+	version, err := target(current, len(migrations))
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; !ok && m.version <= version {
+			if err := applyMigration(ctx, conn, m, true); err != nil {
+				return err
+			}
+			log.Printf("Applied migration %d_%s", m.version, m.name)
+		}
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.version]; ok && m.version > version {
+			if err := applyMigration(ctx, conn, m, false); err != nil {
+				return err
+			}
+			log.Printf("Reverted migration %d_%s", m.version, m.name)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs the up or down statements of a migration and records the change in the
+// same transaction, so a failing migration leaves neither its changes nor its record.
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d_%s: %v", m.version, m.name, err)
+	}
+	defer tx.Rollback()
+
+	statements := m.down
+	record := fmt.Sprintf(deleteAppliedMigrationSQL, schemaMigrationsTableName)
+	recordArgs := []any{m.version}
+	if up {
+		statements = m.up
+		record = fmt.Sprintf(insertAppliedMigrationSQL, schemaMigrationsTableName)
+		recordArgs = []any{m.version, m.name, time.Now()}
+	}
+
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		return fmt.Errorf("migration %d_%s failed: %v", m.version, m.name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, recordArgs...); err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %v", m.version, m.name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d_%s: %v", m.version, m.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+-- Drops every table of the baseline schema, and with them all synced data.
+
+DROP TABLE IF EXISTS sync_failed_releases;
+DROP TABLE IF EXISTS sync_pending_releases;
+DROP TABLE IF EXISTS sync_state;
+DROP TABLE IF EXISTS sync_runs;
+DROP TABLE IF EXISTS label_releases;
+DROP TABLE IF EXISTS labels;
+DROP TABLE IF EXISTS price_snapshots;
+DROP TABLE IF EXISTS community_snapshots;
+DROP TABLE IF EXISTS identifiers;
+DROP TABLE IF EXISTS release_companies;
+DROP TABLE IF EXISTS credits;
+DROP TABLE IF EXISTS track_artists;
+DROP TABLE IF EXISTS tracks;
+DROP TABLE IF EXISTS format_descriptions;
+DROP TABLE IF EXISTS formats;
+DROP TABLE IF EXISTS masters;
+DROP TABLE IF EXISTS artist_relations;
+DROP TABLE IF EXISTS artist_name_variations;
+DROP TABLE IF EXISTS release_artists;
+DROP TABLE IF EXISTS discogs_artists;
+DROP TABLE IF EXISTS styles;
+DROP TABLE IF EXISTS genres;
+DROP TABLE IF EXISTS artists;
+DROP TABLE IF EXISTS releases;
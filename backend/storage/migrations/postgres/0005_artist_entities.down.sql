@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS release_artists;
+DROP TABLE IF EXISTS discogs_artists;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS artist_name_variations (
+	artist_id INT NOT NULL REFERENCES discogs_artists(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	PRIMARY KEY (artist_id, name)
+);
+CREATE TABLE IF NOT EXISTS artist_relations (
+	artist_id INT NOT NULL REFERENCES discogs_artists(id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	related_id INT NOT NULL,
+	related_name TEXT NOT NULL,
+	active BOOLEAN,
+	PRIMARY KEY (artist_id, kind, related_id)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS masters (
+	id INT PRIMARY KEY,
+	title TEXT,
+	year INT,
+	main_release INT,
+	uri TEXT,
+	fetched_at TIMESTAMPTZ
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS release_companies (
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	position INT NOT NULL,
+	company_id INT,
+	name TEXT NOT NULL,
+	role TEXT,
+	catno TEXT,
+	PRIMARY KEY (release_id, kind, position)
+);
+CREATE INDEX IF NOT EXISTS release_companies_catno_idx ON release_companies (catno);
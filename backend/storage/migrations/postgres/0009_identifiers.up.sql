@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS identifiers (
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	position INT NOT NULL,
+	type TEXT NOT NULL,
+	value TEXT NOT NULL,
+	description TEXT,
+	normalized_value TEXT NOT NULL,
+	PRIMARY KEY (release_id, position)
+);
+CREATE INDEX IF NOT EXISTS identifiers_normalized_value_idx ON identifiers (normalized_value);
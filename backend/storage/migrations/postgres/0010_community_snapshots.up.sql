@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS community_snapshots (
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	captured_at TIMESTAMPTZ NOT NULL,
+	have INT NOT NULL,
+	want INT NOT NULL,
+	rating_average DOUBLE PRECISION,
+	rating_count INT,
+	PRIMARY KEY (release_id, captured_at)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS price_snapshots (
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	captured_at TIMESTAMPTZ NOT NULL,
+	currency TEXT NOT NULL,
+	lowest_price DOUBLE PRECISION,
+	num_for_sale INT NOT NULL,
+	blocked_from_sale BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (release_id, captured_at)
+);
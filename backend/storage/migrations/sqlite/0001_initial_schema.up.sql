@@ -0,0 +1,209 @@
+-- The baseline schema for SQLite. It mirrors the Postgres baseline without the statements that
+-- upgrade databases from before versioned migrations. Timestamp columns are declared TIMESTAMP so
+-- the driver reads them back as times.
+
+CREATE TABLE IF NOT EXISTS releases (
+	id INT PRIMARY KEY,
+	fetched_at TIMESTAMP,
+	title TEXT,
+	year INT,
+	released TEXT,
+	country TEXT,
+	data_quality TEXT,
+	notes TEXT,
+	uri TEXT,
+	master_id INT
+);
+CREATE INDEX IF NOT EXISTS releases_master_id_idx ON releases (master_id);
+
+-- Name-only attribute tables. Artists are kept only to recognise releases stored before artist
+-- entities, which the next sync fetches again.
+CREATE TABLE IF NOT EXISTS artists (
+	id INTEGER PRIMARY KEY,
+	release_id INT REFERENCES releases(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	UNIQUE (release_id, name)
+);
+CREATE TABLE IF NOT EXISTS genres (
+	id INTEGER PRIMARY KEY,
+	release_id INT REFERENCES releases(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	UNIQUE (release_id, name)
+);
+CREATE TABLE IF NOT EXISTS styles (
+	id INTEGER PRIMARY KEY,
+	release_id INT REFERENCES releases(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	UNIQUE (release_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS discogs_artists (
+	id INT PRIMARY KEY,
+	name TEXT NOT NULL,
+	resource_url TEXT,
+	real_name TEXT,
+	profile TEXT,
+	profile_fetched_at TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS release_artists (
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	position INT NOT NULL,
+	artist_id INT NOT NULL REFERENCES discogs_artists(id),
+	anv TEXT,
+	join_phrase TEXT,
+	PRIMARY KEY (release_id, position)
+);
+CREATE INDEX IF NOT EXISTS release_artists_artist_id_idx ON release_artists (artist_id);
+CREATE TABLE IF NOT EXISTS artist_name_variations (
+	artist_id INT NOT NULL REFERENCES discogs_artists(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	PRIMARY KEY (artist_id, name)
+);
+CREATE TABLE IF NOT EXISTS artist_relations (
+	artist_id INT NOT NULL REFERENCES discogs_artists(id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	related_id INT NOT NULL,
+	related_name TEXT NOT NULL,
+	active BOOLEAN,
+	PRIMARY KEY (artist_id, kind, related_id)
+);
+
+CREATE TABLE IF NOT EXISTS masters (
+	id INT PRIMARY KEY,
+	title TEXT,
+	year INT,
+	main_release INT,
+	uri TEXT,
+	fetched_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS formats (
+	id INTEGER PRIMARY KEY,
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	position INT NOT NULL,
+	name TEXT NOT NULL,
+	qty INT,
+	text TEXT
+);
+CREATE INDEX IF NOT EXISTS formats_release_id_idx ON formats (release_id);
+CREATE TABLE IF NOT EXISTS format_descriptions (
+	format_id INT NOT NULL REFERENCES formats(id) ON DELETE CASCADE,
+	description TEXT NOT NULL,
+	PRIMARY KEY (format_id, description)
+);
+
+CREATE TABLE IF NOT EXISTS tracks (
+	id INTEGER PRIMARY KEY,
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	sequence INT NOT NULL,
+	position TEXT,
+	type TEXT,
+	title TEXT NOT NULL,
+	duration TEXT,
+	duration_seconds INT
+);
+CREATE INDEX IF NOT EXISTS tracks_release_id_idx ON tracks (release_id);
+CREATE TABLE IF NOT EXISTS track_artists (
+	track_id INT NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+	sequence INT NOT NULL,
+	name TEXT NOT NULL,
+	role TEXT,
+	PRIMARY KEY (track_id, sequence)
+);
+
+CREATE TABLE IF NOT EXISTS credits (
+	id INTEGER PRIMARY KEY,
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	sequence INT NOT NULL,
+	artist_id INT,
+	name TEXT NOT NULL,
+	role TEXT NOT NULL,
+	tracks TEXT
+);
+CREATE INDEX IF NOT EXISTS credits_release_id_idx ON credits (release_id);
+
+CREATE TABLE IF NOT EXISTS release_companies (
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	position INT NOT NULL,
+	company_id INT,
+	name TEXT NOT NULL,
+	role TEXT,
+	catno TEXT,
+	PRIMARY KEY (release_id, kind, position)
+);
+CREATE INDEX IF NOT EXISTS release_companies_catno_idx ON release_companies (catno);
+
+CREATE TABLE IF NOT EXISTS identifiers (
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	position INT NOT NULL,
+	type TEXT NOT NULL,
+	value TEXT NOT NULL,
+	description TEXT,
+	normalized_value TEXT NOT NULL,
+	PRIMARY KEY (release_id, position)
+);
+CREATE INDEX IF NOT EXISTS identifiers_normalized_value_idx ON identifiers (normalized_value);
+
+CREATE TABLE IF NOT EXISTS community_snapshots (
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	captured_at TIMESTAMP NOT NULL,
+	have INT NOT NULL,
+	want INT NOT NULL,
+	rating_average DOUBLE PRECISION,
+	rating_count INT,
+	PRIMARY KEY (release_id, captured_at)
+);
+
+CREATE TABLE IF NOT EXISTS price_snapshots (
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	captured_at TIMESTAMP NOT NULL,
+	currency TEXT NOT NULL,
+	lowest_price DOUBLE PRECISION,
+	num_for_sale INT NOT NULL,
+	blocked_from_sale BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (release_id, captured_at)
+);
+
+CREATE TABLE IF NOT EXISTS labels (
+	id INT PRIMARY KEY,
+	name TEXT NOT NULL,
+	parent_id INT
+);
+CREATE TABLE IF NOT EXISTS label_releases (
+	label_id INT NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+	release_id INT NOT NULL,
+	PRIMARY KEY (label_id, release_id)
+);
+CREATE INDEX IF NOT EXISTS label_releases_release_id_idx ON label_releases (release_id);
+
+CREATE TABLE IF NOT EXISTS sync_runs (
+	id INTEGER PRIMARY KEY,
+	label_id INT NOT NULL,
+	started_at TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP,
+	status TEXT NOT NULL,
+	releases_listed INT NOT NULL DEFAULT 0,
+	releases_fetched INT NOT NULL DEFAULT 0,
+	releases_failed INT NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS sync_state (
+	label_id INT PRIMARY KEY,
+	run_id INT NOT NULL REFERENCES sync_runs(id),
+	pages_listed INT NOT NULL DEFAULT 0,
+	next_page_url TEXT NOT NULL,
+	listing_complete BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE TABLE IF NOT EXISTS sync_pending_releases (
+	label_id INT NOT NULL REFERENCES sync_state(label_id) ON DELETE CASCADE,
+	release_id INT NOT NULL,
+	PRIMARY KEY (label_id, release_id)
+);
+CREATE TABLE IF NOT EXISTS sync_failed_releases (
+	run_id INT NOT NULL REFERENCES sync_runs(id) ON DELETE CASCADE,
+	release_id INT NOT NULL,
+	error TEXT NOT NULL,
+	failed_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (run_id, release_id)
+);
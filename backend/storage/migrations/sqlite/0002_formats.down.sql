@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS format_descriptions;
+DROP TABLE IF EXISTS formats;
@@ -0,0 +1,18 @@
+-- From this version on each feature has a migration of its own. Version 1 already creates the
+-- tables of the features up to price snapshots, so versions 2 to 11 only create what is missing and
+-- let each of those features be reverted on its own.
+
+CREATE TABLE IF NOT EXISTS formats (
+	id INTEGER PRIMARY KEY,
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	position INT NOT NULL,
+	name TEXT NOT NULL,
+	qty INT,
+	text TEXT
+);
+CREATE INDEX IF NOT EXISTS formats_release_id_idx ON formats (release_id);
+CREATE TABLE IF NOT EXISTS format_descriptions (
+	format_id INT NOT NULL REFERENCES formats(id) ON DELETE CASCADE,
+	description TEXT NOT NULL,
+	PRIMARY KEY (format_id, description)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS track_artists;
+DROP TABLE IF EXISTS tracks;
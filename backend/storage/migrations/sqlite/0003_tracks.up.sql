@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS tracks (
+	id INTEGER PRIMARY KEY,
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	sequence INT NOT NULL,
+	position TEXT,
+	type TEXT,
+	title TEXT NOT NULL,
+	duration TEXT,
+	duration_seconds INT
+);
+CREATE INDEX IF NOT EXISTS tracks_release_id_idx ON tracks (release_id);
+CREATE TABLE IF NOT EXISTS track_artists (
+	track_id INT NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+	sequence INT NOT NULL,
+	name TEXT NOT NULL,
+	role TEXT,
+	PRIMARY KEY (track_id, sequence)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS credits (
+	id INTEGER PRIMARY KEY,
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	sequence INT NOT NULL,
+	artist_id INT,
+	name TEXT NOT NULL,
+	role TEXT NOT NULL,
+	tracks TEXT
+);
+CREATE INDEX IF NOT EXISTS credits_release_id_idx ON credits (release_id);
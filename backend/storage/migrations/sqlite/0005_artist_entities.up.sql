@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS discogs_artists (
+	id INT PRIMARY KEY,
+	name TEXT NOT NULL,
+	resource_url TEXT,
+	real_name TEXT,
+	profile TEXT,
+	profile_fetched_at TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS release_artists (
+	release_id INT NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+	position INT NOT NULL,
+	artist_id INT NOT NULL REFERENCES discogs_artists(id),
+	anv TEXT,
+	join_phrase TEXT,
+	PRIMARY KEY (release_id, position)
+);
+CREATE INDEX IF NOT EXISTS release_artists_artist_id_idx ON release_artists (artist_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS artist_relations;
+DROP TABLE IF EXISTS artist_name_variations;
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	postgres, err := loadMigrations(migrationFiles, Postgres.migrationsDir())
+	if err != nil {
+		t.Fatalf("failed to load embedded Postgres migrations: %v", err)
+	}
+	sqlite, err := loadMigrations(migrationFiles, SQLite.migrationsDir())
+	if err != nil {
+		t.Fatalf("failed to load embedded SQLite migrations: %v", err)
+	}
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("expected the same migrations for both dialects, got %d and %d", len(postgres), len(sqlite))
+	}
+	for _, migrations := range [][]migration{postgres, sqlite} {
+		if len(migrations) == 0 || migrations[0].version != 1 || migrations[0].name != "initial_schema" {
+			t.Fatalf("unexpected embedded migrations: %+v", migrations)
+		}
+		if !strings.Contains(migrations[0].up, "CREATE TABLE IF NOT EXISTS releases") ||
+			!strings.Contains(migrations[0].down, "DROP TABLE IF EXISTS releases") {
+			t.Errorf("unexpected statements of the initial migration")
+		}
+	}
+	for i := range postgres {
+		if postgres[i].name != sqlite[i].name {
+			t.Errorf("migration %d is named %s for Postgres but %s for SQLite", i+1, postgres[i].name, sqlite[i].name)
+		}
+	}
+
+	invalid := map[string]fstest.MapFS{
+		"missing down": {
+			"migrations/0001_a.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"version gap": {
+			"migrations/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"migrations/0001_a.down.sql": {Data: []byte("SELECT 1;")},
+			"migrations/0003_c.up.sql":   {Data: []byte("SELECT 1;")},
+			"migrations/0003_c.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"unexpected name": {
+			"migrations/first.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range invalid {
+		if _, err := loadMigrations(fsys, "migrations"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func expectMigrationLock(mock sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations ORDER BY version").WillReturnRows(applied)
+}
+
+func TestMigrate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	migrations, err := loadMigrations(migrationFiles, Postgres.migrationsDir())
+	if err != nil {
+		t.Fatalf("failed to load embedded Postgres migrations: %v", err)
+	}
+
+	expectMigrationLock(mock, sqlmock.NewRows([]string{"version", "applied_at"}))
+	for _, migration := range migrations {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(migration.up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(migration.version, migration.name, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := Migrate(db, Postgres); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrateRollsBackFailedMigration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	expectMigrationLock(mock, sqlmock.NewRows([]string{"version", "applied_at"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS releases").WillReturnError(fmt.Errorf("permission denied"))
+	mock.ExpectRollback()
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := Migrate(db, Postgres); err == nil {
+		t.Fatal("expected an error when a migration fails")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrateToRevertsMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	expectMigrationLock(mock, sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("DROP TABLE IF EXISTS sync_failed_releases").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations WHERE version = \\$1").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := MigrateTo(db, Postgres, 0); err != nil {
+		t.Fatalf("failed to migrate to version 0: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrateRejectsNewerSchema(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	expectMigrationLock(mock, sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()).AddRow(99, time.Now()))
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := Migrate(db, Postgres); err == nil {
+		t.Fatal("expected an error for a schema newer than the known migrations")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
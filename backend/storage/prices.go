@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"sort"
+	"time"
+)
+
+// Price table names
+const (
+	priceSnapshotsTableName = "price_snapshots"
+)
+
+// SQL queries for marketplace prices
+const (
+	insertPriceSnapshotSQL = `
+		INSERT INTO %s (release_id, captured_at, currency, lowest_price, num_for_sale, blocked_from_sale)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (release_id, captured_at) DO NOTHING;
+	`
+	fetchReleasesToPriceSQL = `
+		SELECT r.id FROM %s r
+		WHERE %s
+		ORDER BY r.id
+	`
+	fetchPriceHistorySQL = `
+		SELECT ps.captured_at, ps.currency, ps.lowest_price, ps.num_for_sale, ps.blocked_from_sale
+		FROM %s ps
+		WHERE ps.release_id = $1%s
+		ORDER BY ps.captured_at
+	`
+	// Only the latest snapshot of each release in the currency is aggregated, so releases synced
+	// more often do not weigh more. Prices come sorted within each name for the median.
+	fetchPriceStatsSQL = `
+		SELECT a.name, ps.lowest_price, ps.num_for_sale
+		FROM %s ps
+		JOIN %s a ON a.release_id = ps.release_id
+		WHERE ps.currency = $1
+			AND ps.captured_at = (
+				SELECT MAX(l.captured_at) FROM %s l
+				WHERE l.release_id = ps.release_id AND l.currency = ps.currency
+			)%s
+		ORDER BY a.name, ps.lowest_price
+	`
+)
+
+// FetchReleasesToPrice returns the ids of the stored releases of the filtered label. Prices are
+// collected for all of them on every sync to build their history.
+func FetchReleasesToPrice(db *sql.DB, labelFilter models.LabelFilter) ([]int32, error) {
+	query := fmt.Sprintf(fetchReleasesToPriceSQL, releasesTableName, labelCondition(labelFilter, 1))
+	rows, err := db.Query(query, labelFilter.LabelId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases to price: %v", err)
+	}
+	defer rows.Close()
+
+	var releaseIDs []int32
+	for rows.Next() {
+		var releaseID int32
+		if err := rows.Scan(&releaseID); err != nil {
+			return nil, fmt.Errorf("failed to scan release id: %v", err)
+		}
+		releaseIDs = append(releaseIDs, releaseID)
+	}
+	return releaseIDs, rows.Err()
+}
+
+// StorePriceSnapshot records the marketplace offer of a release. Earlier snapshots are kept to
+// follow the prices over time.
+func StorePriceSnapshot(db *sql.DB, releaseID int32, price *models.PriceSnapshot) error {
+	var lowestPrice sql.NullFloat64
+	if price.LowestPrice != nil {
+		lowestPrice = sql.NullFloat64{Float64: *price.LowestPrice, Valid: true}
+	}
+
+	query := fmt.Sprintf(insertPriceSnapshotSQL, priceSnapshotsTableName)
+	if _, err := db.Exec(query, releaseID, price.CapturedAt, price.Currency, lowestPrice, price.NumForSale,
+		price.BlockedFromSale); err != nil {
+		return fmt.Errorf("failed to store price snapshot of release %d: %v", releaseID, err)
+	}
+	return nil
+}
+
+// FetchPriceHistory returns the price snapshots of a release in the order they were taken,
+// optionally limited to one currency and to those taken from and until the given times.
+func FetchPriceHistory(db *sql.DB, releaseID int32, currency string, from *time.Time, until *time.Time) ([]models.PriceSnapshot, error) {
+	args := []interface{}{releaseID}
+	var conditions string
+	if currency != "" {
+		args = append(args, currency)
+		conditions += fmt.Sprintf(" AND ps.currency = $%d", len(args))
+	}
+	if from != nil {
+		args = append(args, *from)
+		conditions += fmt.Sprintf(" AND ps.captured_at >= $%d", len(args))
+	}
+	if until != nil {
+		args = append(args, *until)
+		conditions += fmt.Sprintf(" AND ps.captured_at <= $%d", len(args))
+	}
+
+	rows, err := db.Query(fmt.Sprintf(fetchPriceHistorySQL, priceSnapshotsTableName, conditions), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price history of release %d: %v", releaseID, err)
+	}
+	defer rows.Close()
+
+	history := []models.PriceSnapshot{}
+	for rows.Next() {
+		var price models.PriceSnapshot
+		var lowestPrice sql.NullFloat64
+		if err := rows.Scan(&price.CapturedAt, &price.Currency, &lowestPrice, &price.NumForSale,
+			&price.BlockedFromSale); err != nil {
+			return nil, fmt.Errorf("failed to scan price snapshot: %v", err)
+		}
+		if lowestPrice.Valid {
+			price.LowestPrice = &lowestPrice.Float64
+		}
+		history = append(history, price)
+	}
+	return history, rows.Err()
+}
+
+// FetchPriceStats aggregates the latest prices in the currency per name of an attribute table,
+// such as styles or genres, ordered from the highest median price. Names without any priced
+// release come last.
+func FetchPriceStats(db *sql.DB, tableName string, currency string, labelFilter models.LabelFilter) ([]models.PriceStats, error) {
+	args := []interface{}{currency}
+	var labelScope string
+	if labelFilter.LabelId != 0 {
+		args = append(args, labelFilter.LabelId)
+		labelScope = fmt.Sprintf(communityLabelScopeSQL, "ps", releasesTableName, labelCondition(labelFilter, len(args)))
+	}
+
+	query := fmt.Sprintf(fetchPriceStatsSQL, priceSnapshotsTableName, tableName, priceSnapshotsTableName, labelScope)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price stats from %s: %v", tableName, err)
+	}
+	defer rows.Close()
+
+	statsList := []models.PriceStats{}
+	var prices []float64
+	for rows.Next() {
+		var name string
+		var lowestPrice sql.NullFloat64
+		var numForSale int
+		if err := rows.Scan(&name, &lowestPrice, &numForSale); err != nil {
+			return nil, fmt.Errorf("failed to scan price: %v", err)
+		}
+
+		if len(statsList) == 0 || statsList[len(statsList)-1].Name != name {
+			if len(statsList) > 0 {
+				aggregatePrices(&statsList[len(statsList)-1], prices)
+			}
+			statsList = append(statsList, models.PriceStats{Name: name})
+			prices = nil
+		}
+		stats := &statsList[len(statsList)-1]
+		stats.ReleaseCount++
+		stats.NumForSale += numForSale
+		if lowestPrice.Valid {
+			prices = append(prices, lowestPrice.Float64)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(statsList) > 0 {
+		aggregatePrices(&statsList[len(statsList)-1], prices)
+	}
+
+	sort.SliceStable(statsList, func(i, j int) bool {
+		if statsList[j].MedianPrice == nil {
+			return statsList[i].MedianPrice != nil
+		}
+		return statsList[i].MedianPrice != nil && *statsList[i].MedianPrice > *statsList[j].MedianPrice
+	})
+	return statsList, nil
+}
+
+// aggregatePrices sets the minimum and median of prices sorted in ascending order.
+func aggregatePrices(stats *models.PriceStats, prices []float64) {
+	stats.PricedCount = len(prices)
+	if len(prices) == 0 {
+		return
+	}
+
+	minPrice := prices[0]
+	median := prices[len(prices)/2]
+	if len(prices)%2 == 0 {
+		median = (prices[len(prices)/2-1] + median) / 2
+	}
+	stats.MinPrice = &minPrice
+	stats.MedianPrice = &median
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+func TestStorePriceSnapshot(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	capturedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec("INSERT INTO price_snapshots").
+		WithArgs(int32(7), capturedAt, "EUR", nil, 0, true).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	price := &models.PriceSnapshot{Currency: "EUR", BlockedFromSale: true, CapturedAt: capturedAt}
+	if err := StorePriceSnapshot(db, 7, price); err != nil {
+		t.Fatalf("failed to store price snapshot: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchPriceStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT a.name, ps.lowest_price, ps.num_for_sale FROM price_snapshots ps JOIN styles a ON a.release_id = ps.release_id WHERE ps.currency = \$1 .* AND ps.release_id IN \(SELECT r.id FROM releases r WHERE .*\) ORDER BY a.name, ps.lowest_price`).
+		WithArgs("USD", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "lowest_price", "num_for_sale"}).
+			AddRow("Bop", 10.0, 3).
+			AddRow("Bop", 30.0, 1).
+			AddRow("Free Jazz", nil, 0).
+			AddRow("Hard Bop", 20.0, 4).
+			AddRow("Hard Bop", 25.0, 2).
+			AddRow("Hard Bop", 40.0, 1))
+
+	stats, err := FetchPriceStats(db, StylesTableName, "USD", models.LabelFilter{LabelId: 5})
+	if err != nil {
+		t.Fatalf("failed to fetch price stats: %v", err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("expected 3 styles, got %d", len(stats))
+	}
+
+	hardBop, bop, freeJazz := stats[0], stats[1], stats[2]
+	if hardBop.Name != "Hard Bop" || *hardBop.MedianPrice != 25 || *hardBop.MinPrice != 20 || hardBop.NumForSale != 7 {
+		t.Errorf("unexpected Hard Bop price stats: %+v", hardBop)
+	}
+	if bop.Name != "Bop" || *bop.MedianPrice != 20 || bop.PricedCount != 2 {
+		t.Errorf("unexpected Bop price stats: %+v", bop)
+	}
+	if freeJazz.Name != "Free Jazz" || freeJazz.ReleaseCount != 1 || freeJazz.PricedCount != 0 || freeJazz.MedianPrice != nil {
+		t.Errorf("unexpected Free Jazz price stats: %+v", freeJazz)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"modernc.org/sqlite"
+	"regexp"
+	"time"
+)
+
+const defaultSQLitePath = "discogs.sqlite"
+
+// sqliteOptions enforce the foreign keys the cascading deletes rely on, wait for locks instead of
+// failing and write times in a format SQLite compares and reads back as times.
+const sqliteOptions = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+
+var ilikeOperator = regexp.MustCompile(`\bILIKE\b`)
+
+// OpenSQLiteStore opens the SQLite database at path, creating the file when it does not exist.
+// SQLite is pure Go here, so the tool runs without a database server.
+func OpenSQLiteStore(path string) (*SQLStore, error) {
+	db := sql.OpenDB(sqliteConnector{dsn: fmt.Sprintf("file:%s?%s", path, sqliteOptions)})
+	// SQLite allows a single writer, one connection keeps the sync workers from failing on locks.
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open SQLite database %s: %v", path, err)
+	}
+	return &SQLStore{db: db, dialect: SQLite}, nil
+}
+
+// sqliteConnector opens SQLite connections that accept the queries written for Postgres.
+type sqliteConnector struct {
+	dsn string
+}
+
+func (c sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Driver().Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteConn{conn.(sqliteDriverConn)}, nil
+}
+
+func (c sqliteConnector) Driver() driver.Driver {
+	return &sqlite.Driver{}
+}
+
+// sqliteDriverConn is what database/sql uses of a SQLite connection.
+type sqliteDriverConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// sqliteConn translates the queries shared with Postgres. ILIKE becomes LIKE, which SQLite
+// already compares case-insensitively for ASCII. Times are bound in UTC, so that the stored text
+// orders like the times it holds.
+type sqliteConn struct {
+	sqliteDriverConn
+}
+
+func (c sqliteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.sqliteDriverConn.Prepare(rewriteSQLiteQuery(query))
+}
+
+func (c sqliteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.sqliteDriverConn.PrepareContext(ctx, rewriteSQLiteQuery(query))
+}
+
+func (c sqliteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.sqliteDriverConn.ExecContext(ctx, rewriteSQLiteQuery(query), args)
+}
+
+func (c sqliteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.sqliteDriverConn.QueryContext(ctx, rewriteSQLiteQuery(query), args)
+}
+
+func (c sqliteConn) CheckNamedValue(value *driver.NamedValue) error {
+	converted, err := driver.DefaultParameterConverter.ConvertValue(value.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := converted.(time.Time); ok {
+		converted = t.UTC()
+	}
+	value.Value = converted
+	return nil
+}
+
+func rewriteSQLiteQuery(query string) string {
+	return ilikeOperator.ReplaceAllString(query, "LIKE")
+}
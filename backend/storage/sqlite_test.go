@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRewriteSQLiteQuery(t *testing.T) {
+	query := rewriteSQLiteQuery("SELECT id FROM tracks WHERE title ILIKE $1 AND position NOT ILIKE $2")
+	expected := "SELECT id FROM tracks WHERE title LIKE $1 AND position NOT LIKE $2"
+	if query != expected {
+		t.Errorf("expected %q, got %q", expected, query)
+	}
+}
+
+func openTestSQLiteStore(t *testing.T) *SQLStore {
+	t.Helper()
+	store, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "discogs.sqlite"))
+	if err != nil {
+		t.Fatalf("failed to open SQLite store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("failed to migrate SQLite store: %v", err)
+	}
+	return store
+}
+
+func TestSQLiteStoreMigratesDown(t *testing.T) {
+	store := openTestSQLiteStore(t)
+
+	if err := store.MigrateTo(0); err != nil {
+		t.Fatalf("failed to migrate to version 0: %v", err)
+	}
+	migrations, err := store.FetchMigrations()
+	if err != nil {
+		t.Fatalf("failed to fetch migrations: %v", err)
+	}
+	for _, migration := range migrations {
+		if migration.AppliedAt != nil {
+			t.Errorf("expected migration %d to be reverted", migration.Version)
+		}
+	}
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("failed to migrate again: %v", err)
+	}
+	migrations, err = store.FetchMigrations()
+	if err != nil {
+		t.Fatalf("failed to fetch migrations: %v", err)
+	}
+	for _, migration := range migrations {
+		if migration.AppliedAt == nil {
+			t.Errorf("expected migration %d to be applied", migration.Version)
+		}
+	}
+}
+
+func TestSQLiteStoreRecreatesFeatureTables(t *testing.T) {
+	store := openTestSQLiteStore(t)
+
+	// Reverting to the initial schema drops the tables of later features, which migrating again
+	// recreates as version 1 created them.
+	if err := store.MigrateTo(1); err != nil {
+		t.Fatalf("failed to migrate to version 1: %v", err)
+	}
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("failed to migrate again: %v", err)
+	}
+	testStoreReleases(t, store)
+	testStoreArtists(t, store)
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"os"
+	"time"
+)
+
+// Dialect is the SQL database a store keeps its data in.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+func (d Dialect) migrationsDir() string {
+	return "migrations/" + string(d)
+}
+
+func (d Dialect) timestampType() string {
+	if d == SQLite {
+		return "TIMESTAMP"
+	}
+	return "TIMESTAMPTZ"
+}
+
+// Facet is an attribute of releases that names are listed and prices aggregated by.
+type Facet string
+
+const (
+	FacetStyle Facet = "style"
+	FacetGenre Facet = "genre"
+)
+
+// tableName returns the attribute table the facet's names are stored in.
+func (f Facet) tableName() (string, error) {
+	switch f {
+	case FacetStyle:
+		return StylesTableName, nil
+	case FacetGenre:
+		return GenresTableName, nil
+	}
+	return "", fmt.Errorf("unknown facet: %s", f)
+}
+
+// Store keeps the synced releases and everything fetched along with them. The GraphQL resolvers
+// and the sync only go through it, so they work the same whichever database holds the data.
+type Store interface {
+	// Schema
+	Migrate() error
+	MigrateDown() error
+	MigrateTo(version int) error
+	FetchMigrations() ([]models.Migration, error)
+	Close() error
+
+	// Releases
+	StoreRelease(release *models.Release) error
+	StoreReleases(releases []*models.Release) map[int32]error
+	FetchRelease(releaseID int32) (*models.Release, error)
+	FetchReleases(filter models.ReleaseFilter, order models.ReleaseOrder, limit int, offset int) ([]*models.Release, error)
+	FetchReleasesByIdentifier(value string, identifierType string) ([]*models.Release, error)
+	FetchReleaseCounts(filter models.ReleaseFilter, groupByMaster bool) (models.CountResult, error)
+	FetchReleaseFetchTimes() (map[int32]time.Time, error)
+	FetchUniqueNames(facet Facet, labelFilter models.LabelFilter) ([]*models.UniqueName, error)
+
+	// Artists, masters and labels
+	FetchUniqueArtists(labelFilter models.LabelFilter) ([]*models.Artist, error)
+	FetchArtist(artistID int) (*models.Artist, error)
+	FetchArtistsToEnrich(labelFilter models.LabelFilter, refetchBefore *time.Time) ([]int, error)
+	StoreArtistProfile(artist *models.Artist, fetchedAt time.Time) error
+	FetchMaster(masterID int) (*models.Master, error)
+	FetchMastersToFetch(labelFilter models.LabelFilter, refetchBefore *time.Time) ([]int, error)
+	StoreMaster(master *models.Master, fetchedAt time.Time) error
+	StoreLabel(label *models.Label) error
+	FetchLabels(parentID int) ([]*models.Label, error)
+
+	// Community statistics and marketplace prices
+	FetchCommunityHistory(releaseID int32, from *time.Time, until *time.Time) ([]models.CommunityStats, error)
+	FetchMostWanted(labelFilter models.LabelFilter, limit int) ([]*models.Release, error)
+	FetchTrending(labelFilter models.LabelFilter, from time.Time, until time.Time, limit int) ([]models.WantGrowth, error)
+	FetchReleasesToSnapshot(labelFilter models.LabelFilter, capturedSince time.Time) ([]int32, error)
+	FetchReleasesToPrice(labelFilter models.LabelFilter) ([]int32, error)
+	StorePriceSnapshot(releaseID int32, price *models.PriceSnapshot) error
+	FetchPriceHistory(releaseID int32, currency string, from *time.Time, until *time.Time) ([]models.PriceSnapshot, error)
+	FetchPriceStats(facet Facet, currency string, labelFilter models.LabelFilter) ([]models.PriceStats, error)
+
+	// Sync runs
+	StartSyncRun(labelID int, firstPageURL string, startedAt time.Time) (*models.SyncState, error)
+	SaveListedPage(state *models.SyncState, listedIDs []int32, pendingIDs []int32, nextPageURL string) error
+	FetchPendingReleaseIDs(labelID int) ([]int32, error)
+	CompletePendingRelease(state *models.SyncState, releaseID int32) error
+	FailPendingRelease(state *models.SyncState, releaseID int32, reason error, failedAt time.Time) error
+	FinishSyncRun(state *models.SyncState, runErr error, finishedAt time.Time) error
+	FetchSyncRun(runID int) (*models.SyncRun, error)
+	FetchLastSyncedAt(labelID int) (*time.Time, error)
+}
+
+var (
+	_ Store = (*SQLStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// SQLStore is the Store backed by a SQL database. Postgres and SQLite share its queries, the
+// dialect only picks the migrations and the few statements that differ.
+type SQLStore struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewPostgresStore returns a store on an open Postgres connection pool.
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: Postgres}
+}
+
+// OpenStore opens the store chosen by STORAGE_DRIVER, postgres by default or sqlite. Postgres
+// is configured by the POSTGRES_* variables, SQLite by SQLITE_PATH.
+func OpenStore() (*SQLStore, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); Dialect(driver) {
+	case "", Postgres:
+		db, err := openPostgres()
+		if err != nil {
+			return nil, err
+		}
+		return NewPostgresStore(db), nil
+	case SQLite:
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = defaultSQLitePath
+		}
+		return OpenSQLiteStore(path)
+	default:
+		return nil, fmt.Errorf("STORAGE_DRIVER must be postgres or sqlite, got: %v", driver)
+	}
+}
+
+func (s *SQLStore) Migrate() error {
+	return Migrate(s.db, s.dialect)
+}
+
+func (s *SQLStore) MigrateDown() error {
+	return MigrateDown(s.db, s.dialect)
+}
+
+func (s *SQLStore) MigrateTo(version int) error {
+	return MigrateTo(s.db, s.dialect, version)
+}
+
+func (s *SQLStore) FetchMigrations() ([]models.Migration, error) {
+	return FetchMigrations(s.db, s.dialect)
+}
+
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLStore) StoreRelease(release *models.Release) error {
+	return StoreRelease(s.db, release)
+}
+
+func (s *SQLStore) StoreReleases(releases []*models.Release) map[int32]error {
+	return StoreReleases(s.db, releases)
+}
+
+func (s *SQLStore) FetchRelease(releaseID int32) (*models.Release, error) {
+	return FetchRelease(s.db, releaseID)
+}
+
+func (s *SQLStore) FetchReleases(filter models.ReleaseFilter, order models.ReleaseOrder, limit int, offset int) ([]*models.Release, error) {
+	return FetchReleases(s.db, filter, order, limit, offset)
+}
+
+func (s *SQLStore) FetchReleasesByIdentifier(value string, identifierType string) ([]*models.Release, error) {
+	return FetchReleasesByIdentifier(s.db, value, identifierType)
+}
+
+func (s *SQLStore) FetchReleaseCounts(filter models.ReleaseFilter, groupByMaster bool) (models.CountResult, error) {
+	return FetchReleaseCounts(s.db, filter, groupByMaster)
+}
+
+func (s *SQLStore) FetchReleaseFetchTimes() (map[int32]time.Time, error) {
+	return FetchReleaseFetchTimes(s.db)
+}
+
+func (s *SQLStore) FetchUniqueNames(facet Facet, labelFilter models.LabelFilter) ([]*models.UniqueName, error) {
+	tableName, err := facet.tableName()
+	if err != nil {
+		return nil, err
+	}
+	return FetchUniqueNames(s.db, tableName, labelFilter)
+}
+
+func (s *SQLStore) FetchUniqueArtists(labelFilter models.LabelFilter) ([]*models.Artist, error) {
+	return FetchUniqueArtists(s.db, labelFilter)
+}
+
+func (s *SQLStore) FetchArtist(artistID int) (*models.Artist, error) {
+	return FetchArtist(s.db, artistID)
+}
+
+func (s *SQLStore) FetchArtistsToEnrich(labelFilter models.LabelFilter, refetchBefore *time.Time) ([]int, error) {
+	return FetchArtistsToEnrich(s.db, labelFilter, refetchBefore)
+}
+
+func (s *SQLStore) StoreArtistProfile(artist *models.Artist, fetchedAt time.Time) error {
+	return StoreArtistProfile(s.db, artist, fetchedAt)
+}
+
+func (s *SQLStore) FetchMaster(masterID int) (*models.Master, error) {
+	return FetchMaster(s.db, masterID)
+}
+
+func (s *SQLStore) FetchMastersToFetch(labelFilter models.LabelFilter, refetchBefore *time.Time) ([]int, error) {
+	return FetchMastersToFetch(s.db, labelFilter, refetchBefore)
+}
+
+func (s *SQLStore) StoreMaster(master *models.Master, fetchedAt time.Time) error {
+	return StoreMaster(s.db, master, fetchedAt)
+}
+
+func (s *SQLStore) StoreLabel(label *models.Label) error {
+	return StoreLabel(s.db, label)
+}
+
+func (s *SQLStore) FetchLabels(parentID int) ([]*models.Label, error) {
+	return FetchLabels(s.db, parentID)
+}
+
+func (s *SQLStore) FetchCommunityHistory(releaseID int32, from *time.Time, until *time.Time) ([]models.CommunityStats, error) {
+	return FetchCommunityHistory(s.db, releaseID, from, until)
+}
+
+func (s *SQLStore) FetchMostWanted(labelFilter models.LabelFilter, limit int) ([]*models.Release, error) {
+	return FetchMostWanted(s.db, labelFilter, limit)
+}
+
+func (s *SQLStore) FetchTrending(labelFilter models.LabelFilter, from time.Time, until time.Time, limit int) ([]models.WantGrowth, error) {
+	return FetchTrending(s.db, labelFilter, from, until, limit)
+}
+
+func (s *SQLStore) FetchReleasesToSnapshot(labelFilter models.LabelFilter, capturedSince time.Time) ([]int32, error) {
+	return FetchReleasesToSnapshot(s.db, labelFilter, capturedSince)
+}
+
+func (s *SQLStore) FetchReleasesToPrice(labelFilter models.LabelFilter) ([]int32, error) {
+	return FetchReleasesToPrice(s.db, labelFilter)
+}
+
+func (s *SQLStore) StorePriceSnapshot(releaseID int32, price *models.PriceSnapshot) error {
+	return StorePriceSnapshot(s.db, releaseID, price)
+}
+
+func (s *SQLStore) FetchPriceHistory(releaseID int32, currency string, from *time.Time, until *time.Time) ([]models.PriceSnapshot, error) {
+	return FetchPriceHistory(s.db, releaseID, currency, from, until)
+}
+
+func (s *SQLStore) FetchPriceStats(facet Facet, currency string, labelFilter models.LabelFilter) ([]models.PriceStats, error) {
+	tableName, err := facet.tableName()
+	if err != nil {
+		return nil, err
+	}
+	return FetchPriceStats(s.db, tableName, currency, labelFilter)
+}
+
+func (s *SQLStore) StartSyncRun(labelID int, firstPageURL string, startedAt time.Time) (*models.SyncState, error) {
+	return StartSyncRun(s.db, labelID, firstPageURL, startedAt)
+}
+
+func (s *SQLStore) SaveListedPage(state *models.SyncState, listedIDs []int32, pendingIDs []int32, nextPageURL string) error {
+	return SaveListedPage(s.db, state, listedIDs, pendingIDs, nextPageURL)
+}
+
+func (s *SQLStore) FetchPendingReleaseIDs(labelID int) ([]int32, error) {
+	return FetchPendingReleaseIDs(s.db, labelID)
+}
+
+func (s *SQLStore) CompletePendingRelease(state *models.SyncState, releaseID int32) error {
+	return CompletePendingRelease(s.db, state, releaseID)
+}
+
+func (s *SQLStore) FailPendingRelease(state *models.SyncState, releaseID int32, reason error, failedAt time.Time) error {
+	return FailPendingRelease(s.db, state, releaseID, reason, failedAt)
+}
+
+func (s *SQLStore) FinishSyncRun(state *models.SyncState, runErr error, finishedAt time.Time) error {
+	return FinishSyncRun(s.db, state, runErr, finishedAt)
+}
+
+func (s *SQLStore) FetchSyncRun(runID int) (*models.SyncRun, error) {
+	return FetchSyncRun(s.db, runID)
+}
+
+func (s *SQLStore) FetchLastSyncedAt(labelID int) (*time.Time, error) {
+	return FetchLastSyncedAt(s.db, labelID)
+}
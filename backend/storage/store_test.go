@@ -0,0 +1,802 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+// The conformance suite checks that every Store answers the same. Postgres is only tested when
+// TEST_POSTGRES_URL points to a database the suite may wipe.
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		return NewMemoryStore()
+	})
+}
+
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		return openTestSQLiteStore(t)
+	})
+}
+
+func TestPostgresStore(t *testing.T) {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+	testStore(t, func(t *testing.T) Store {
+		db, err := sql.Open("postgres", url)
+		if err != nil {
+			t.Fatalf("failed to open Postgres database: %v", err)
+		}
+		store := NewPostgresStore(db)
+		t.Cleanup(func() { store.Close() })
+		if err := store.MigrateTo(0); err != nil {
+			t.Fatalf("failed to empty Postgres database: %v", err)
+		}
+		if err := store.Migrate(); err != nil {
+			t.Fatalf("failed to migrate Postgres database: %v", err)
+		}
+		return store
+	})
+}
+
+func testStore(t *testing.T, open func(t *testing.T) Store) {
+	tests := map[string]func(t *testing.T, store Store){
+		"releases":    testStoreReleases,
+		"batches":     testStoreBatches,
+		"filters":     testStoreFilters,
+		"counts":      testStoreCounts,
+		"identifiers": testStoreIdentifiers,
+		"artists":     testStoreArtists,
+		"masters":     testStoreMasters,
+		"labels":      testStoreLabels,
+		"community":   testStoreCommunity,
+		"prices":      testStorePrices,
+		"sync":        testStoreSync,
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, open(t))
+		})
+	}
+}
+
+var conformanceSyncedAt = time.Date(2024, 10, 24, 12, 0, 0, 0, time.UTC)
+
+// conformanceReleases are listed by Warp (5), its sublabel (6) and another label (7).
+func conformanceReleases() map[int][]*models.Release {
+	return map[int][]*models.Release{
+		5: {
+			{
+				Id: 1, Title: "Selected Ambient Works 85-92", Year: 1992, Country: "UK", MasterId: 10,
+				Labels:      []models.ReleaseLabel{{Id: 5, Name: "Warp Records", Catno: "WARP 2"}},
+				Companies:   []models.Company{{Id: 900, Name: "MPO", Role: "Pressed By"}},
+				Identifiers: []models.Identifier{{Type: "Barcode", Value: "5 021603 05417 9"}},
+				Community:   &models.CommunityStats{Have: 100, Want: 40, RatingAverage: 4.5, RatingCount: 20},
+				Artists:     []models.ReleaseArtist{{Id: 45, Name: "Aphex Twin"}},
+				Styles:      []string{"Ambient", "Techno"},
+				Genres:      []string{"Electronic"},
+				Formats:     []models.Format{{Name: "Vinyl", Qty: 2, Descriptions: []string{"Album", "LP"}}},
+				Tracks: []models.Track{
+					{Type: "heading", Title: "Side A"},
+					{Position: "A1", Title: "Xtal", Duration: "4:51", DurationSeconds: 291},
+					{Position: "A2", Title: "Tha", Duration: "9:01", DurationSeconds: 541},
+					{Type: "heading", Title: "Side B"},
+					{Position: "B1", Title: "Pulsewidth", Duration: "3:47", DurationSeconds: 227},
+				},
+				Credits: []models.Credit{{ArtistId: 77, Name: "Kevin Metcalfe", Role: "Mastered By"}},
+			},
+			{
+				Id: 2, Title: "Richard D. James Album", Year: 1996, Country: "UK",
+				Labels:    []models.ReleaseLabel{{Id: 5, Name: "Warp Records", Catno: "WARP 1"}},
+				Companies: []models.Company{{Id: 900, Name: "MPO", Role: "Pressed By"}, {Id: 901, Name: "PDO", Role: "Made By"}},
+				Community: &models.CommunityStats{Have: 300, Want: 90},
+				Artists:   []models.ReleaseArtist{{Id: 45, Name: "Aphex Twin", Anv: "AFX"}},
+				Styles:    []string{"IDM"},
+				Genres:    []string{"Electronic"},
+				Formats:   []models.Format{{Name: "CD", Qty: 1, Descriptions: []string{"Album"}}},
+				Tracks: []models.Track{{Position: "1", Title: "4", Duration: "3:37", DurationSeconds: 217,
+					Artists: []models.TrackArtist{{Name: "Aphex Twin", Role: "Written-By"}}}},
+			},
+		},
+		6: {
+			{
+				// Discogs lists its catalog number as "none", which is stored empty. The reissue kept the barcode.
+				Id: 3, Title: "Selected Ambient Works 85-92", Year: 2008, Country: "Europe", MasterId: 10,
+				Labels: []models.ReleaseLabel{{Id: 6, Name: "Warp Sublabel"}},
+				Identifiers: []models.Identifier{
+					{Type: "Barcode", Value: "5021603054179"},
+					{Type: "Matrix / Runout", Value: "WARPLP6 A1", Description: "Side A"},
+				},
+				Artists: []models.ReleaseArtist{{Id: 45, Name: "Aphex Twin"}},
+				Styles:  []string{"Ambient"},
+				Genres:  []string{"Electronic"},
+				Formats: []models.Format{{Name: "Vinyl", Qty: 2, Descriptions: []string{"LP", "Reissue"}}},
+			},
+		},
+		7: {
+			{
+				Id: 4, Title: "Music Has The Right To Children", Year: 1998, Country: "UK",
+				Labels:  []models.ReleaseLabel{{Id: 7, Name: "Skam"}, {Id: 7, Name: "Skam", Catno: "SKA 8"}},
+				Artists: []models.ReleaseArtist{{Id: 46, Name: "Boards Of Canada"}},
+				Styles:  []string{"Downtempo"},
+				Genres:  []string{"Electronic", "Hip Hop"},
+				Formats: []models.Format{{Name: "Vinyl", Qty: 2}},
+			},
+		},
+	}
+}
+
+// seedStore stores the conformance labels and syncs their releases.
+func seedStore(t *testing.T, store Store) {
+	t.Helper()
+	warp := 5
+	for _, label := range []*models.Label{{Id: 5, Name: "Warp Records"}, {Id: 6, Name: "Warp Sublabel", ParentId: &warp}, {Id: 7, Name: "Skam"}} {
+		if err := store.StoreLabel(label); err != nil {
+			t.Fatalf("failed to store label %d: %v", label.Id, err)
+		}
+	}
+
+	for labelID, releases := range conformanceReleases() {
+		state, err := store.StartSyncRun(labelID, fmt.Sprintf("https://api.discogs.com/labels/%d/releases", labelID), conformanceSyncedAt)
+		if err != nil {
+			t.Fatalf("failed to start sync run of label %d: %v", labelID, err)
+		}
+		var releaseIDs []int32
+		for _, release := range releases {
+			releaseIDs = append(releaseIDs, release.Id)
+		}
+		if err := store.SaveListedPage(state, releaseIDs, releaseIDs, ""); err != nil {
+			t.Fatalf("failed to save listed page of label %d: %v", labelID, err)
+		}
+		for _, release := range releases {
+			if err := store.StoreRelease(release); err != nil {
+				t.Fatalf("failed to store release %d: %v", release.Id, err)
+			}
+			if err := store.CompletePendingRelease(state, release.Id); err != nil {
+				t.Fatalf("failed to complete release %d: %v", release.Id, err)
+			}
+		}
+		if err := store.FinishSyncRun(state, nil, conformanceSyncedAt.Add(time.Duration(labelID)*time.Minute)); err != nil {
+			t.Fatalf("failed to finish sync run of label %d: %v", labelID, err)
+		}
+	}
+}
+
+func releaseIDsOf(releases []*models.Release) []int32 {
+	ids := []int32{}
+	for _, release := range releases {
+		ids = append(ids, release.Id)
+	}
+	return ids
+}
+
+func testStoreReleases(t *testing.T, store Store) {
+	seedStore(t, store)
+
+	// Storing a release again replaces its attributes instead of adding to them.
+	expected := conformanceReleases()[5][0]
+	if err := store.StoreRelease(expected); err != nil {
+		t.Fatalf("failed to store release again: %v", err)
+	}
+
+	release, err := store.FetchRelease(1)
+	if err != nil {
+		t.Fatalf("failed to fetch release: %v", err)
+	}
+	if release == nil || release.Community == nil {
+		t.Fatalf("expected release 1 with community statistics, got %+v", release)
+	}
+	release.Community.CapturedAt = time.Time{}
+	if !reflect.DeepEqual(release, expected) {
+		t.Errorf("expected release %+v, got %+v", expected, release)
+	}
+	// Tracks keep their listed order, headings included, whatever their positions and titles.
+	titles := []string{}
+	for _, track := range release.Tracks {
+		titles = append(titles, track.Title)
+	}
+	if expectedTitles := []string{"Side A", "Xtal", "Tha", "Side B", "Pulsewidth"}; !reflect.DeepEqual(titles, expectedTitles) {
+		t.Errorf("expected tracks %v, got %v", expectedTitles, titles)
+	}
+
+	missing, err := store.FetchRelease(404)
+	if err != nil || missing != nil {
+		t.Errorf("expected no release 404, got %+v, %v", missing, err)
+	}
+
+	fetchTimes, err := store.FetchReleaseFetchTimes()
+	if err != nil {
+		t.Fatalf("failed to fetch release fetch times: %v", err)
+	}
+	if len(fetchTimes) != 4 || fetchTimes[1].IsZero() {
+		t.Errorf("expected fetch times of 4 releases, got %v", fetchTimes)
+	}
+}
+
+func testStoreBatches(t *testing.T, store Store) {
+	var releases []*models.Release
+	for _, labelReleases := range conformanceReleases() {
+		releases = append(releases, labelReleases...)
+	}
+	if failed := store.StoreReleases(releases); len(failed) != 0 {
+		t.Fatalf("failed to store a batch of releases: %v", failed)
+	}
+
+	// A release listed twice in a batch is stored as its last copy, replacing the stored one.
+	changed := *releases[0]
+	changed.Styles = []string{"IDM"}
+	if failed := store.StoreReleases([]*models.Release{releases[0], &changed}); len(failed) != 0 {
+		t.Fatalf("failed to store a batch again: %v", failed)
+	}
+	releases[0] = &changed
+
+	for _, expected := range releases {
+		release, err := store.FetchRelease(expected.Id)
+		if err != nil {
+			t.Fatalf("failed to fetch release %d: %v", expected.Id, err)
+		}
+		if release != nil && release.Community != nil {
+			release.Community.CapturedAt = time.Time{}
+		}
+		if !reflect.DeepEqual(release, expected) {
+			t.Errorf("expected release %+v, got %+v", expected, release)
+		}
+	}
+}
+
+func testStoreFilters(t *testing.T, store Store) {
+	seedStore(t, store)
+
+	tests := []struct {
+		name     string
+		filter   models.ReleaseFilter
+		order    models.ReleaseOrder
+		limit    int
+		offset   int
+		expected []int32
+	}{
+		{name: "all", expected: []int32{1, 2, 3, 4}},
+		{name: "page", limit: 2, offset: 1, expected: []int32{2, 3}},
+		{name: "artist", filter: models.ReleaseFilter{Artist: "aphex"}, expected: []int32{1, 2, 3}},
+		{name: "name variation", filter: models.ReleaseFilter{Artist: "afx"}, expected: []int32{2}},
+		{name: "style", filter: models.ReleaseFilter{Style: "AMBIENT"}, expected: []int32{1, 3}},
+		{name: "genre", filter: models.ReleaseFilter{Genre: "hip"}, expected: []int32{4}},
+		{name: "format", filter: models.ReleaseFilter{Format: "cd"}, expected: []int32{2}},
+		{name: "format description", filter: models.ReleaseFilter{Format: "reissue"}, expected: []int32{3}},
+		{name: "track", filter: models.ReleaseFilter{Track: "XTAL"}, expected: []int32{1}},
+		{name: "credit", filter: models.ReleaseFilter{Credit: models.CreditFilter{Role: "mastered", Name: "metcalfe"}}, expected: []int32{1}},
+		{name: "company", filter: models.ReleaseFilter{Company: models.CompanyFilter{Role: "made"}}, expected: []int32{2}},
+		{name: "catno", filter: models.ReleaseFilter{Catno: "warp"}, expected: []int32{1, 2}},
+		{name: "year", filter: models.ReleaseFilter{Year: 1996}, expected: []int32{2}},
+		{name: "country", filter: models.ReleaseFilter{Country: "uk"}, expected: []int32{1, 2, 4}},
+		{name: "master", filter: models.ReleaseFilter{MasterId: 10}, expected: []int32{1, 3}},
+		{name: "label", filter: models.ReleaseFilter{LabelFilter: models.LabelFilter{LabelId: 5}}, expected: []int32{1, 2}},
+		{name: "sublabels", filter: models.ReleaseFilter{LabelFilter: models.LabelFilter{LabelId: 5, IncludeSublabels: true}}, expected: []int32{1, 2, 3}},
+		{name: "combined", filter: models.ReleaseFilter{Artist: "aphex", Style: "ambient", Year: 2008}, expected: []int32{3}},
+		{name: "catno order", order: models.ReleaseOrderCatno, expected: []int32{4, 2, 1, 3}},
+		{name: "catno order page", order: models.ReleaseOrderCatno, limit: 2, offset: 1, expected: []int32{2, 1}},
+		{name: "catno order filtered", filter: models.ReleaseFilter{Style: "ambient"}, order: models.ReleaseOrderCatno, expected: []int32{1, 3}},
+	}
+	for _, test := range tests {
+		order, limit := test.order, test.limit
+		if order == "" {
+			order = models.ReleaseOrderId
+		}
+		if limit == 0 {
+			limit = 10
+		}
+		releases, err := store.FetchReleases(test.filter, order, limit, test.offset)
+		if err != nil {
+			t.Fatalf("%s: failed to fetch releases: %v", test.name, err)
+		}
+		if ids := releaseIDsOf(releases); !reflect.DeepEqual(ids, test.expected) {
+			t.Errorf("%s: expected releases %v, got %v", test.name, test.expected, ids)
+		}
+	}
+
+	styles, err := store.FetchUniqueNames(FacetStyle, models.LabelFilter{LabelId: 5, IncludeSublabels: true})
+	if err != nil {
+		t.Fatalf("failed to fetch unique styles: %v", err)
+	}
+	expectedStyles := []*models.UniqueName{{Name: "Ambient"}, {Name: "IDM"}, {Name: "Techno"}}
+	if !reflect.DeepEqual(styles, expectedStyles) {
+		t.Errorf("expected styles %v, got %v", expectedStyles, styles)
+	}
+	genres, err := store.FetchUniqueNames(FacetGenre, models.LabelFilter{})
+	if err != nil {
+		t.Fatalf("failed to fetch unique genres: %v", err)
+	}
+	if len(genres) != 2 || genres[0].Name != "Electronic" || genres[1].Name != "Hip Hop" {
+		t.Errorf("unexpected genres: %v", genres)
+	}
+}
+
+func testStoreCounts(t *testing.T, store Store) {
+	seedStore(t, store)
+
+	counts, err := store.FetchReleaseCounts(models.ReleaseFilter{LabelFilter: models.LabelFilter{LabelId: 5, IncludeSublabels: true}}, false)
+	if err != nil {
+		t.Fatalf("failed to fetch release counts: %v", err)
+	}
+	// Release 1 has two styles, it still counts once.
+	if counts.ReleaseCount != 3 {
+		t.Errorf("expected 3 releases, got %d", counts.ReleaseCount)
+	}
+	expectedArtists := []models.ArtistCount{{Id: 45, Name: "Aphex Twin", Count: 3}}
+	if !reflect.DeepEqual(counts.ArtistCounts, expectedArtists) {
+		t.Errorf("expected artist counts %v, got %v", expectedArtists, counts.ArtistCounts)
+	}
+	expectedStyles := []models.NameCount{{Name: "Ambient", Count: 2}, {Name: "IDM", Count: 1}, {Name: "Techno", Count: 1}}
+	if !reflect.DeepEqual(counts.StyleCounts, expectedStyles) {
+		t.Errorf("expected style counts %v, got %v", expectedStyles, counts.StyleCounts)
+	}
+	expectedGenres := []models.NameCount{{Name: "Electronic", Count: 3}}
+	if !reflect.DeepEqual(counts.GenreCounts, expectedGenres) {
+		t.Errorf("expected genre counts %v, got %v", expectedGenres, counts.GenreCounts)
+	}
+	expectedFormats := []models.NameCount{{Name: "Album", Count: 2}, {Name: "LP", Count: 2}, {Name: "Vinyl", Count: 2},
+		{Name: "CD", Count: 1}, {Name: "Reissue", Count: 1}}
+	if !reflect.DeepEqual(counts.FormatCounts, expectedFormats) {
+		t.Errorf("expected format counts %v, got %v", expectedFormats, counts.FormatCounts)
+	}
+	expectedCredits := []models.CreditCount{{Name: "Kevin Metcalfe", Role: "Mastered By", Count: 1}}
+	if !reflect.DeepEqual(counts.CreditCounts, expectedCredits) {
+		t.Errorf("expected credit counts %v, got %v", expectedCredits, counts.CreditCounts)
+	}
+	expectedCompanies := []models.CompanyCount{{Name: "MPO", Role: "Pressed By", Count: 2}, {Name: "PDO", Role: "Made By", Count: 1}}
+	if !reflect.DeepEqual(counts.CompanyCounts, expectedCompanies) {
+		t.Errorf("expected company counts %v, got %v", expectedCompanies, counts.CompanyCounts)
+	}
+
+	// The versions of master 10 count once.
+	counts, err = store.FetchReleaseCounts(models.ReleaseFilter{Format: "vinyl"}, true)
+	if err != nil {
+		t.Fatalf("failed to fetch release counts by master: %v", err)
+	}
+	if counts.ReleaseCount != 2 {
+		t.Errorf("expected 2 masters and releases without one, got %d", counts.ReleaseCount)
+	}
+	expectedStyles = []models.NameCount{{Name: "Ambient", Count: 1}, {Name: "Downtempo", Count: 1}, {Name: "Techno", Count: 1}}
+	if !reflect.DeepEqual(counts.StyleCounts, expectedStyles) {
+		t.Errorf("expected style counts by master %v, got %v", expectedStyles, counts.StyleCounts)
+	}
+	expectedGenres = []models.NameCount{{Name: "Electronic", Count: 2}, {Name: "Hip Hop", Count: 1}}
+	if !reflect.DeepEqual(counts.GenreCounts, expectedGenres) {
+		t.Errorf("expected genre counts by master %v, got %v", expectedGenres, counts.GenreCounts)
+	}
+	expectedFormats = []models.NameCount{{Name: "Vinyl", Count: 2}, {Name: "Album", Count: 1}, {Name: "LP", Count: 1},
+		{Name: "Reissue", Count: 1}}
+	if !reflect.DeepEqual(counts.FormatCounts, expectedFormats) {
+		t.Errorf("expected format counts by master %v, got %v", expectedFormats, counts.FormatCounts)
+	}
+
+	// Filtering by a style or an artist name counts only the matching names.
+	counts, err = store.FetchReleaseCounts(models.ReleaseFilter{Style: "ambient"}, false)
+	if err != nil {
+		t.Fatalf("failed to fetch release counts by style: %v", err)
+	}
+	expectedStyles = []models.NameCount{{Name: "Ambient", Count: 2}}
+	if counts.ReleaseCount != 2 || !reflect.DeepEqual(counts.StyleCounts, expectedStyles) {
+		t.Errorf("expected 2 releases counted as %v, got %d counted as %v", expectedStyles, counts.ReleaseCount, counts.StyleCounts)
+	}
+	counts, err = store.FetchReleaseCounts(models.ReleaseFilter{Artist: "afx"}, false)
+	if err != nil {
+		t.Fatalf("failed to fetch release counts by name variation: %v", err)
+	}
+	expectedArtists = []models.ArtistCount{{Id: 45, Name: "Aphex Twin", Count: 1}}
+	if counts.ReleaseCount != 1 || !reflect.DeepEqual(counts.ArtistCounts, expectedArtists) {
+		t.Errorf("expected 1 release counted as %v, got %d counted as %v", expectedArtists, counts.ReleaseCount, counts.ArtistCounts)
+	}
+
+	counts, err = store.FetchReleaseCounts(models.ReleaseFilter{Company: models.CompanyFilter{Role: "pressed"}}, false)
+	if err != nil {
+		t.Fatalf("failed to fetch release counts by company: %v", err)
+	}
+	expectedCompanies = []models.CompanyCount{{Name: "MPO", Role: "Pressed By", Count: 2}}
+	if !reflect.DeepEqual(counts.CompanyCounts, expectedCompanies) {
+		t.Errorf("expected pressing plant counts %v, got %v", expectedCompanies, counts.CompanyCounts)
+	}
+}
+
+func testStoreIdentifiers(t *testing.T, store Store) {
+	seedStore(t, store)
+
+	tests := []struct {
+		name           string
+		value          string
+		identifierType string
+		expected       []int32
+	}{
+		{name: "shared barcode", value: "5021603-054179", expected: []int32{1, 3}},
+		{name: "spaced barcode", value: " 5 021603 05417 9", identifierType: "BARCODE", expected: []int32{1, 3}},
+		{name: "matrix", value: "warplp6-a1", identifierType: "matrix / runout", expected: []int32{3}},
+		{name: "other type", value: "5021603054179", identifierType: "matrix / runout", expected: []int32{}},
+		{name: "unknown", value: "WARP 2", expected: []int32{}},
+	}
+	for _, test := range tests {
+		releases, err := store.FetchReleasesByIdentifier(test.value, test.identifierType)
+		if err != nil {
+			t.Fatalf("%s: failed to fetch releases by identifier: %v", test.name, err)
+		}
+		if ids := releaseIDsOf(releases); !reflect.DeepEqual(ids, test.expected) {
+			t.Errorf("%s: expected releases %v, got %v", test.name, test.expected, ids)
+		}
+	}
+}
+
+func testStoreArtists(t *testing.T, store Store) {
+	seedStore(t, store)
+	fetchedAt := conformanceSyncedAt.Add(time.Hour)
+	active := true
+
+	due, err := store.FetchArtistsToEnrich(models.LabelFilter{LabelId: 5}, nil)
+	if err != nil {
+		t.Fatalf("failed to fetch artists to enrich: %v", err)
+	}
+	if !reflect.DeepEqual(due, []int{45}) {
+		t.Errorf("expected artist 45 to enrich, got %v", due)
+	}
+
+	profile := &models.Artist{
+		Id: 45, Name: "Aphex Twin", RealName: "Richard David James", Profile: "Electronic musician.",
+		NameVariations: []string{"AFX", "Aphex", "AFX"},
+		Aliases:        []models.ArtistRef{{Id: 3, Name: "Polygon Window"}, {Id: 2, Name: "Caustic Window"}},
+		Groups:         []models.ArtistRef{{Id: 8, Name: "Mike & Rich", Active: &active}},
+	}
+	if err := store.StoreArtistProfile(profile, fetchedAt); err != nil {
+		t.Fatalf("failed to store artist profile: %v", err)
+	}
+
+	artist, err := store.FetchArtist(45)
+	if err != nil {
+		t.Fatalf("failed to fetch artist: %v", err)
+	}
+	expected := &models.Artist{
+		Id: 45, Name: "Aphex Twin", RealName: "Richard David James", Profile: "Electronic musician.",
+		NameVariations: []string{"AFX", "Aphex"},
+		Aliases:        []models.ArtistRef{{Id: 2, Name: "Caustic Window"}, {Id: 3, Name: "Polygon Window"}},
+		Groups:         []models.ArtistRef{{Id: 8, Name: "Mike & Rich", Active: &active}},
+	}
+	if artist == nil || artist.ProfileFetchedAt == nil || !artist.ProfileFetchedAt.Equal(fetchedAt) {
+		t.Fatalf("expected artist 45 fetched at %v, got %+v", fetchedAt, artist)
+	}
+	artist.ProfileFetchedAt = nil
+	if !reflect.DeepEqual(artist, expected) {
+		t.Errorf("expected artist %+v, got %+v", expected, artist)
+	}
+
+	due, err = store.FetchArtistsToEnrich(models.LabelFilter{}, nil)
+	if err != nil {
+		t.Fatalf("failed to fetch artists to enrich: %v", err)
+	}
+	if !reflect.DeepEqual(due, []int{46}) {
+		t.Errorf("expected only artist 46 to enrich, got %v", due)
+	}
+	refetchBefore := fetchedAt.Add(time.Minute)
+	due, err = store.FetchArtistsToEnrich(models.LabelFilter{}, &refetchBefore)
+	if err != nil {
+		t.Fatalf("failed to fetch stale artists: %v", err)
+	}
+	if !reflect.DeepEqual(due, []int{45, 46}) {
+		t.Errorf("expected artists 45 and 46 to enrich, got %v", due)
+	}
+
+	artists, err := store.FetchUniqueArtists(models.LabelFilter{LabelId: 7})
+	if err != nil {
+		t.Fatalf("failed to fetch unique artists: %v", err)
+	}
+	if len(artists) != 1 || artists[0].Name != "Boards Of Canada" {
+		t.Errorf("expected only Boards Of Canada on label 7, got %+v", artists)
+	}
+}
+
+func testStoreMasters(t *testing.T, store Store) {
+	seedStore(t, store)
+
+	due, err := store.FetchMastersToFetch(models.LabelFilter{}, nil)
+	if err != nil {
+		t.Fatalf("failed to fetch masters to fetch: %v", err)
+	}
+	if !reflect.DeepEqual(due, []int{10}) {
+		t.Errorf("expected master 10 to fetch, got %v", due)
+	}
+
+	master, err := store.FetchMaster(10)
+	if err != nil {
+		t.Fatalf("failed to fetch master: %v", err)
+	}
+	if master == nil || master.VersionCount != 2 || master.FetchedAt != nil {
+		t.Errorf("expected unfetched master 10 with 2 versions, got %+v", master)
+	}
+
+	fetchedAt := conformanceSyncedAt.Add(time.Hour)
+	if err := store.StoreMaster(&models.Master{Id: 10, Title: "Selected Ambient Works 85-92", Year: 1992, MainRelease: 1}, fetchedAt); err != nil {
+		t.Fatalf("failed to store master: %v", err)
+	}
+	master, err = store.FetchMaster(10)
+	if err != nil {
+		t.Fatalf("failed to fetch master: %v", err)
+	}
+	if master == nil || master.Title != "Selected Ambient Works 85-92" || master.MainRelease != 1 ||
+		master.VersionCount != 2 || master.FetchedAt == nil || !master.FetchedAt.Equal(fetchedAt) {
+		t.Errorf("unexpected master: %+v", master)
+	}
+
+	due, err = store.FetchMastersToFetch(models.LabelFilter{}, nil)
+	if err != nil {
+		t.Fatalf("failed to fetch masters to fetch: %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("expected no master to fetch, got %v", due)
+	}
+
+	missing, err := store.FetchMaster(404)
+	if err != nil || missing != nil {
+		t.Errorf("expected no master 404, got %+v, %v", missing, err)
+	}
+}
+
+func testStoreLabels(t *testing.T, store Store) {
+	seedStore(t, store)
+
+	// Storing the sublabel again without a parent keeps its parent.
+	if err := store.StoreLabel(&models.Label{Id: 6, Name: "Warp Sublabel Records"}); err != nil {
+		t.Fatalf("failed to store label: %v", err)
+	}
+
+	labels, err := store.FetchLabels(0)
+	if err != nil {
+		t.Fatalf("failed to fetch labels: %v", err)
+	}
+	var names []string
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"Skam", "Warp Records", "Warp Sublabel Records"}) {
+		t.Errorf("unexpected labels: %v", names)
+	}
+
+	sublabels, err := store.FetchLabels(5)
+	if err != nil {
+		t.Fatalf("failed to fetch sublabels: %v", err)
+	}
+	if len(sublabels) != 1 || sublabels[0].Id != 6 || sublabels[0].ParentId == nil || *sublabels[0].ParentId != 5 {
+		t.Errorf("expected sublabel 6 of label 5, got %+v", sublabels)
+	}
+}
+
+func testStoreCommunity(t *testing.T, store Store) {
+	seedStore(t, store)
+	from := time.Now().Add(-time.Hour)
+
+	// A later sync finds release 2 wanted less and release 1 wanted more.
+	time.Sleep(time.Millisecond)
+	releases := conformanceReleases()[5]
+	releases[0].Community.Want = 140
+	releases[1].Community.Want = 80
+	for _, release := range releases {
+		if err := store.StoreRelease(release); err != nil {
+			t.Fatalf("failed to store release %d again: %v", release.Id, err)
+		}
+	}
+
+	history, err := store.FetchCommunityHistory(1, &from, nil)
+	if err != nil {
+		t.Fatalf("failed to fetch community history: %v", err)
+	}
+	if len(history) != 2 || history[0].Want != 40 || history[1].Want != 140 || history[0].RatingAverage != 4.5 {
+		t.Errorf("unexpected community history: %+v", history)
+	}
+
+	mostWanted, err := store.FetchMostWanted(models.LabelFilter{LabelId: 5}, 10)
+	if err != nil {
+		t.Fatalf("failed to fetch most wanted releases: %v", err)
+	}
+	if ids := releaseIDsOf(mostWanted); !reflect.DeepEqual(ids, []int32{1, 2}) {
+		t.Errorf("expected releases 1 and 2 by want, got %v", ids)
+	}
+
+	trending, err := store.FetchTrending(models.LabelFilter{}, from, time.Now().Add(time.Hour), 10)
+	if err != nil {
+		t.Fatalf("failed to fetch trending releases: %v", err)
+	}
+	if len(trending) != 2 || trending[0].Release.Id != 1 || trending[0].Growth != 100 ||
+		trending[1].Release.Id != 2 || trending[1].Growth != -10 || trending[1].WantFrom != 90 {
+		t.Errorf("unexpected trending releases: %+v", trending)
+	}
+
+	// Releases without a snapshot since a sync started are due until they are stored again.
+	labelFilter := models.LabelFilter{LabelId: 5, IncludeSublabels: true}
+	due, err := store.FetchReleasesToSnapshot(labelFilter, from)
+	if err != nil {
+		t.Fatalf("failed to fetch releases to snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(due, []int32{3}) {
+		t.Errorf("expected release 3 without statistics to snapshot, got %v", due)
+	}
+	syncStart := time.Now()
+	time.Sleep(time.Millisecond)
+	releases[1].Community.Want = 95
+	if err := store.StoreRelease(releases[1]); err != nil {
+		t.Fatalf("failed to store release 2 again: %v", err)
+	}
+	due, err = store.FetchReleasesToSnapshot(labelFilter, syncStart)
+	if err != nil {
+		t.Fatalf("failed to fetch releases to snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(due, []int32{1, 3}) {
+		t.Errorf("expected releases 1 and 3 to snapshot, got %v", due)
+	}
+}
+
+func testStorePrices(t *testing.T, store Store) {
+	seedStore(t, store)
+	price := func(value float64) *float64 { return &value }
+
+	snapshots := map[int32][]models.PriceSnapshot{
+		1: {
+			{Currency: "USD", LowestPrice: price(30), NumForSale: 4, CapturedAt: conformanceSyncedAt},
+			{Currency: "USD", LowestPrice: price(35), NumForSale: 3, CapturedAt: conformanceSyncedAt.Add(24 * time.Hour)},
+		},
+		2: {{Currency: "USD", LowestPrice: price(20), NumForSale: 10, CapturedAt: conformanceSyncedAt}},
+		3: {{Currency: "USD", NumForSale: 0, BlockedFromSale: true, CapturedAt: conformanceSyncedAt}},
+		4: {{Currency: "EUR", LowestPrice: price(25), NumForSale: 2, CapturedAt: conformanceSyncedAt}},
+	}
+	for releaseID, releaseSnapshots := range snapshots {
+		for _, snapshot := range releaseSnapshots {
+			if err := store.StorePriceSnapshot(releaseID, &snapshot); err != nil {
+				t.Fatalf("failed to store price snapshot of release %d: %v", releaseID, err)
+			}
+		}
+	}
+
+	toPrice, err := store.FetchReleasesToPrice(models.LabelFilter{LabelId: 5, IncludeSublabels: true})
+	if err != nil {
+		t.Fatalf("failed to fetch releases to price: %v", err)
+	}
+	if !reflect.DeepEqual(toPrice, []int32{1, 2, 3}) {
+		t.Errorf("expected releases 1 to 3 to price, got %v", toPrice)
+	}
+
+	from := conformanceSyncedAt.Add(time.Hour)
+	history, err := store.FetchPriceHistory(1, "USD", &from, nil)
+	if err != nil {
+		t.Fatalf("failed to fetch price history: %v", err)
+	}
+	if len(history) != 1 || history[0].LowestPrice == nil || *history[0].LowestPrice != 35 {
+		t.Errorf("unexpected price history: %+v", history)
+	}
+
+	stats, err := store.FetchPriceStats(FacetStyle, "USD", models.LabelFilter{})
+	if err != nil {
+		t.Fatalf("failed to fetch price stats: %v", err)
+	}
+	expected := []models.PriceStats{
+		{Name: "Techno", ReleaseCount: 1, PricedCount: 1, NumForSale: 3, MinPrice: price(35), MedianPrice: price(35)},
+		{Name: "IDM", ReleaseCount: 1, PricedCount: 1, NumForSale: 10, MinPrice: price(20), MedianPrice: price(20)},
+		{Name: "Ambient", ReleaseCount: 2, PricedCount: 1, NumForSale: 3, MinPrice: price(35), MedianPrice: price(35)},
+	}
+	// Ambient and Techno share a median, so their order is only fixed by name before sorting.
+	if len(stats) != 3 || !reflect.DeepEqual(stats[2], expected[1]) {
+		t.Fatalf("expected IDM with the lowest median last, got %+v", stats)
+	}
+	for _, name := range []string{"Ambient", "Techno"} {
+		found := false
+		for i, want := range expected {
+			for _, got := range stats[:2] {
+				if got.Name == name && want.Name == name {
+					found = reflect.DeepEqual(got, expected[i])
+				}
+			}
+		}
+		if !found {
+			t.Errorf("unexpected price stats of %s: %+v", name, stats)
+		}
+	}
+}
+
+func testStoreSync(t *testing.T, store Store) {
+	if err := store.StoreLabel(&models.Label{Id: 5, Name: "Warp Records"}); err != nil {
+		t.Fatalf("failed to store label: %v", err)
+	}
+
+	crashed, err := store.StartSyncRun(5, "https://api.discogs.com/labels/5/releases", conformanceSyncedAt)
+	if err != nil {
+		t.Fatalf("failed to start sync run: %v", err)
+	}
+	if err := store.SaveListedPage(crashed, []int32{1, 2, 3}, []int32{3, 1, 2}, "https://api.discogs.com/labels/5/releases?page=2"); err != nil {
+		t.Fatalf("failed to save listed page: %v", err)
+	}
+
+	// A new run takes over the checkpoint of the crashed one.
+	state, err := store.StartSyncRun(5, "https://api.discogs.com/labels/5/releases", conformanceSyncedAt.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to restart sync run: %v", err)
+	}
+	if state.RunId == crashed.RunId || state.PagesListed != 1 || state.NextPageURL != "https://api.discogs.com/labels/5/releases?page=2" {
+		t.Errorf("expected the new run to resume after page 1, got %+v", state)
+	}
+	interrupted, err := store.FetchSyncRun(crashed.RunId)
+	if err != nil {
+		t.Fatalf("failed to fetch interrupted run: %v", err)
+	}
+	if interrupted.Status != models.SyncRunInterrupted || interrupted.ReleasesListed != 3 {
+		t.Errorf("expected an interrupted run with 3 listed releases, got %+v", interrupted)
+	}
+
+	pending, err := store.FetchPendingReleaseIDs(5)
+	if err != nil {
+		t.Fatalf("failed to fetch pending releases: %v", err)
+	}
+	if !reflect.DeepEqual(pending, []int32{1, 2, 3}) {
+		t.Errorf("expected pending releases 1 to 3, got %v", pending)
+	}
+
+	if err := store.SaveListedPage(state, []int32{4}, nil, ""); err != nil {
+		t.Fatalf("failed to save last page: %v", err)
+	}
+	if !state.ListingComplete || state.PagesListed != 2 {
+		t.Errorf("expected a complete listing of 2 pages, got %+v", state)
+	}
+	if err := store.StoreRelease(&models.Release{Id: 1, Title: "Selected Ambient Works 85-92"}); err != nil {
+		t.Fatalf("failed to store release: %v", err)
+	}
+	if err := store.CompletePendingRelease(state, 1); err != nil {
+		t.Fatalf("failed to complete release: %v", err)
+	}
+	if err := store.FailPendingRelease(state, 2, fmt.Errorf("not found"), conformanceSyncedAt.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to fail release: %v", err)
+	}
+	pending, err = store.FetchPendingReleaseIDs(5)
+	if err != nil {
+		t.Fatalf("failed to fetch pending releases: %v", err)
+	}
+	if !reflect.DeepEqual(pending, []int32{3}) {
+		t.Errorf("expected pending release 3, got %v", pending)
+	}
+
+	lastSyncedAt, err := store.FetchLastSyncedAt(5)
+	if err != nil || lastSyncedAt != nil {
+		t.Errorf("expected no successful sync yet, got %v, %v", lastSyncedAt, err)
+	}
+
+	finishedAt := conformanceSyncedAt.Add(2 * time.Hour)
+	if err := store.FinishSyncRun(state, nil, finishedAt); err != nil {
+		t.Fatalf("failed to finish sync run: %v", err)
+	}
+	run, err := store.FetchSyncRun(state.RunId)
+	if err != nil {
+		t.Fatalf("failed to fetch sync run: %v", err)
+	}
+	if run.Status != models.SyncRunSucceeded || run.ReleasesListed != 1 || run.ReleasesFetched != 1 ||
+		run.ReleasesFailed != 1 || run.FinishedAt == nil || !run.FinishedAt.Equal(finishedAt) {
+		t.Errorf("unexpected finished run: %+v", run)
+	}
+	pending, err = store.FetchPendingReleaseIDs(5)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("expected the checkpoint to be removed, got %v, %v", pending, err)
+	}
+
+	for _, labelID := range []int{0, 5} {
+		lastSyncedAt, err = store.FetchLastSyncedAt(labelID)
+		if err != nil {
+			t.Fatalf("failed to fetch last sync time: %v", err)
+		}
+		if lastSyncedAt == nil || !lastSyncedAt.Equal(finishedAt) {
+			t.Errorf("expected last sync of label %d at %v, got %v", labelID, finishedAt, lastSyncedAt)
+		}
+	}
+	lastSyncedAt, err = store.FetchLastSyncedAt(6)
+	if err != nil || lastSyncedAt != nil {
+		t.Errorf("expected no sync of label 6, got %v, %v", lastSyncedAt, err)
+	}
+}
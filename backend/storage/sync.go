@@ -0,0 +1,305 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+	"time"
+)
+
+// Sync table names
+const (
+	syncRunsTableName    = "sync_runs"
+	syncStateTableName   = "sync_state"
+	syncPendingTableName = "sync_pending_releases"
+	syncFailedTableName  = "sync_failed_releases"
+)
+
+// SQL queries for sync checkpoints
+const (
+	interruptSyncRunsSQL = `
+		UPDATE %s SET status = $1, finished_at = $2
+		WHERE label_id = $3 AND status = $4;
+	`
+	insertSyncRunSQL = `
+		INSERT INTO %s (label_id, started_at, status)
+		VALUES ($1, $2, $3)
+		RETURNING id;
+	`
+	upsertSyncStateSQL = `
+		INSERT INTO %s (label_id, run_id, next_page_url)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (label_id) DO UPDATE SET run_id = EXCLUDED.run_id;
+	`
+	fetchSyncStateSQL = `
+		SELECT label_id, run_id, pages_listed, next_page_url, listing_complete
+		FROM %s WHERE label_id = $1;
+	`
+	insertPendingReleaseSQL = `
+		INSERT INTO %s (label_id, release_id)
+		VALUES ($1, $2)
+		ON CONFLICT (label_id, release_id) DO NOTHING;
+	`
+	updateListedPageSQL = `
+		UPDATE %s SET pages_listed = pages_listed + 1, next_page_url = $1, listing_complete = $2
+		WHERE label_id = $3;
+	`
+	incrementRunCounterSQL = `
+		UPDATE %s SET %s = %s + $1 WHERE id = $2;
+	`
+	fetchPendingReleasesSQL = `
+		SELECT release_id FROM %s WHERE label_id = $1 ORDER BY release_id;
+	`
+	deletePendingReleaseSQL = `
+		DELETE FROM %s WHERE label_id = $1 AND release_id = $2;
+	`
+	insertFailedReleaseSQL = `
+		INSERT INTO %s (run_id, release_id, error, failed_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (run_id, release_id) DO UPDATE SET error = EXCLUDED.error, failed_at = EXCLUDED.failed_at;
+	`
+	finishSyncRunSQL = `
+		UPDATE %s SET status = $1, finished_at = $2, error = $3 WHERE id = $4;
+	`
+	deleteSyncStateSQL = `
+		DELETE FROM %s WHERE label_id = $1;
+	`
+	fetchLastSyncedAtSQL = `
+		SELECT finished_at FROM %s WHERE status = $1%s ORDER BY finished_at DESC LIMIT 1
+	`
+	fetchSyncRunSQL = `
+		SELECT id, label_id, started_at, finished_at, status, releases_listed, releases_fetched, releases_failed, error
+		FROM %s WHERE id = $1;
+	`
+)
+
+// StartSyncRun records a new sync run for the label and attaches it to the label's checkpoint,
+// creating the checkpoint at firstPageURL when no unfinished sync exists. Runs of the same label
+// still marked as running were cut short by a crash and are marked as interrupted.
+func StartSyncRun(db *sql.DB, labelID int, firstPageURL string, startedAt time.Time) (*models.SyncState, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	interruptQuery := fmt.Sprintf(interruptSyncRunsSQL, syncRunsTableName)
+	if _, err := tx.Exec(interruptQuery, models.SyncRunInterrupted, startedAt, labelID, models.SyncRunRunning); err != nil {
+		return nil, fmt.Errorf("failed to mark interrupted sync runs: %v", err)
+	}
+
+	var runID int
+	insertQuery := fmt.Sprintf(insertSyncRunSQL, syncRunsTableName)
+	if err := tx.QueryRow(insertQuery, labelID, startedAt, models.SyncRunRunning).Scan(&runID); err != nil {
+		return nil, fmt.Errorf("failed to insert sync run: %v", err)
+	}
+
+	stateQuery := fmt.Sprintf(upsertSyncStateSQL, syncStateTableName)
+	if _, err := tx.Exec(stateQuery, labelID, runID, firstPageURL); err != nil {
+		return nil, fmt.Errorf("failed to save sync state: %v", err)
+	}
+
+	state, err := fetchSyncState(tx, labelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return state, nil
+}
+
+func fetchSyncState(tx *sql.Tx, labelID int) (*models.SyncState, error) {
+	var state models.SyncState
+	query := fmt.Sprintf(fetchSyncStateSQL, syncStateTableName)
+
+	err := tx.QueryRow(query, labelID).
+		Scan(&state.LabelId, &state.RunId, &state.PagesListed, &state.NextPageURL, &state.ListingComplete)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sync state of label %d: %v", labelID, err)
+	}
+	return &state, nil
+}
+
+// SaveListedPage links the releases of one listed page to the label, stores the ones selected for
+// fetching as pending and moves the checkpoint to the next page. An empty nextPageURL marks the
+// listing as complete.
+func SaveListedPage(db *sql.DB, state *models.SyncState, listedIDs []int32, pendingIDs []int32, nextPageURL string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertLabelReleases(tx, state.LabelId, listedIDs); err != nil {
+		return err
+	}
+
+	pendingQuery := fmt.Sprintf(insertPendingReleaseSQL, syncPendingTableName)
+	for _, releaseID := range pendingIDs {
+		if _, err := tx.Exec(pendingQuery, state.LabelId, releaseID); err != nil {
+			return fmt.Errorf("failed to insert pending release %d: %v", releaseID, err)
+		}
+	}
+
+	listingComplete := nextPageURL == ""
+	pageQuery := fmt.Sprintf(updateListedPageSQL, syncStateTableName)
+	if _, err := tx.Exec(pageQuery, nextPageURL, listingComplete, state.LabelId); err != nil {
+		return fmt.Errorf("failed to update sync state: %v", err)
+	}
+
+	if err := incrementRunCounter(tx, state.RunId, "releases_listed", len(listedIDs)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	state.PagesListed++
+	state.NextPageURL = nextPageURL
+	state.ListingComplete = listingComplete
+	return nil
+}
+
+func incrementRunCounter(tx *sql.Tx, runID int, column string, delta int) error {
+	query := fmt.Sprintf(incrementRunCounterSQL, syncRunsTableName, column, column)
+	if _, err := tx.Exec(query, delta, runID); err != nil {
+		return fmt.Errorf("failed to update %s of sync run %d: %v", column, runID, err)
+	}
+	return nil
+}
+
+func FetchPendingReleaseIDs(db *sql.DB, labelID int) ([]int32, error) {
+	query := fmt.Sprintf(fetchPendingReleasesSQL, syncPendingTableName)
+	rows, err := db.Query(query, labelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending releases: %v", err)
+	}
+	defer rows.Close()
+
+	var releaseIDs []int32
+	for rows.Next() {
+		var releaseID int32
+		if err := rows.Scan(&releaseID); err != nil {
+			return nil, fmt.Errorf("failed to scan pending release: %v", err)
+		}
+		releaseIDs = append(releaseIDs, releaseID)
+	}
+
+	return releaseIDs, rows.Err()
+}
+
+// CompletePendingRelease removes a stored release from the pending list of the checkpoint.
+func CompletePendingRelease(db *sql.DB, state *models.SyncState, releaseID int32) error {
+	return resolvePendingRelease(db, state, releaseID, func(tx *sql.Tx) error {
+		return incrementRunCounter(tx, state.RunId, "releases_fetched", 1)
+	})
+}
+
+// FailPendingRelease removes a release from the pending list and records why it failed.
+func FailPendingRelease(db *sql.DB, state *models.SyncState, releaseID int32, reason error, failedAt time.Time) error {
+	return resolvePendingRelease(db, state, releaseID, func(tx *sql.Tx) error {
+		query := fmt.Sprintf(insertFailedReleaseSQL, syncFailedTableName)
+		if _, err := tx.Exec(query, state.RunId, releaseID, reason.Error(), failedAt); err != nil {
+			return fmt.Errorf("failed to record failed release %d: %v", releaseID, err)
+		}
+		return incrementRunCounter(tx, state.RunId, "releases_failed", 1)
+	})
+}
+
+func resolvePendingRelease(db *sql.DB, state *models.SyncState, releaseID int32, record func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(deletePendingReleaseSQL, syncPendingTableName)
+	if _, err := tx.Exec(query, state.LabelId, releaseID); err != nil {
+		return fmt.Errorf("failed to delete pending release %d: %v", releaseID, err)
+	}
+
+	if err := record(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// FinishSyncRun closes the sync run. A successful run also removes the label's checkpoint, while
+// a failed one keeps it so the next run resumes where this one stopped.
+func FinishSyncRun(db *sql.DB, state *models.SyncState, runErr error, finishedAt time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	status, errMsg := models.SyncRunSucceeded, ""
+	if runErr != nil {
+		status, errMsg = models.SyncRunFailed, runErr.Error()
+	}
+
+	finishQuery := fmt.Sprintf(finishSyncRunSQL, syncRunsTableName)
+	if _, err := tx.Exec(finishQuery, status, finishedAt, errMsg, state.RunId); err != nil {
+		return fmt.Errorf("failed to finish sync run %d: %v", state.RunId, err)
+	}
+
+	if runErr == nil {
+		deleteQuery := fmt.Sprintf(deleteSyncStateSQL, syncStateTableName)
+		if _, err := tx.Exec(deleteQuery, state.LabelId); err != nil {
+			return fmt.Errorf("failed to delete sync state of label %d: %v", state.LabelId, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func FetchSyncRun(db *sql.DB, runID int) (*models.SyncRun, error) {
+	var run models.SyncRun
+	var finishedAt sql.NullTime
+	query := fmt.Sprintf(fetchSyncRunSQL, syncRunsTableName)
+
+	err := db.QueryRow(query, runID).Scan(&run.Id, &run.LabelId, &run.StartedAt, &finishedAt, &run.Status,
+		&run.ReleasesListed, &run.ReleasesFetched, &run.ReleasesFailed, &run.Error)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sync run %d: %v", runID, err)
+	}
+
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	return &run, nil
+}
+
+// FetchLastSyncedAt returns when the last successful sync of the label, or of any label if labelID
+// is zero, finished. It returns nil if there has been none yet.
+func FetchLastSyncedAt(db *sql.DB, labelID int) (*time.Time, error) {
+	var lastSyncedAt time.Time
+	labelClause := ""
+	args := []interface{}{models.SyncRunSucceeded}
+	if labelID != 0 {
+		labelClause = " AND label_id = $2"
+		args = append(args, labelID)
+	}
+
+	// The latest run is ordered to the top rather than taken with MAX, whose result SQLite does not
+	// read back as a time.
+	query := fmt.Sprintf(fetchLastSyncedAtSQL, syncRunsTableName, labelClause)
+	err := db.QueryRow(query, args...).Scan(&lastSyncedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch last sync time: %v", err)
+	}
+	return &lastSyncedAt, nil
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+func TestStartSyncRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	startedAt := time.Date(2024, 10, 24, 12, 0, 0, 0, time.UTC)
+	firstPageURL := "https://api.discogs.com/labels/5/releases?page=1&per_page=100"
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE sync_runs SET status").
+		WithArgs(models.SyncRunInterrupted, startedAt, 5, models.SyncRunRunning).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO sync_runs").
+		WithArgs(5, startedAt, models.SyncRunRunning).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec("INSERT INTO sync_state").
+		WithArgs(5, 7, firstPageURL).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT label_id, run_id, pages_listed, next_page_url, listing_complete FROM sync_state").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"label_id", "run_id", "pages_listed", "next_page_url", "listing_complete"}).
+			AddRow(5, 7, 3, "https://api.discogs.com/labels/5/releases?page=4&per_page=100", false))
+	mock.ExpectCommit()
+
+	state, err := StartSyncRun(db, 5, firstPageURL, startedAt)
+	if err != nil {
+		t.Fatalf("failed to start sync run: %v", err)
+	}
+	if state.RunId != 7 || state.PagesListed != 3 || state.ListingComplete {
+		t.Errorf("expected resumed state of run 7 after 3 pages, got %+v", state)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSaveListedPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	state := &models.SyncState{LabelId: 5, RunId: 7, PagesListed: 1, NextPageURL: "page-2"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO label_releases").WithArgs(5, int32(10)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO label_releases").WithArgs(5, int32(11)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO label_releases").WithArgs(5, int32(12)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO sync_pending_releases").WithArgs(5, int32(10)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO sync_pending_releases").WithArgs(5, int32(11)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE sync_state SET pages_listed").WithArgs("", true, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE sync_runs SET releases_listed = releases_listed").WithArgs(3, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := SaveListedPage(db, state, []int32{10, 11, 12}, []int32{10, 11}, ""); err != nil {
+		t.Fatalf("failed to save listed page: %v", err)
+	}
+	if state.PagesListed != 2 || !state.ListingComplete {
+		t.Errorf("expected listing to be complete after 2 pages, got %+v", state)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFailPendingRelease(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	state := &models.SyncState{LabelId: 5, RunId: 7}
+	failedAt := time.Date(2024, 10, 24, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM sync_pending_releases").WithArgs(5, int32(10)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO sync_failed_releases").
+		WithArgs(7, int32(10), "release not found", failedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE sync_runs SET releases_failed = releases_failed").WithArgs(1, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := FailPendingRelease(db, state, 10, fmt.Errorf("release not found"), failedAt); err != nil {
+		t.Fatalf("failed to record failed release: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFinishSyncRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	state := &models.SyncState{LabelId: 5, RunId: 7}
+	finishedAt := time.Date(2024, 10, 24, 13, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE sync_runs SET status").
+		WithArgs(models.SyncRunSucceeded, finishedAt, "", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM sync_state").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := FinishSyncRun(db, state, nil, finishedAt); err != nil {
+		t.Fatalf("failed to finish sync run: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE sync_runs SET status").
+		WithArgs(models.SyncRunFailed, finishedAt, "discogs unreachable", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := FinishSyncRun(db, state, fmt.Errorf("discogs unreachable"), finishedAt); err != nil {
+		t.Fatalf("failed to finish sync run: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFetchLastSyncedAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock database: %v", err)
+	}
+	defer db.Close()
+
+	finishedAt := time.Date(2024, 10, 24, 13, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT finished_at FROM sync_runs WHERE status = \\$1 ORDER BY finished_at DESC LIMIT 1").
+		WithArgs(models.SyncRunSucceeded).
+		WillReturnRows(sqlmock.NewRows([]string{"finished_at"}).AddRow(finishedAt))
+	mock.ExpectQuery("SELECT finished_at FROM sync_runs WHERE status = \\$1 AND label_id = \\$2 ORDER BY finished_at DESC").
+		WithArgs(models.SyncRunSucceeded, 6).
+		WillReturnRows(sqlmock.NewRows([]string{"finished_at"}))
+
+	lastSyncedAt, err := FetchLastSyncedAt(db, 0)
+	if err != nil {
+		t.Fatalf("failed to fetch last sync time: %v", err)
+	}
+	if lastSyncedAt == nil || !lastSyncedAt.Equal(finishedAt) {
+		t.Errorf("expected last sync at %v, got %v", finishedAt, lastSyncedAt)
+	}
+
+	lastSyncedAt, err = FetchLastSyncedAt(db, 6)
+	if err != nil {
+		t.Fatalf("failed to fetch last sync time: %v", err)
+	}
+	if lastSyncedAt != nil {
+		t.Errorf("expected no last sync time, got %v", lastSyncedAt)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/LissaGreense/discogs_record_label/backend/models"
+)
+
+// Track table names
+const (
+	tracksTableName       = "tracks"
+	trackArtistsTableName = "track_artists"
+)
+
+// SQL queries for tracks
+const (
+	deleteTracksSQL = `
+		DELETE FROM %s WHERE release_id = $1;
+	`
+	insertTrackSQL = `
+		INSERT INTO %s (release_id, sequence, position, type, title, duration, duration_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id;
+	`
+	insertTrackArtistSQL = `
+		INSERT INTO %s (track_id, sequence, name, role)
+		VALUES ($1, $2, $3, $4);
+	`
+	fetchReleaseTracksSQL = `
+		SELECT t.release_id, t.id, COALESCE(t.position, ''), COALESCE(t.type, ''), t.title,
+			COALESCE(t.duration, ''), COALESCE(t.duration_seconds, 0),
+			COALESCE(ta.name, ''), COALESCE(ta.role, '')
+		FROM %s t
+		LEFT JOIN %s ta ON ta.track_id = t.id
+		WHERE t.release_id IN (%s)
+		ORDER BY t.release_id, t.sequence, ta.sequence
+	`
+	trackFilterSQL = `r.id IN (SELECT release_id FROM %s WHERE title ILIKE $%d)`
+)
+
+// replaceTracks swaps the stored tracklist of a release, with the track artists, for the given
+// one.
+func replaceTracks(tx *sql.Tx, releaseID int32, tracks []models.Track) error {
+	if _, err := tx.Exec(fmt.Sprintf(deleteTracksSQL, tracksTableName), releaseID); err != nil {
+		return fmt.Errorf("failed to delete from table %s: %v", tracksTableName, err)
+	}
+
+	trackQuery := fmt.Sprintf(insertTrackSQL, tracksTableName)
+	artistQuery := fmt.Sprintf(insertTrackArtistSQL, trackArtistsTableName)
+
+	for sequence, track := range tracks {
+		var trackID int
+		if err := tx.QueryRow(trackQuery, releaseID, sequence, track.Position, track.Type, track.Title,
+			track.Duration, nullIfZero(track.DurationSeconds)).Scan(&trackID); err != nil {
+			return fmt.Errorf("failed to insert into table %s: %v", tracksTableName, err)
+		}
+
+		for artistSequence, artist := range track.Artists {
+			if _, err := tx.Exec(artistQuery, trackID, artistSequence, artist.Name, artist.Role); err != nil {
+				return fmt.Errorf("failed to insert into table %s: %v", trackArtistsTableName, err)
+			}
+		}
+	}
+	return nil
+}
+
+func fetchReleaseTracks(db *sql.DB, releases map[int32]*models.Release, placeholders string, args []interface{}) error {
+	query := fmt.Sprintf(fetchReleaseTracksSQL, tracksTableName, trackArtistsTableName, placeholders)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch release tracks: %v", err)
+	}
+	defer rows.Close()
+
+	lastTrackID := 0
+	for rows.Next() {
+		var releaseID int32
+		var trackID int
+		var track models.Track
+		var artist models.TrackArtist
+		if err := rows.Scan(&releaseID, &trackID, &track.Position, &track.Type, &track.Title,
+			&track.Duration, &track.DurationSeconds, &artist.Name, &artist.Role); err != nil {
+			return fmt.Errorf("failed to scan release track: %v", err)
+		}
+
+		release := releases[releaseID]
+		if trackID != lastTrackID {
+			release.Tracks = append(release.Tracks, track)
+			lastTrackID = trackID
+		}
+		if artist.Name != "" {
+			last := &release.Tracks[len(release.Tracks)-1]
+			last.Artists = append(last.Artists, artist)
+		}
+	}
+	return rows.Err()
+}
+
+func trackCondition(argIndex int) string {
+	return fmt.Sprintf(trackFilterSQL, tracksTableName, argIndex)
+}